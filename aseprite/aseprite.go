@@ -0,0 +1,568 @@
+// Package aseprite reads Aseprite `.aseprite`/`.ase` files: the layers, the
+// cels of each frame, the palette, and the animation tags.
+//
+// The RGBA, grayscale, and indexed colour modes are supported. Tilemap layers
+// are not, and their cels are skipped, as are the chunks not needed for
+// building the frame images, such as slices and user data.
+//
+// See: https://github.com/aseprite/aseprite/blob/main/docs/ase-file-specs.md
+package aseprite
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strings"
+)
+
+const (
+	fileMagic  = 0xA5E0
+	frameMagic = 0xF1FA
+
+	chunkOldPalette = 0x0004
+	chunkLayer      = 0x2004
+	chunkCel        = 0x2005
+	chunkTags       = 0x2018
+	chunkPalette    = 0x2019
+
+	celRaw        = 0
+	celLinked     = 1
+	celCompressed = 2
+)
+
+// ColourDepth is the colour mode of the file, in bits per pixel.
+type ColourDepth int
+
+const (
+	RGBA      ColourDepth = 32
+	Grayscale ColourDepth = 16
+	Indexed   ColourDepth = 8
+)
+
+// Layer flags.
+const (
+	LayerVisible    = 1
+	LayerBackground = 8 // the opaque background layer, where the transparent index is a colour
+)
+
+// Layer types.
+const (
+	LayerImage   = 0
+	LayerGroup   = 1
+	LayerTilemap = 2
+)
+
+// Tag directions.
+const (
+	Forward         = 0
+	Reverse         = 1
+	PingPong        = 2
+	PingPongReverse = 3
+)
+
+// File is a decoded Aseprite file.
+type File struct {
+	Width, Height    int
+	ColourDepth      ColourDepth
+	TransparentIndex uint8 // the transparent palette index, for indexed files
+	Palette          color.Palette
+	Layers           []Layer
+	Frames           []Frame
+	Tags             []Tag
+}
+
+// Layer is a layer, or layer group, listed from the bottom layer up, with
+// each group followed by its layers.
+type Layer struct {
+	Name       string
+	Flags      int
+	Type       int
+	ChildLevel int // the group nesting level, 0 for top level layers
+}
+
+// Frame holds the cels of the frame, and its duration in milliseconds.
+type Frame struct {
+	Duration int
+	Cels     []Cel
+}
+
+// Cel is the image of a layer in a frame, placed at the X/Y position of the
+// canvas. Linked cels share the image of the cel they link to.
+type Cel struct {
+	Layer int
+	X, Y  int
+	Image image.Image // an *image.Paletted for indexed files, otherwise *image.NRGBA
+}
+
+// Tag is a named animation, over the frames From..To (inclusive).
+type Tag struct {
+	Name      string
+	From, To  int
+	Direction int
+}
+
+// Decode reads an Aseprite file.
+func Decode(r io.Reader) (*File, error) {
+	header := struct {
+		FileSize         uint32
+		Magic            uint16
+		Frames           uint16
+		Width, Height    uint16
+		ColourDepth      uint16
+		Flags            uint32
+		Speed            uint16
+		_                [2]uint32
+		TransparentIndex uint8
+		_                [3]uint8
+		Colours          uint16
+		_                [94]uint8 // pixel ratio, grid, and reserved bytes
+	}{}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("error reading Aseprite header: %w", err)
+	}
+	if header.Magic != fileMagic {
+		return nil, fmt.Errorf("not an Aseprite file")
+	}
+
+	f := &File{
+		Width:            int(header.Width),
+		Height:           int(header.Height),
+		ColourDepth:      ColourDepth(header.ColourDepth),
+		TransparentIndex: header.TransparentIndex,
+	}
+	switch f.ColourDepth {
+	case RGBA, Grayscale, Indexed:
+	default:
+		return nil, fmt.Errorf("unsupported colour depth: %d", header.ColourDepth)
+	}
+
+	for i := 0; i < int(header.Frames); i++ {
+		if err := f.decodeFrame(r); err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i, err)
+		}
+	}
+	return f, nil
+}
+
+func (f *File) decodeFrame(r io.Reader) error {
+	header := struct {
+		Size      uint32
+		Magic     uint16
+		OldChunks uint16
+		Duration  uint16
+		_         [2]uint8
+		Chunks    uint32
+	}{}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	if header.Magic != frameMagic {
+		return fmt.Errorf("invalid frame header")
+	}
+	chunks := int(header.Chunks)
+	if chunks == 0 {
+		chunks = int(header.OldChunks)
+	}
+
+	f.Frames = append(f.Frames, Frame{Duration: int(header.Duration)})
+	newPalette := false
+
+	for i := 0; i < chunks; i++ {
+		var size uint32
+		var kind uint16
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return err
+		}
+		if err := binary.Read(r, binary.LittleEndian, &kind); err != nil {
+			return err
+		}
+		if size < 6 {
+			return fmt.Errorf("invalid chunk size: %d", size)
+		}
+		data := make([]uint8, size-6)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return err
+		}
+
+		var err error
+		switch kind {
+		case chunkLayer:
+			err = f.decodeLayer(data)
+		case chunkCel:
+			err = f.decodeCel(data)
+		case chunkTags:
+			err = f.decodeTags(data)
+		case chunkPalette:
+			newPalette = true
+			err = f.decodePalette(data)
+		case chunkOldPalette:
+			if !newPalette {
+				err = f.decodeOldPalette(data)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("chunk $%04X: %w", kind, err)
+		}
+	}
+	return nil
+}
+
+func (f *File) decodeLayer(data []uint8) error {
+	r := bytes.NewReader(data)
+	fields := struct {
+		Flags         uint16
+		Type          uint16
+		ChildLevel    uint16
+		Width, Height uint16
+		BlendMode     uint16
+		Opacity       uint8
+		_             [3]uint8
+	}{}
+	if err := binary.Read(r, binary.LittleEndian, &fields); err != nil {
+		return err
+	}
+	name, err := readString(r)
+	if err != nil {
+		return err
+	}
+	f.Layers = append(f.Layers, Layer{
+		Name:       name,
+		Flags:      int(fields.Flags),
+		Type:       int(fields.Type),
+		ChildLevel: int(fields.ChildLevel),
+	})
+	return nil
+}
+
+func (f *File) decodeCel(data []uint8) error {
+	r := bytes.NewReader(data)
+	fields := struct {
+		Layer   uint16
+		X, Y    int16
+		Opacity uint8
+		Type    uint16
+		ZIndex  int16
+		_       [5]uint8
+	}{}
+	if err := binary.Read(r, binary.LittleEndian, &fields); err != nil {
+		return err
+	}
+	cel := Cel{Layer: int(fields.Layer), X: int(fields.X), Y: int(fields.Y)}
+
+	switch fields.Type {
+	case celRaw, celCompressed:
+		var width, height uint16
+		if err := binary.Read(r, binary.LittleEndian, &width); err != nil {
+			return err
+		}
+		if err := binary.Read(r, binary.LittleEndian, &height); err != nil {
+			return err
+		}
+		var pixels io.Reader = r
+		if fields.Type == celCompressed {
+			zr, err := zlib.NewReader(r)
+			if err != nil {
+				return err
+			}
+			defer zr.Close()
+			pixels = zr
+		}
+		img, err := f.decodePixels(pixels, int(width), int(height))
+		if err != nil {
+			return err
+		}
+		cel.Image = img
+	case celLinked:
+		var frame uint16
+		if err := binary.Read(r, binary.LittleEndian, &frame); err != nil {
+			return err
+		}
+		linked, ok := Cel{}, false
+		if int(frame) < len(f.Frames) {
+			linked, ok = f.cel(int(frame), cel.Layer)
+		}
+		if !ok {
+			return fmt.Errorf("linked cel not found in frame %d", frame)
+		}
+		cel.Image = linked.Image
+	default:
+		return nil // tilemap cels
+	}
+
+	frame := &f.Frames[len(f.Frames)-1]
+	frame.Cels = append(frame.Cels, cel)
+	return nil
+}
+
+// reads the cel pixels, in the colour depth of the file
+func (f *File) decodePixels(r io.Reader, width, height int) (image.Image, error) {
+	data := make([]uint8, width*height*int(f.ColourDepth)/8)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("error reading cel pixels: %w", err)
+	}
+	rect := image.Rect(0, 0, width, height)
+
+	switch f.ColourDepth {
+	case Indexed:
+		return &image.Paletted{Pix: data, Stride: width, Rect: rect, Palette: f.Palette}, nil
+	case Grayscale:
+		img := image.NewNRGBA(rect)
+		for i := 0; i < width*height; i++ {
+			v, a := data[i*2], data[i*2+1]
+			copy(img.Pix[i*4:], []uint8{v, v, v, a})
+		}
+		return img, nil
+	default:
+		return &image.NRGBA{Pix: data, Stride: width * 4, Rect: rect}, nil
+	}
+}
+
+func (f *File) decodeTags(data []uint8) error {
+	r := bytes.NewReader(data)
+	fields := struct {
+		Count uint16
+		_     [8]uint8
+	}{}
+	if err := binary.Read(r, binary.LittleEndian, &fields); err != nil {
+		return err
+	}
+	for i := 0; i < int(fields.Count); i++ {
+		tag := struct {
+			From, To  uint16
+			Direction uint8
+			Repeat    uint16
+			_         [6]uint8
+			Colour    [3]uint8
+			_         uint8
+		}{}
+		if err := binary.Read(r, binary.LittleEndian, &tag); err != nil {
+			return err
+		}
+		name, err := readString(r)
+		if err != nil {
+			return err
+		}
+		f.Tags = append(f.Tags, Tag{Name: name, From: int(tag.From), To: int(tag.To), Direction: int(tag.Direction)})
+	}
+	return nil
+}
+
+func (f *File) decodePalette(data []uint8) error {
+	r := bytes.NewReader(data)
+	fields := struct {
+		Size        uint32
+		First, Last uint32
+		_           [8]uint8
+	}{}
+	if err := binary.Read(r, binary.LittleEndian, &fields); err != nil {
+		return err
+	}
+	if fields.Size > 256 || fields.Last >= fields.Size || fields.First > fields.Last {
+		return fmt.Errorf("invalid palette size")
+	}
+	f.resizePalette(int(fields.Size))
+
+	for i := fields.First; i <= fields.Last; i++ {
+		entry := struct {
+			Flags      uint16
+			R, G, B, A uint8
+		}{}
+		if err := binary.Read(r, binary.LittleEndian, &entry); err != nil {
+			return err
+		}
+		if entry.Flags&1 != 0 { // has a name
+			if _, err := readString(r); err != nil {
+				return err
+			}
+		}
+		f.Palette[i] = color.NRGBA{R: entry.R, G: entry.G, B: entry.B, A: entry.A}
+	}
+	return nil
+}
+
+// decodes the palette used by files from older Aseprite versions
+func (f *File) decodeOldPalette(data []uint8) error {
+	r := bytes.NewReader(data)
+	var packets uint16
+	if err := binary.Read(r, binary.LittleEndian, &packets); err != nil {
+		return err
+	}
+	index := 0
+	for i := 0; i < int(packets); i++ {
+		var skip, count uint8
+		if err := binary.Read(r, binary.LittleEndian, &skip); err != nil {
+			return err
+		}
+		if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+			return err
+		}
+		index += int(skip)
+		n := int(count)
+		if n == 0 {
+			n = 256
+		}
+		if index+n > 256 {
+			return fmt.Errorf("invalid palette size")
+		}
+		f.resizePalette(index + n)
+		for j := 0; j < n; j++ {
+			var rgb [3]uint8
+			if _, err := io.ReadFull(r, rgb[:]); err != nil {
+				return err
+			}
+			f.Palette[index] = color.NRGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 255}
+			index++
+		}
+	}
+	return nil
+}
+
+// grows the palette to the size, keeping the current colours
+func (f *File) resizePalette(size int) {
+	for len(f.Palette) < size {
+		f.Palette = append(f.Palette, color.NRGBA{})
+	}
+}
+
+// LayerIndex returns the index of the layer with the name, ignoring case.
+func (f *File) LayerIndex(name string) (int, bool) {
+	for i, layer := range f.Layers {
+		if strings.EqualFold(layer.Name, name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Visible returns true when the layer, and all the groups it is in, are
+// visible.
+func (f *File) Visible(layer int) bool {
+	level := f.Layers[layer].ChildLevel
+	if f.Layers[layer].Flags&LayerVisible == 0 {
+		return false
+	}
+	// a group is listed before its layers, so the group of a layer is the
+	// nearest layer before it with a lower level
+	for i := layer - 1; i >= 0 && level > 0; i-- {
+		if f.Layers[i].ChildLevel < level {
+			if f.Layers[i].Flags&LayerVisible == 0 {
+				return false
+			}
+			level = f.Layers[i].ChildLevel
+		}
+	}
+	return true
+}
+
+// FrameImage draws the cels of the layers for the frame, in layer order, onto
+// a canvas of the file size. Pixels are drawn over those below without any
+// blending, as the SMS has no translucent colours.
+//
+// For indexed files, an *image.Paletted is returned, using the file palette,
+// where the transparent index is only drawn for the background layer, and is
+// transparent when that layer is not drawn. Otherwise, an *image.NRGBA is
+// returned.
+func (f *File) FrameImage(frame int, layers ...int) (image.Image, error) {
+	if frame < 0 || frame >= len(f.Frames) {
+		return nil, fmt.Errorf("frame %d out of range, the file has %d frames", frame, len(f.Frames))
+	}
+	drawn := make(map[int]bool)
+	background := false
+	for _, layer := range layers {
+		if layer < 0 || layer >= len(f.Layers) {
+			return nil, fmt.Errorf("layer %d out of range, the file has %d layers", layer, len(f.Layers))
+		}
+		drawn[layer] = true
+		background = background || f.Layers[layer].Flags&LayerBackground != 0
+	}
+	rect := image.Rect(0, 0, f.Width, f.Height)
+
+	if f.ColourDepth == Indexed {
+		palette := append(color.Palette{}, f.Palette...)
+		for len(palette) <= int(f.TransparentIndex) {
+			palette = append(palette, color.NRGBA{})
+		}
+		if !background {
+			palette[f.TransparentIndex] = color.NRGBA{}
+		}
+		img := image.NewPaletted(rect, palette)
+		for i := range img.Pix {
+			img.Pix[i] = f.TransparentIndex
+		}
+		for _, cel := range f.sortedCels(frame, drawn) {
+			opaque := f.Layers[cel.Layer].Flags&LayerBackground != 0
+			src := cel.Image.(*image.Paletted)
+			f.drawCel(rect, cel, func(x, y, cx, cy int) {
+				index := src.ColorIndexAt(cx, cy)
+				if opaque || index != f.TransparentIndex {
+					img.SetColorIndex(x, y, index)
+				}
+			})
+		}
+		return img, nil
+	}
+
+	img := image.NewNRGBA(rect)
+	for _, cel := range f.sortedCels(frame, drawn) {
+		src := cel.Image.(*image.NRGBA)
+		f.drawCel(rect, cel, func(x, y, cx, cy int) {
+			if c := src.NRGBAAt(cx, cy); c.A > 0 {
+				img.SetNRGBA(x, y, c)
+			}
+		})
+	}
+	return img, nil
+}
+
+// returns the cels of the frame for the layers, from the bottom layer up
+func (f *File) sortedCels(frame int, layers map[int]bool) []Cel {
+	var cels []Cel
+	for layer := range f.Layers {
+		if !layers[layer] {
+			continue
+		}
+		if cel, ok := f.cel(frame, layer); ok {
+			cels = append(cels, cel)
+		}
+	}
+	return cels
+}
+
+// calls the draw function for each cel pixel within the canvas, with the
+// canvas and cel positions
+func (f *File) drawCel(canvas image.Rectangle, cel Cel, draw func(x, y, cx, cy int)) {
+	bounds := cel.Image.Bounds()
+	for cy := bounds.Min.Y; cy < bounds.Max.Y; cy++ {
+		for cx := bounds.Min.X; cx < bounds.Max.X; cx++ {
+			x, y := cel.X+cx-bounds.Min.X, cel.Y+cy-bounds.Min.Y
+			if image.Pt(x, y).In(canvas) {
+				draw(x, y, cx, cy)
+			}
+		}
+	}
+}
+
+func (f *File) cel(frame, layer int) (Cel, bool) {
+	for _, cel := range f.Frames[frame].Cels {
+		if cel.Layer == layer {
+			return cel, true
+		}
+	}
+	return Cel{}, false
+}
+
+func readString(r io.Reader) (string, error) {
+	var length uint16
+	if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+		return "", err
+	}
+	s := make([]uint8, length)
+	if _, err := io.ReadFull(r, s); err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
@@ -0,0 +1,251 @@
+package aseprite_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/mrcook/smstilemap/aseprite"
+)
+
+func TestAseprite_DecodeIndexed(t *testing.T) {
+	f, err := aseprite.Decode(bytes.NewReader(indexedFile()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if f.Width != 4 || f.Height != 2 || f.ColourDepth != aseprite.Indexed {
+		t.Errorf("unexpected file header: %dx%d, %d bpp", f.Width, f.Height, f.ColourDepth)
+	}
+	if len(f.Palette) != 4 || f.Palette[3] != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("unexpected palette: %v", f.Palette)
+	}
+	if len(f.Layers) != 4 || f.Layers[1].Name != "Sprite" {
+		t.Fatalf("unexpected layers: %+v", f.Layers)
+	}
+	if len(f.Frames) != 2 || f.Frames[0].Duration != 100 || f.Frames[1].Duration != 150 {
+		t.Fatalf("unexpected frames: %+v", f.Frames)
+	}
+	if len(f.Tags) != 1 || f.Tags[0] != (aseprite.Tag{Name: "walk", From: 0, To: 1, Direction: aseprite.PingPong}) {
+		t.Errorf("unexpected tags: %+v", f.Tags)
+	}
+
+	if i, ok := f.LayerIndex("sprite"); !ok || i != 1 {
+		t.Errorf("expected the Sprite layer at index 1, got %d (found: %t)", i, ok)
+	}
+	if _, ok := f.LayerIndex("collision"); ok {
+		t.Error("expected no collision layer")
+	}
+	if !f.Visible(1) {
+		t.Error("expected the Sprite layer to be visible")
+	}
+	if f.Visible(3) {
+		t.Error("expected the layer of the hidden group to be hidden")
+	}
+}
+
+func TestAseprite_FrameImageIndexed(t *testing.T) {
+	f, err := aseprite.Decode(bytes.NewReader(indexedFile()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// frame 1 links the background cel of frame 0, and moves the sprite cel
+	table := map[string]struct {
+		frame  int
+		layers []int
+		pixels []uint8
+	}{
+		"background":        {frame: 0, layers: []int{0}, pixels: []uint8{1, 1, 1, 1, 2, 2, 2, 2}},
+		"sprite":            {frame: 0, layers: []int{1}, pixels: []uint8{0, 3, 0, 0, 0, 0, 0, 0}},
+		"both":              {frame: 0, layers: []int{0, 1}, pixels: []uint8{1, 3, 1, 1, 2, 2, 2, 2}},
+		"linked background": {frame: 1, layers: []int{0, 1}, pixels: []uint8{1, 1, 1, 1, 2, 2, 3, 2}},
+	}
+	for name, test := range table {
+		img, err := f.FrameImage(test.frame, test.layers...)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+		paletted, ok := img.(*image.Paletted)
+		if !ok {
+			t.Fatalf("%s: expected a paletted image, got %T", name, img)
+		}
+		if !bytes.Equal(paletted.Pix, test.pixels) {
+			t.Errorf("%s: expected pixels %v, got %v", name, test.pixels, paletted.Pix)
+		}
+	}
+
+	img, _ := f.FrameImage(0, 1)
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Error("expected the transparent index to be transparent without the background layer")
+	}
+	img, _ = f.FrameImage(0, 0)
+	if _, _, _, a := img.(*image.Paletted).Palette[0].RGBA(); a == 0 {
+		t.Error("expected the transparent index to be a colour of the background layer")
+	}
+
+	if _, err := f.FrameImage(2, 0); err == nil {
+		t.Error("expected an error for a missing frame")
+	}
+}
+
+func TestAseprite_FrameImageRGBA(t *testing.T) {
+	red := []uint8{255, 0, 0, 255}
+	clear := []uint8{0, 0, 255, 0}
+	var pixels []uint8
+	for _, p := range [][]uint8{red, clear, clear, red} {
+		pixels = append(pixels, p...)
+	}
+
+	data := file(2, 2, 32, 0, frame(100,
+		chunk(0x2004, layer(1, 0, 0, "Layer")),
+		chunk(0x2005, compressedCel(0, 0, 0, 2, 2, pixels)),
+	))
+	f, err := aseprite.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	img, err := f.FrameImage(0, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	nrgba, ok := img.(*image.NRGBA)
+	if !ok {
+		t.Fatalf("expected an NRGBA image, got %T", img)
+	}
+	if c := nrgba.NRGBAAt(0, 0); c != (color.NRGBA{R: 255, A: 255}) {
+		t.Errorf("expected a red pixel, got %v", c)
+	}
+	if c := nrgba.NRGBAAt(1, 0); c.A != 0 {
+		t.Errorf("expected a transparent pixel, got %v", c)
+	}
+}
+
+func TestAseprite_DecodeErrors(t *testing.T) {
+	table := map[string][]uint8{
+		"short header": {0, 1, 2, 3},
+		"bad magic":    file(1, 1, 8, 0)[:128],
+		"colour depth": file(1, 1, 24, 0),
+	}
+	table["bad magic"][4] = 0
+	for name, data := range table {
+		if _, err := aseprite.Decode(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// an indexed 4x2 file of two frames, with a background layer, a sprite
+// layer, and a hidden group holding a layer.
+func indexedFile() []uint8 {
+	return file(4, 2, 8, 0,
+		frame(100,
+			chunk(0x2019, palette(color.NRGBA{A: 255}, color.NRGBA{B: 255, A: 255}, color.NRGBA{G: 255, A: 255}, color.NRGBA{R: 255, A: 255})),
+			chunk(0x2004, layer(aseprite.LayerVisible|aseprite.LayerBackground, 0, 0, "Background")),
+			chunk(0x2004, layer(aseprite.LayerVisible, 0, 0, "Sprite")),
+			chunk(0x2004, layer(0, aseprite.LayerGroup, 0, "Group")),
+			chunk(0x2004, layer(aseprite.LayerVisible, 0, 1, "Hidden")),
+			chunk(0x2018, tags("walk", 0, 1, aseprite.PingPong)),
+			chunk(0x2005, rawCel(0, 0, 0, 4, 2, []uint8{1, 1, 1, 1, 2, 2, 2, 2})),
+			chunk(0x2005, compressedCel(1, 1, 0, 1, 1, []uint8{3})),
+		),
+		frame(150,
+			chunk(0x2005, linkedCel(0, 0)),
+			chunk(0x2005, rawCel(1, 2, 1, 1, 1, []uint8{3})),
+		),
+	)
+}
+
+func file(width, height, depth int, transparent uint8, frames ...[]uint8) []uint8 {
+	var b bytes.Buffer
+	write(&b, uint32(0), uint16(0xA5E0), uint16(len(frames)), uint16(width), uint16(height), uint16(depth))
+	write(&b, uint32(1), uint16(100), [2]uint32{}, transparent, [3]uint8{}, uint16(0), [94]uint8{})
+	for _, f := range frames {
+		b.Write(f)
+	}
+	return b.Bytes()
+}
+
+func frame(duration int, chunks ...[]uint8) []uint8 {
+	var data []uint8
+	for _, c := range chunks {
+		data = append(data, c...)
+	}
+	var b bytes.Buffer
+	write(&b, uint32(16+len(data)), uint16(0xF1FA), uint16(len(chunks)), uint16(duration), [2]uint8{}, uint32(len(chunks)))
+	b.Write(data)
+	return b.Bytes()
+}
+
+func chunk(kind uint16, data []uint8) []uint8 {
+	var b bytes.Buffer
+	write(&b, uint32(6+len(data)), kind)
+	b.Write(data)
+	return b.Bytes()
+}
+
+func layer(flags, kind, level int, name string) []uint8 {
+	var b bytes.Buffer
+	write(&b, uint16(flags), uint16(kind), uint16(level), uint16(0), uint16(0), uint16(0), uint8(255), [3]uint8{})
+	writeString(&b, name)
+	return b.Bytes()
+}
+
+func celHeader(b *bytes.Buffer, layer, x, y, kind int) {
+	write(b, uint16(layer), int16(x), int16(y), uint8(255), uint16(kind), int16(0), [5]uint8{})
+}
+
+func rawCel(layer, x, y, width, height int, pixels []uint8) []uint8 {
+	var b bytes.Buffer
+	celHeader(&b, layer, x, y, 0)
+	write(&b, uint16(width), uint16(height))
+	b.Write(pixels)
+	return b.Bytes()
+}
+
+func linkedCel(layer, frame int) []uint8 {
+	var b bytes.Buffer
+	celHeader(&b, layer, 0, 0, 1)
+	write(&b, uint16(frame))
+	return b.Bytes()
+}
+
+func compressedCel(layer, x, y, width, height int, pixels []uint8) []uint8 {
+	var b bytes.Buffer
+	celHeader(&b, layer, x, y, 2)
+	write(&b, uint16(width), uint16(height))
+	zw := zlib.NewWriter(&b)
+	zw.Write(pixels)
+	zw.Close()
+	return b.Bytes()
+}
+
+func palette(colours ...color.NRGBA) []uint8 {
+	var b bytes.Buffer
+	write(&b, uint32(len(colours)), uint32(0), uint32(len(colours)-1), [8]uint8{})
+	for _, c := range colours {
+		write(&b, uint16(0), c.R, c.G, c.B, c.A)
+	}
+	return b.Bytes()
+}
+
+func tags(name string, from, to, direction int) []uint8 {
+	var b bytes.Buffer
+	write(&b, uint16(1), [8]uint8{})
+	write(&b, uint16(from), uint16(to), uint8(direction), uint16(0), [6]uint8{}, [3]uint8{}, uint8(0))
+	writeString(&b, name)
+	return b.Bytes()
+}
+
+func writeString(b *bytes.Buffer, s string) {
+	write(b, uint16(len(s)))
+	b.WriteString(s)
+}
+
+func write(b *bytes.Buffer, values ...any) {
+	for _, v := range values {
+		binary.Write(b, binary.LittleEndian, v)
+	}
+}
@@ -5,118 +5,239 @@ import (
 	"strings"
 )
 
-func Tiles(data []uint8) *strings.Builder {
+// Define is a named constant, written using the `.define` directive.
+type Define struct {
+	Name    string
+	Value   int
+	Hex     bool   // write the value as a 16-bit hex number, e.g. $3800
+	Comment string // optional
+}
+
+// Defines writes the constants using `.define` directives.
+func Defines(title string, defines []Define) *strings.Builder {
+	return Options{}.Defines(title, defines)
+}
+
+// Defines writes the constants using the dialect's constant directive.
+func (o Options) Defines(title string, defines []Define) *strings.Builder {
 	var sb strings.Builder
-	lines := tileToHexStrings(data[:])
-
-	sb.WriteString("; Tile data (characters)\n")
-	sb.WriteString("; An 8x8 pixel tile is represented by 4x8 bytes. Each horizontal byte specifies\n")
-	sb.WriteString("; 2 pixels, with each nibble of the byte referencing a palette ID.\n")
-	sb.WriteString("TileData:\n")
-	tileNumber := 0
-	for i, line := range lines {
-		if i == 0 || i%2 == 0 {
-			sb.WriteString(fmt.Sprintf("; tile %03d:\n", tileNumber))
-			tileNumber++
+
+	o.heading(&sb, title)
+	for _, d := range defines {
+		value := fmt.Sprintf("%d", d.Value)
+		if d.Hex {
+			value = fmt.Sprintf("$%04X", d.Value)
+		}
+		line := o.define(o.name(d.Name), value)
+		if len(d.Comment) > 0 && o.Comments != NoComments {
+			line = fmt.Sprintf("%-32s ; %s", line, d.Comment)
 		}
-		sb.WriteString(fmt.Sprintf(".db %s\n", line))
+		sb.WriteString(line + "\n")
 	}
-	sb.WriteString("TileDataEnd:\n")
 	return &sb
 }
 
-func Tilemap(data []uint16) *strings.Builder {
+func Tiles(data []uint8) *strings.Builder {
+	return TilesFrom(data, 0)
+}
+
+// TilesFrom writes the tile data, numbering the tiles from the given tile
+// number, for tiles loaded into VRAM after others.
+func TilesFrom(data []uint8, firstTile int) *strings.Builder {
+	return Options{}.TilesFrom(data, firstTile)
+}
+
+// TilesFrom writes the tile data, see TilesFrom.
+func (o Options) TilesFrom(data []uint8, firstTile int) *strings.Builder {
 	var sb strings.Builder
-	lines := tilemapToBinaryStrings(data[:])
-
-	sb.WriteString("; Tilemap data (the name table)\n")
-	sb.WriteString("; A matrix of 28 rows and 32 columns consisting of 16-bit [WORD] values:\n")
-	sb.WriteString(";   Bit  |15 14 13|    12    |    11     |      10       |        9        | 8 7 6 5 4 3 2 1 0\n")
-	sb.WriteString(";   Data | Unused | Priority | Palette # | Vertical flip | Horizontal flip |    Tile number\n")
-	sb.WriteString("Tilemap:\n")
-	row := 0
-	for i, line := range lines {
-		if i == 0 || i%8 == 0 {
-			if row == 24 {
-				break // don't show the unused last 4 rows of the 28 row tilemap
-			}
-			sb.WriteString(fmt.Sprintf("; row %02d\n", row))
-			row++
-		}
-		sb.WriteString(fmt.Sprintf(".dw %s\n", line))
+
+	o.heading(&sb,
+		"Tile data (characters)",
+		"An 8x8 pixel tile is represented by 4x8 bytes. Each horizontal byte specifies",
+		"2 pixels, with each nibble of the byte referencing a palette ID.",
+	)
+	o.label(&sb, "TileData")
+	for i := 0; i < len(data); i += tileSize {
+		o.note(&sb, "tile %03d:", firstTile+i/tileSize)
+		o.bytes(&sb, data[i:min(i+tileSize, len(data))], Hex, 16)
 	}
-	sb.WriteString("TilemapEnd:\n")
+	o.label(&sb, "TileDataEnd")
 	return &sb
 }
 
-func Palettes(data [32]uint8) *strings.Builder {
+// Tilemap writes the name table data, which holds 32 words per row, up to the
+// given number of visible rows. Any remaining off-screen rows are not written.
+func Tilemap(data []uint16, rows int) *strings.Builder {
+	return Options{}.Tilemap(data, rows)
+}
+
+// Tilemap writes the name table data, see Tilemap.
+func (o Options) Tilemap(data []uint16, rows int) *strings.Builder {
 	var sb strings.Builder
-	lines := paletteToBinaryStrings(data[:])
-
-	sb.WriteString("; Palette data; two 16 colour palettes\n")
-	sb.WriteString(";   Bit:   7 6  |  5 4 |  3 2  | 1 0\n")
-	sb.WriteString(";     %: Unused | Blue | Green | Red\n")
-	sb.WriteString("PaletteData:\n")
-	sb.WriteString("; palette 1\n")
-	sb.WriteString(fmt.Sprintf(".db %s\n", lines[0]))
-	sb.WriteString(fmt.Sprintf(".db %s\n", lines[1]))
-	sb.WriteString("; palette 2\n")
-	sb.WriteString(fmt.Sprintf(".db %s\n", lines[2]))
-	sb.WriteString(fmt.Sprintf(".db %s\n", lines[3]))
-	sb.WriteString("PaletteDataEnd:\n")
+
+	heading := []string{
+		"Tilemap data (the name table)",
+		fmt.Sprintf("A matrix of %d rows and 32 columns consisting of 16-bit [WORD] values:", len(data)/tilemapWidth),
+		"  Bit  |15 14 13|    12    |    11     |      10       |        9        | 8 7 6 5 4 3 2 1 0",
+		"  Data | Unused | Priority | Palette # | Vertical flip | Horizontal flip |    Tile number",
+	}
+	if len(data)/tilemapWidth > 28 {
+		heading = append(heading, "The 224/240-line mode name table is normally located at VRAM $3700.")
+	}
+	o.heading(&sb, heading...)
+	o.label(&sb, "Tilemap")
+	// don't show the unused off-screen rows of the name table
+	for row := 0; row < rows && row*tilemapWidth < len(data); row++ {
+		o.note(&sb, "row %02d", row)
+		o.words(&sb, data[row*tilemapWidth:min((row+1)*tilemapWidth, len(data))], Binary, 4)
+	}
+	o.label(&sb, "TilemapEnd")
 	return &sb
 }
 
-func tileToHexStrings(data []uint8) []string {
-	bytesPerLine := 16
-	var lines []string
-	var pixels []string
-	var pid = 0
-	for _, b := range data {
-		pixels = append(pixels, fmt.Sprintf("$%02X", b))
-		if pid == bytesPerLine-1 {
-			lines = append(lines, strings.Join(pixels[:], ", "))
-			pid = 0
-			pixels = []string{}
-		} else {
-			pid++
+// MapRows writes a map larger than the screen as rows of tilemap words.
+// Each row is a strip to be streamed into the name table when scrolling vertically.
+func MapRows(data []uint16, width int) *strings.Builder {
+	return Options{}.MapRows(data, width)
+}
+
+// MapRows writes the map as rows of tilemap words, see MapRows.
+func (o Options) MapRows(data []uint16, width int) *strings.Builder {
+	return o.mapStrips(data, width, "MapRows", "row",
+		fmt.Sprintf("Tilemap [WORD] values, one row strip of %d words for each map row.", width),
+		"When scrolling vertically, copy 32 words of map row R, from the left",
+		"column C of the screen, into name table row R mod 28 (32 in the 224-line",
+		"mode), with map column C+i going to name table column (C+i) mod 32.",
+	)
+}
+
+// MapColumns writes a map larger than the screen as columns of tilemap words.
+// Each column is a strip to be streamed into the name table when scrolling horizontally.
+func MapColumns(data []uint16, height int) *strings.Builder {
+	return Options{}.MapColumns(data, height)
+}
+
+// MapColumns writes the map as columns of tilemap words, see MapColumns.
+func (o Options) MapColumns(data []uint16, height int) *strings.Builder {
+	return o.mapStrips(data, height, "MapColumns", "column",
+		fmt.Sprintf("Tilemap [WORD] values, one column strip of %d words for each map column.", height),
+		"When scrolling horizontally, copy the words of map column C, from the top",
+		"row R of the screen, into name table column C mod 32, with map row R+i",
+		"going to name table row (R+i) mod 28 (32 in the 224-line mode), so a",
+		"column write steps 64 bytes through the name table for each word.",
+	)
+}
+
+func (o Options) mapStrips(data []uint16, stripLength int, label, name string, description ...string) *strings.Builder {
+	var sb strings.Builder
+
+	o.heading(&sb, append([]string{fmt.Sprintf("Map data as %s strips", name)}, description...)...)
+	o.label(&sb, label)
+	if stripLength > 0 {
+		for i := 0; i < len(data); i += stripLength {
+			o.note(&sb, "%s %03d", name, i/stripLength)
+			o.words(&sb, data[i:i+stripLength], Binary, 4)
 		}
 	}
-	return lines
-}
-
-func paletteToBinaryStrings(data []uint8) []string {
-	bytesPerLine := 8
-	var lines []string
-	var pixels []string
-	var pid = 0
-	for _, b := range data {
-		pixels = append(pixels, fmt.Sprintf("%%%08b", b))
-		if pid == bytesPerLine-1 {
-			lines = append(lines, strings.Join(pixels[:], ", "))
-			pid = 0
-			pixels = []string{}
-		} else {
-			pid++
-		}
+	o.label(&sb, label+"End")
+	return &sb
+}
+
+func SpriteFrames(frames [][]uint8, cols, spriteHeight int) *strings.Builder {
+	return Options{}.SpriteFrames(frames, cols, spriteHeight)
+}
+
+// SpriteFrames writes the tile numbers of the sprites in each frame.
+func (o Options) SpriteFrames(frames [][]uint8, cols, spriteHeight int) *strings.Builder {
+	var sb strings.Builder
+
+	o.heading(&sb,
+		"Sprite frames",
+		fmt.Sprintf("Each frame lists the tile numbers of its 8x%d sprites, %d sprites per row,", spriteHeight, cols),
+		"ordered left-to-right, top-to-bottom.",
+	)
+	o.label(&sb, "SpriteFrames")
+	for i, frame := range frames {
+		o.note(&sb, "frame %03d", i)
+		o.bytes(&sb, frame, Hex, cols)
 	}
-	return lines
-}
-
-func tilemapToBinaryStrings(data []uint16) []string {
-	wordsPerLine := 4
-	var lines []string
-	var pixels []string
-	var pid = 0
-	for _, b := range data {
-		pixels = append(pixels, fmt.Sprintf("%%%016b", b))
-		if pid == wordsPerLine-1 {
-			lines = append(lines, strings.Join(pixels[:], ", "))
-			pid = 0
-			pixels = []string{}
-		} else {
-			pid++
-		}
+	o.label(&sb, "SpriteFramesEnd")
+	return &sb
+}
+
+func CollisionMap(data []uint8, width int) *strings.Builder {
+	return Options{}.CollisionMap(data, width)
+}
+
+// CollisionMap writes a byte for each tile of the map, row by row, where the
+// non-zero tiles are solid.
+func (o Options) CollisionMap(data []uint8, width int) *strings.Builder {
+	var sb strings.Builder
+
+	o.heading(&sb,
+		"Collision map",
+		fmt.Sprintf("One byte for each tile, %d tiles per row, where 1 is a solid tile.", width),
+	)
+	o.label(&sb, "CollisionMap")
+	for i := 0; i < len(data); i += width {
+		o.note(&sb, "row %03d", i/width)
+		o.bytes(&sb, data[i:min(i+width, len(data))], Hex, width)
 	}
-	return lines
+	o.label(&sb, "CollisionMapEnd")
+	return &sb
+}
+
+func Palettes(data [32]uint8) *strings.Builder {
+	return Options{}.Palettes(data)
 }
+
+// Palettes writes the two 16 colour palettes.
+func (o Options) Palettes(data [32]uint8) *strings.Builder {
+	var sb strings.Builder
+
+	o.heading(&sb,
+		"Palette data; two 16 colour palettes",
+		"  Bit:   7 6  |  5 4 |  3 2  | 1 0",
+		"    %: Unused | Blue | Green | Red",
+	)
+	o.label(&sb, "PaletteData")
+	o.note(&sb, "palette 1")
+	o.bytes(&sb, data[:16], Binary, 8)
+	o.note(&sb, "palette 2")
+	o.bytes(&sb, data[16:], Binary, 8)
+	o.label(&sb, "PaletteDataEnd")
+	return &sb
+}
+
+func GGPalettes(data [64]uint8) *strings.Builder {
+	return Options{}.GGPalettes(data)
+}
+
+// GGPalettes writes the 64 bytes of Game Gear palette data, the little-endian
+// CRAM bytes, as a word for each colour.
+func (o Options) GGPalettes(data [64]uint8) *strings.Builder {
+	var sb strings.Builder
+
+	colours := make([]uint16, len(data)/2)
+	for i := range colours {
+		colours[i] = uint16(data[i*2]) | uint16(data[i*2+1])<<8
+	}
+
+	o.heading(&sb,
+		"Palette data; two 16 colour palettes, a word for each colour",
+		"  Bit: 15-12  | 11-8 | 7-4   | 3-0",
+		"    $: Unused | Blue | Green | Red",
+	)
+	o.label(&sb, "PaletteData")
+	o.note(&sb, "palette 1")
+	o.words(&sb, colours[:16], Hex, 8)
+	o.note(&sb, "palette 2")
+	o.words(&sb, colours[16:], Hex, 8)
+	o.label(&sb, "PaletteDataEnd")
+	return &sb
+}
+
+const (
+	tileSize     = 32 // bytes
+	tilemapWidth = 32 // words
+)
@@ -44,6 +44,15 @@ TileDataEnd:
 	}
 }
 
+func TestAssembly_TilesFrom(t *testing.T) {
+	data := make([]uint8, 64)
+
+	got := assembly.TilesFrom(data, 96).String()
+	if !strings.Contains(got, "; tile 096:\n") || !strings.Contains(got, "; tile 097:\n") {
+		t.Errorf("expected tiles to be numbered from the first tile, got:\n%s", got)
+	}
+}
+
 func TestAssembly_TilemapData(t *testing.T) {
 	tilemapData := make([]uint16, 896)
 	tilemapData[0] = 0b0000000000000001
@@ -51,7 +60,7 @@ func TestAssembly_TilemapData(t *testing.T) {
 	tilemapData[8] = 0b0000000000000110
 	tilemapData[9] = 0b0000010000000110
 
-	fullTilemap := assembly.Tilemap(tilemapData)
+	fullTilemap := assembly.Tilemap(tilemapData, 24)
 	want := `Tilemap:
 ; row 00
 .dw %0000000000000001, %0000001000000001, %0000000000000000, %0000000000000000
@@ -67,6 +76,110 @@ func TestAssembly_TilemapData(t *testing.T) {
 	}
 }
 
+func TestAssembly_TilemapRows(t *testing.T) {
+	t.Run("only the visible rows are written", func(t *testing.T) {
+		got := assembly.Tilemap(make([]uint16, 896), 24).String()
+		if !strings.Contains(got, "; row 23\n") || strings.Contains(got, "; row 24\n") {
+			t.Errorf("expected 24 rows to be written, got:\n%s", got)
+		}
+	})
+
+	t.Run("with the 240-line name table", func(t *testing.T) {
+		got := assembly.Tilemap(make([]uint16, 1024), 30).String()
+		if !strings.Contains(got, "; A matrix of 32 rows and 32 columns") {
+			t.Errorf("expected the 32 row name table to be described, got:\n%s", got)
+		}
+		if !strings.Contains(got, "; row 29\n") || strings.Contains(got, "; row 30\n") {
+			t.Errorf("expected 30 rows to be written, got:\n%s", got)
+		}
+	})
+}
+
+func TestAssembly_Defines(t *testing.T) {
+	got := assembly.Defines("Constants", []assembly.Define{
+		{Name: "MapWidth", Value: 64},
+		{Name: "NameTableAddress", Value: 0x3800, Hex: true, Comment: "name table"},
+	}).String()
+	want := `; Constants
+.define MapWidth 64
+.define NameTableAddress $3800   ; name table
+`
+	if got != want {
+		t.Errorf("unexpected output, got:\n%s", got)
+	}
+}
+
+func TestAssembly_MapStrips(t *testing.T) {
+	data := []uint16{0, 1, 2, 3, 4, 5}
+
+	t.Run("as rows", func(t *testing.T) {
+		got := assembly.MapRows(data, 3).String()
+		want := `MapRows:
+; row 000
+.dw %0000000000000000, %0000000000000001, %0000000000000010
+; row 001
+.dw %0000000000000011, %0000000000000100, %0000000000000101
+MapRowsEnd:
+`
+		got = got[strings.Index(got, "MapRows:"):]
+		if got != want {
+			t.Errorf("unexpected output, got:\n%s", got)
+		}
+	})
+
+	t.Run("as columns", func(t *testing.T) {
+		got := assembly.MapColumns(data, 2).String()
+		if !strings.Contains(got, "; column 002\n.dw %0000000000000100, %0000000000000101\nMapColumnsEnd:") {
+			t.Errorf("unexpected output, got:\n%s", got)
+		}
+	})
+}
+
+func TestAssembly_SpriteFrames(t *testing.T) {
+	frames := [][]uint8{
+		{0x00, 0x02, 0x04, 0x06},
+		{0x08, 0x02, 0x0A, 0x0C},
+	}
+
+	got := assembly.SpriteFrames(frames, 2, 16).String()
+	want := `SpriteFrames:
+; frame 000
+.db $00, $02
+.db $04, $06
+; frame 001
+.db $08, $02
+.db $0A, $0C
+SpriteFramesEnd:
+`
+	lines := strings.Split(got, "\n")
+	if lines[1] != "; Each frame lists the tile numbers of its 8x16 sprites, 2 sprites per row," {
+		t.Errorf("unexpected sprite size comment, got: %s", lines[1])
+	}
+	got = strings.Join(lines[3:], "\n")
+	if got != want {
+		t.Errorf("unexpected output, got:\n%s", got)
+	}
+}
+
+func TestAssembly_CollisionMap(t *testing.T) {
+	got := assembly.CollisionMap([]uint8{0, 1, 1, 0, 0, 1}, 3).String()
+	want := `CollisionMap:
+; row 000
+.db $00, $01, $01
+; row 001
+.db $00, $00, $01
+CollisionMapEnd:
+`
+	lines := strings.Split(got, "\n")
+	if lines[1] != "; One byte for each tile, 3 tiles per row, where 1 is a solid tile." {
+		t.Errorf("unexpected collision map comment, got: %s", lines[1])
+	}
+	got = strings.Join(lines[2:], "\n")
+	if got != want {
+		t.Errorf("unexpected output, got:\n%s", got)
+	}
+}
+
 func TestAssembly_PaletteData(t *testing.T) {
 	var paletteData [32]uint8
 	paletteData[0] = 0b00000011
@@ -90,3 +203,89 @@ PaletteDataEnd:
 		t.Errorf("unexpected output, got:\n%s", got)
 	}
 }
+
+func TestAssembly_GGPaletteData(t *testing.T) {
+	var paletteData [64]uint8
+	paletteData[0], paletteData[1] = 0xBC, 0x0A
+	paletteData[62], paletteData[63] = 0xFF, 0x0F
+
+	got := assembly.GGPalettes(paletteData).String()
+	want := `PaletteData:
+; palette 1
+.dw $0ABC, $0000, $0000, $0000, $0000, $0000, $0000, $0000
+.dw $0000, $0000, $0000, $0000, $0000, $0000, $0000, $0000
+; palette 2
+.dw $0000, $0000, $0000, $0000, $0000, $0000, $0000, $0000
+.dw $0000, $0000, $0000, $0000, $0000, $0000, $0000, $0FFF
+PaletteDataEnd:
+`
+	lines := strings.Split(got, "\n")
+	got = strings.Join(lines[3:], "\n")
+	if got != want {
+		t.Errorf("unexpected output, got:\n%s", got)
+	}
+}
+
+func TestAssembly_CompressedData(t *testing.T) {
+	data := make([]uint8, 18)
+	data[0] = 0x1A
+	data[17] = 0xFF
+
+	got := assembly.CompressedData("TileData", "Tile data, compressed", data).String()
+	want := `; Tile data, compressed (18 bytes)
+TileData:
+.db $1A, $00, $00, $00, $00, $00, $00, $00, $00, $00, $00, $00, $00, $00, $00, $00
+.db $00, $FF
+TileDataEnd:
+`
+	if got != want {
+		t.Errorf("unexpected output, got:\n%s", got)
+	}
+}
+
+func TestAssembly_PSGaidenDecompressor(t *testing.T) {
+	got := assembly.PSGaidenDecompressor().String()
+	if !strings.Contains(got, "\nPSGaidenDecompress:\n") || !strings.HasSuffix(got, "PSGaidenDecompressEnd:\n") {
+		t.Errorf("expected the decompressor routine labels, got:\n%s", got)
+	}
+}
+
+func TestAssembly_TilemapSTM(t *testing.T) {
+	data := make([]uint16, 32*28)
+	data[0] = 0x0001
+
+	got, err := assembly.TilemapSTM(data, 24)
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	want := `; Tilemap data, STM compressed, 24 rows and 32 columns (28 bytes)
+Tilemap:
+.db $20, $04, $01, $FD, $00, $FD, $00, $FD, $00, $FD, $00, $FD, $00, $FD, $00, $FD
+.db $00, $FD, $00, $FD, $00, $FD, $00, $FD, $00, $C9, $00, $00
+TilemapEnd:
+`
+	if got.String() != want {
+		t.Errorf("unexpected output, got:\n%s", got)
+	}
+}
+
+func TestAssembly_STMDecompressor(t *testing.T) {
+	got := assembly.STMDecompressor().String()
+	if !strings.Contains(got, "\nSTMDecompress:\n") || !strings.HasSuffix(got, "STMDecompressEnd:\n") {
+		t.Errorf("expected the decompressor routine labels, got:\n%s", got)
+	}
+}
+
+func TestAssembly_ZX0Decompressor(t *testing.T) {
+	got := assembly.ZX0Decompressor().String()
+	if !strings.Contains(got, "\nZX0Decompress:\n") || !strings.HasSuffix(got, "ZX0DecompressEnd:\n") {
+		t.Errorf("expected the decompressor routine labels, got:\n%s", got)
+	}
+}
+
+func TestAssembly_ZX7Decompressor(t *testing.T) {
+	got := assembly.ZX7Decompressor().String()
+	if !strings.Contains(got, "\nZX7Decompress:\n") || !strings.HasSuffix(got, "ZX7DecompressEnd:\n") {
+		t.Errorf("expected the decompressor routine labels, got:\n%s", got)
+	}
+}
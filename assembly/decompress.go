@@ -0,0 +1,429 @@
+package assembly
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mrcook/smstilemap/compress"
+)
+
+// CompressedData writes a block of compressed data as `.db` bytes.
+func CompressedData(label, description string, data []uint8) *strings.Builder {
+	return Options{}.CompressedData(label, description, data)
+}
+
+// CompressedData writes a block of compressed data, see CompressedData.
+func (o Options) CompressedData(label, description string, data []uint8) *strings.Builder {
+	var sb strings.Builder
+
+	o.heading(&sb, fmt.Sprintf("%s (%d bytes)", description, len(data)))
+	o.label(&sb, label)
+	o.bytes(&sb, data, Hex, 16)
+	o.label(&sb, label+"End")
+	return &sb
+}
+
+// TilemapSTM writes the name table data, up to the given number of rows, as
+// STM compressed data.
+func TilemapSTM(data []uint16, rows int) (*strings.Builder, error) {
+	return Options{}.TilemapSTM(data, rows)
+}
+
+// TilemapSTM writes the STM compressed name table data, see TilemapSTM.
+func (o Options) TilemapSTM(data []uint16, rows int) (*strings.Builder, error) {
+	if len(data) > rows*tilemapWidth {
+		data = data[:rows*tilemapWidth]
+	}
+	packed, err := compress.STMCompress(data, tilemapWidth)
+	if err != nil {
+		return nil, err
+	}
+	description := fmt.Sprintf("Tilemap data, STM compressed, %d rows and 32 columns", len(data)/tilemapWidth)
+	return o.CompressedData("Tilemap", description, packed), nil
+}
+
+// STMDecompressor writes the Z80 routine for decompressing STM tilemap data
+// directly to VRAM.
+func STMDecompressor() *strings.Builder {
+	return Options{}.STMDecompressor()
+}
+
+// STMDecompressor writes the STM routine in the dialect, see STMDecompressor.
+func (o Options) STMDecompressor() *strings.Builder {
+	var sb strings.Builder
+	sb.WriteString(o.routine(stmDecompressor))
+	return &sb
+}
+
+// PSGaidenDecompressor writes the Z80 routine for decompressing PSGaiden tile
+// data directly to VRAM.
+func PSGaidenDecompressor() *strings.Builder {
+	return Options{}.PSGaidenDecompressor()
+}
+
+// PSGaidenDecompressor writes the PSGaiden routine in the dialect, see
+// PSGaidenDecompressor.
+func (o Options) PSGaidenDecompressor() *strings.Builder {
+	var sb strings.Builder
+	sb.WriteString(o.routine(psgaidenDecompressor))
+	return &sb
+}
+
+// ZX0Decompressor writes the Z80 routine for decompressing ZX0 data to RAM.
+func ZX0Decompressor() *strings.Builder {
+	return Options{}.ZX0Decompressor()
+}
+
+// ZX0Decompressor writes the ZX0 routine in the dialect, see ZX0Decompressor.
+func (o Options) ZX0Decompressor() *strings.Builder {
+	var sb strings.Builder
+	sb.WriteString(o.routine(zx0Decompressor))
+	return &sb
+}
+
+// ZX7Decompressor writes the Z80 routine for decompressing ZX7 data to RAM.
+func ZX7Decompressor() *strings.Builder {
+	return Options{}.ZX7Decompressor()
+}
+
+// ZX7Decompressor writes the ZX7 routine in the dialect, see ZX7Decompressor.
+func (o Options) ZX7Decompressor() *strings.Builder {
+	var sb strings.Builder
+	sb.WriteString(o.routine(zx7Decompressor))
+	return &sb
+}
+
+const psgaidenDecompressor = `; PSGaiden tile decompressor
+; Decompresses the tiles to the VDP, which must already be set to the VRAM
+; write address for the first tile, e.g. $4000 | (tile number * 32).
+;   in: hl = PSGaiden compressed data
+; Uses a 32 byte RAM buffer, which can be set by defining PSGaidenBuffer.
+.ifndef PSGaidenBuffer
+.define PSGaidenBuffer $C000
+.endif
+PSGaidenDecompress:
+  ld c,(hl)               ; bc = number of tiles
+  inc hl
+  ld b,(hl)
+  inc hl
+  ld a,b
+  or c
+  ret z
+_PSGaidenTileLoop:
+  push bc
+  ld a,(hl)               ; method byte: 2-bits per bitplane, bitplane 0 first
+  inc hl
+  ld de,PSGaidenBuffer
+  ld b,4
+_PSGaidenPlaneLoop:
+  rlca                    ; move the bitplane method into bits 1-0
+  rlca
+  ld c,a                  ; c = remaining methods
+  and %11
+  jr z,_PSGaidenZero      ; %00
+  dec a
+  jr z,_PSGaidenOnes      ; %01
+  dec a
+  jr z,_PSGaidenRaw       ; %10
+  ld a,(hl)               ; %11: compressed
+  inc hl
+  cp $04
+  jr c,_PSGaidenCopy      ; $00-$03: copy of a bitplane
+  cp $10
+  jr c,_PSGaidenCommon
+  cp $14
+  jr c,_PSGaidenInverted  ; $10-$13: inverted copy of a bitplane
+_PSGaidenCommon:
+  push bc                 ; a = bitmask of the bytes using the common value
+  ld c,a
+  ld a,(hl)               ; common value
+  inc hl
+  push de
+  ld b,8
+_PSGaidenCommonFill:
+  ld (de),a               ; fill the bitplane with the common value
+  inc de
+  djnz _PSGaidenCommonFill
+  pop de
+  ld b,8
+_PSGaidenMaskLoop:
+  sla c                   ; bit set: byte is the common value
+  jr c,_PSGaidenMaskNext
+  ld a,(hl)               ; otherwise the next data byte
+  inc hl
+  ld (de),a
+_PSGaidenMaskNext:
+  inc de
+  djnz _PSGaidenMaskLoop
+  pop bc
+  jr _PSGaidenNextPlane
+_PSGaidenZero:
+  xor a
+  jr _PSGaidenFill
+_PSGaidenOnes:
+  ld a,$FF
+_PSGaidenFill:
+  push bc
+  ld b,8
+_PSGaidenFillLoop:
+  ld (de),a
+  inc de
+  djnz _PSGaidenFillLoop
+  pop bc
+  jr _PSGaidenNextPlane
+_PSGaidenRaw:
+  push bc
+  ld bc,8
+  ldir
+  pop bc
+  jr _PSGaidenNextPlane
+_PSGaidenCopy:
+  push bc
+  push hl
+  call _PSGaidenPlaneAddress
+  ld bc,8
+  ldir
+  pop hl
+  pop bc
+  jr _PSGaidenNextPlane
+_PSGaidenInverted:
+  push bc
+  push hl
+  call _PSGaidenPlaneAddress
+  ld b,8
+_PSGaidenInvertLoop:
+  ld a,(hl)
+  cpl
+  ld (de),a
+  inc hl
+  inc de
+  djnz _PSGaidenInvertLoop
+  pop hl
+  pop bc
+_PSGaidenNextPlane:
+  ld a,c
+  djnz _PSGaidenPlaneLoop
+  push hl                 ; write the interleaved bitplanes to the VDP
+  ld hl,PSGaidenBuffer
+  ld de,8
+  ld b,8
+_PSGaidenOutputRow:
+  push hl
+  ld a,(hl)
+  out ($BE),a
+  add hl,de
+  ld a,(hl)
+  out ($BE),a
+  add hl,de
+  ld a,(hl)
+  out ($BE),a
+  add hl,de
+  ld a,(hl)
+  out ($BE),a
+  pop hl
+  inc hl
+  djnz _PSGaidenOutputRow
+  pop hl
+  pop bc                  ; next tile
+  dec bc
+  ld a,b
+  or c
+  jp nz,_PSGaidenTileLoop
+  ret
+_PSGaidenPlaneAddress:    ; hl = PSGaidenBuffer + (a & 3) * 8
+  and %11
+  add a,a
+  add a,a
+  add a,a
+  ld l,a
+  ld h,0
+  ld bc,PSGaidenBuffer
+  add hl,bc
+  ret
+PSGaidenDecompressEnd:
+`
+
+const stmDecompressor = `; STM tilemap decompressor
+; Decompresses the tilemap entries to the VDP, which must already be set to the
+; VRAM write address of the name table, e.g. $7800. Call with the display off,
+; or during VBlank.
+;   in: hl = STM compressed data
+STMDecompress:
+  inc hl                  ; skip the map width
+  ld d,0                  ; d = current high byte
+_STMLoop:
+  ld a,(hl)               ; command: type in bits 1-0, value in bits 7-2
+  inc hl
+  ld c,a
+  and %11
+  jr z,_STMRaw            ; %00
+  dec a
+  jr z,_STMRun            ; %01
+  dec a
+  jr z,_STMIncrement      ; %10
+  call _STMValue          ; %11: set the high byte
+  ld d,a
+  jr _STMLoop
+_STMRaw:
+  or c
+  ret z                   ; $00: end of data
+  call _STMValue
+  ld b,a
+_STMRawLoop:
+  ld a,(hl)
+  inc hl
+  out ($BE),a
+  ld a,d
+  out ($BE),a
+  djnz _STMRawLoop
+  jr _STMLoop
+_STMRun:
+  call _STMValue
+  add a,2
+  ld b,a
+  ld e,(hl)
+  inc hl
+_STMRunLoop:
+  ld a,e
+  out ($BE),a
+  ld a,d
+  out ($BE),a
+  djnz _STMRunLoop
+  jr _STMLoop
+_STMIncrement:
+  call _STMValue
+  add a,2
+  ld b,a
+  ld e,(hl)
+  inc hl
+_STMIncrementLoop:
+  ld a,e
+  out ($BE),a
+  ld a,d
+  out ($BE),a
+  inc e
+  djnz _STMIncrementLoop
+  jr _STMLoop
+_STMValue:                ; a = command value, bits 7-2 of c
+  ld a,c
+  rrca
+  rrca
+  and %00111111
+  ret
+STMDecompressEnd:
+`
+
+const zx0Decompressor = `; ZX0 decompressor, the "standard" version by Einar Saukas & Urusergi
+; Decompresses the data to RAM.
+;   in: hl = ZX0 compressed data
+;       de = destination address
+ZX0Decompress:
+  ld bc,$FFFF             ; preserve default offset 1
+  push bc
+  inc bc
+  ld a,$80
+_ZX0Literals:
+  call _ZX0Elias          ; obtain length
+  ldir                    ; copy literals
+  add a,a                 ; copy from last offset or new offset?
+  jr c,_ZX0NewOffset
+  call _ZX0Elias          ; obtain length
+_ZX0Copy:
+  ex (sp),hl              ; preserve source, restore offset
+  push hl                 ; preserve offset
+  add hl,de               ; calculate destination - offset
+  ldir                    ; copy from offset
+  pop hl                  ; restore offset
+  ex (sp),hl              ; preserve offset, restore source
+  add a,a                 ; copy from literals or new offset?
+  jr nc,_ZX0Literals
+_ZX0NewOffset:
+  pop bc                  ; discard last offset
+  ld c,$FE                ; prepare negative offset
+  call _ZX0EliasLoop      ; obtain offset MSB
+  inc c
+  ret z                   ; check end marker
+  ld b,c
+  ld c,(hl)               ; obtain offset LSB
+  inc hl
+  rr b                    ; last offset bit becomes first length bit
+  rr c
+  push bc                 ; preserve new offset
+  ld bc,1                 ; obtain length
+  call nc,_ZX0EliasBacktrack
+  inc bc
+  jr _ZX0Copy
+_ZX0Elias:
+  inc c                   ; interlaced Elias gamma coding
+_ZX0EliasLoop:
+  add a,a
+  jr nz,_ZX0EliasSkip
+  ld a,(hl)               ; load another group of 8 bits
+  inc hl
+  rla
+_ZX0EliasSkip:
+  ret c
+_ZX0EliasBacktrack:
+  add a,a
+  rl c
+  rl b
+  jr _ZX0EliasLoop
+ZX0DecompressEnd:
+`
+
+const zx7Decompressor = `; ZX7 decompressor, the "standard" version by Einar Saukas
+; Decompresses the data to RAM.
+;   in: hl = ZX7 compressed data
+;       de = destination address
+ZX7Decompress:
+  ld a,$80
+_ZX7CopyByteLoop:
+  ldi                     ; copy literal byte
+_ZX7MainLoop:
+  call _ZX7NextBit
+  jr nc,_ZX7CopyByteLoop  ; next bit indicates either literal or sequence
+  push de                 ; determine number of bits used for length
+  ld bc,0
+  ld d,b
+_ZX7LenSizeLoop:
+  inc d
+  call _ZX7NextBit
+  jr nc,_ZX7LenSizeLoop
+_ZX7LenValueLoop:
+  call nc,_ZX7NextBit     ; determine length
+  rl c
+  rl b
+  jr c,_ZX7Exit           ; check end marker
+  dec d
+  jr nz,_ZX7LenValueLoop
+  inc bc                  ; adjust length
+  ld e,(hl)               ; load offset flag (1 bit) + offset value (7 bits)
+  inc hl
+  .db $CB, $33            ; sll e
+  jr nc,_ZX7OffsetEnd     ; if offset flag is set, load 4 extra bits
+  ld d,$10                ; bit marker to load 4 bits
+_ZX7RldNextBit:
+  call _ZX7NextBit
+  rl d                    ; insert next bit into D
+  jr nc,_ZX7RldNextBit    ; repeat 4 times, until bit marker is out
+  inc d                   ; add 128 to DE
+  srl d                   ; retrieve fourth bit from D
+_ZX7OffsetEnd:
+  rr e                    ; insert fourth bit into E
+  ex (sp),hl              ; store source, restore destination
+  push hl                 ; store destination
+  sbc hl,de               ; HL = destination - offset - 1
+  pop de                  ; DE = destination
+  ldir
+_ZX7Exit:
+  pop hl                  ; restore source address (compressed data)
+  jr nc,_ZX7MainLoop
+_ZX7NextBit:
+  add a,a                 ; check next bit
+  ret nz                  ; no more bits left?
+  ld a,(hl)               ; load another group of 8 bits
+  inc hl
+  rla
+  ret
+ZX7DecompressEnd:
+`
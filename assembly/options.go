@@ -0,0 +1,186 @@
+package assembly
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dialect is the assembler syntax used for the output.
+type Dialect int
+
+const (
+	WLADX     Dialect = iota // WLA-DX, the default
+	Sjasmplus                // sjasmplus
+	Pasmo                    // Pasmo
+	Z80asm                   // z88dk z80asm
+)
+
+// Radix is the number base used for the data values.
+type Radix int
+
+const (
+	DefaultRadix Radix = iota // hex for tiles and sprite frames, binary for tilemaps and palettes
+	Hex
+	Binary
+	Decimal
+)
+
+// CommentLevel sets how much of the output is commented.
+type CommentLevel int
+
+const (
+	AllComments   CommentLevel = iota // describe each block, and number its tiles, rows, etc.
+	BriefComments                     // a single line describing each block
+	NoComments
+)
+
+// Options controls the syntax and layout of the assembly output. The zero
+// value writes WLA-DX syntax using the default layout of each block.
+type Options struct {
+	Dialect       Dialect
+	LabelPrefix   string // prefixed to the data labels and constants, e.g. `Level1`
+	Radix         Radix
+	ValuesPerLine int // 0 uses the default for each block
+	Comments      CommentLevel
+}
+
+// the syntax differences between the dialects
+type syntax struct {
+	indent string // for the data directives and instructions
+	bytes  string
+	words  string
+}
+
+var syntaxes = map[Dialect]syntax{
+	WLADX:     {indent: "", bytes: ".db", words: ".dw"},
+	Sjasmplus: {indent: "  ", bytes: "db", words: "dw"},
+	Pasmo:     {indent: "  ", bytes: "defb", words: "defw"},
+	Z80asm:    {indent: "  ", bytes: "defb", words: "defw"},
+}
+
+func (o Options) syntax() syntax {
+	if s, ok := syntaxes[o.Dialect]; ok {
+		return s
+	}
+	return syntaxes[WLADX]
+}
+
+// returns the name with the label prefix
+func (o Options) name(name string) string {
+	return o.LabelPrefix + name
+}
+
+// writes a label line, with the label prefix
+func (o Options) label(sb *strings.Builder, name string) {
+	sb.WriteString(o.name(name) + ":\n")
+}
+
+// writes the comment lines describing a block, where only the first line is
+// written for brief comments
+func (o Options) heading(sb *strings.Builder, lines ...string) {
+	for i, line := range lines {
+		if o.Comments == NoComments || (o.Comments == BriefComments && i > 0) {
+			break
+		}
+		sb.WriteString("; " + line + "\n")
+	}
+}
+
+// writes a comment within a block, such as a tile number, which is only
+// written when all comments are enabled
+func (o Options) note(sb *strings.Builder, format string, a ...any) {
+	if o.Comments == AllComments {
+		sb.WriteString("; " + fmt.Sprintf(format, a...) + "\n")
+	}
+}
+
+// returns the constant definition, without the label prefix
+func (o Options) define(name, value string) string {
+	switch o.Dialect {
+	case Sjasmplus, Pasmo:
+		return fmt.Sprintf("%s equ %s", name, value)
+	case Z80asm:
+		return fmt.Sprintf("  defc %s = %s", name, value)
+	default:
+		return fmt.Sprintf(".define %s %s", name, value)
+	}
+}
+
+// writes the bytes as data lines, using the radix (or the block default) and
+// the values per line (or the block default)
+func (o Options) bytes(sb *strings.Builder, data []uint8, radix Radix, perLine int) {
+	values := make([]string, len(data))
+	for i, b := range data {
+		values[i] = o.format(int(b), 8, radix)
+	}
+	o.dataLines(sb, o.syntax().bytes, values, perLine)
+}
+
+// writes the words as data lines, see bytes
+func (o Options) words(sb *strings.Builder, data []uint16, radix Radix, perLine int) {
+	values := make([]string, len(data))
+	for i, w := range data {
+		values[i] = o.format(int(w), 16, radix)
+	}
+	o.dataLines(sb, o.syntax().words, values, perLine)
+}
+
+func (o Options) dataLines(sb *strings.Builder, directive string, values []string, perLine int) {
+	if o.ValuesPerLine > 0 {
+		perLine = o.ValuesPerLine
+	}
+	for i := 0; i < len(values); i += perLine {
+		line := strings.Join(values[i:min(i+perLine, len(values))], ", ")
+		sb.WriteString(fmt.Sprintf("%s%s %s\n", o.syntax().indent, directive, line))
+	}
+}
+
+// returns the value using the radix, or the block default
+func (o Options) format(value, bits int, radix Radix) string {
+	if o.Radix != DefaultRadix {
+		radix = o.Radix
+	}
+	switch radix {
+	case Binary:
+		return fmt.Sprintf("%%%0*b", bits, value)
+	case Decimal:
+		return fmt.Sprintf("%d", value)
+	default:
+		return fmt.Sprintf("$%0*X", bits/4, value)
+	}
+}
+
+// routine returns a Z80 routine, written in WLA-DX syntax, converted to the
+// dialect and comment level. The routine labels do not use the label prefix,
+// so a routine should only be included once in a program.
+func (o Options) routine(src string) string {
+	var sb strings.Builder
+
+	for i, line := range strings.Split(strings.TrimSuffix(src, "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, ";") {
+			if o.Comments == AllComments || (o.Comments == BriefComments && i == 0) {
+				sb.WriteString(line + "\n")
+			}
+			continue
+		}
+		if o.Comments != AllComments {
+			if code, _, found := strings.Cut(line, ";"); found {
+				line = strings.TrimRight(code, " ")
+			}
+		}
+
+		fields := strings.Fields(trimmed)
+		switch {
+		case o.Dialect == WLADX:
+		case fields[0] == ".ifndef" || fields[0] == ".endif":
+			continue // the other dialects always define the constants
+		case fields[0] == ".define":
+			line = o.define(fields[1], fields[2])
+		case fields[0] == ".db":
+			line = strings.Replace(line, ".db", o.syntax().bytes, 1)
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,76 @@
+package assembly_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mrcook/smstilemap/assembly"
+)
+
+func TestOptions_Dialects(t *testing.T) {
+	palette := [32]uint8{0x3F}
+	defines := []assembly.Define{{Name: "NameTableAddress", Value: 0x3800, Hex: true}}
+
+	tests := map[assembly.Dialect][]string{
+		assembly.WLADX:     {".define NameTableAddress $3800\n", "\n.db %00111111, %00000000"},
+		assembly.Sjasmplus: {"NameTableAddress equ $3800\n", "\n  db %00111111, %00000000"},
+		assembly.Pasmo:     {"NameTableAddress equ $3800\n", "\n  defb %00111111, %00000000"},
+		assembly.Z80asm:    {"  defc NameTableAddress = $3800\n", "\n  defb %00111111, %00000000"},
+	}
+	for dialect, want := range tests {
+		o := assembly.Options{Dialect: dialect}
+		if got := o.Defines("VRAM", defines).String(); !strings.Contains(got, want[0]) {
+			t.Errorf("dialect %d: unexpected define, got:\n%s", dialect, got)
+		}
+		if got := o.Palettes(palette).String(); !strings.Contains(got, want[1]) {
+			t.Errorf("dialect %d: unexpected data, got:\n%s", dialect, got)
+		}
+	}
+}
+
+func TestOptions_Layout(t *testing.T) {
+	o := assembly.Options{
+		LabelPrefix:   "Level1",
+		Radix:         assembly.Decimal,
+		ValuesPerLine: 8,
+		Comments:      assembly.NoComments,
+	}
+	data := make([]uint16, 32*28)
+	data[1] = 0x0201
+
+	got := o.Tilemap(data, 1).String()
+	want := `Level1Tilemap:
+.dw 0, 513, 0, 0, 0, 0, 0, 0
+.dw 0, 0, 0, 0, 0, 0, 0, 0
+.dw 0, 0, 0, 0, 0, 0, 0, 0
+.dw 0, 0, 0, 0, 0, 0, 0, 0
+Level1TilemapEnd:
+`
+	if got != want {
+		t.Errorf("unexpected output, got:\n%s", got)
+	}
+}
+
+func TestOptions_BriefComments(t *testing.T) {
+	o := assembly.Options{Comments: assembly.BriefComments}
+
+	got := o.TilesFrom(make([]uint8, 32), 0).String()
+	if !strings.HasPrefix(got, "; Tile data (characters)\nTileData:\n.db $00") {
+		t.Errorf("expected only the block heading comment, got:\n%s", got)
+	}
+}
+
+func TestOptions_Routines(t *testing.T) {
+	o := assembly.Options{Dialect: assembly.Pasmo, Comments: assembly.NoComments}
+
+	got := o.PSGaidenDecompressor().String()
+	if strings.Contains(got, ";") || strings.Contains(got, ".ifndef") {
+		t.Errorf("expected no comments or WLA-DX directives, got:\n%s", got)
+	}
+	if !strings.HasPrefix(got, "PSGaidenBuffer equ $C000\nPSGaidenDecompress:\n") {
+		t.Errorf("expected the buffer constant in the dialect, got:\n%s", got)
+	}
+	if got := o.ZX7Decompressor().String(); !strings.Contains(got, "\n  defb $CB, $33\n") {
+		t.Errorf("expected data directives in the dialect, got:\n%s", got)
+	}
+}
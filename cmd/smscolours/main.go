@@ -1,14 +1,14 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"image"
+	"image/color"
 	"image/png"
 	"os"
 	"path/filepath"
-
-	"github.com/mrcook/smstilemap/gg"
-	"github.com/mrcook/smstilemap/sms"
+	"strings"
 )
 
 const version = "0.1.0"
@@ -16,66 +16,143 @@ const version = "0.1.0"
 var usage = `
 %s v%s
 
-This small utility will generate PNG files for the colours generated by the
-Sega Master System and Game Gear consoles. These files can then be used as
-palette files for your favourite sprite editor, e.g. Krita, LibreSprite.
+This small utility will generate palette files for the colours generated by
+the Sega Master System and Game Gear consoles. These files can then be used
+with your favourite sprite editor, e.g. Aseprite, GIMP, Krita, LibreSprite.
+
+Please specify an output directory for the palette files:
+  %s [options] /path/to/dir/
 
-Please specify an output directory for the PNG files:
-  %s /path/to/dir/
+Options:
 `
 
+// palette file formats, with their file extensions
+var formatExtensions = map[string]string{
+	"png": ".png",
+	"gpl": ".gpl",
+	"pal": ".pal",
+	"txt": ".txt",
+	"hex": ".hex",
+}
+
+// the most colours the palette file formats can hold, as the JASC readers
+// stop after 256 colours, and Paint.NET only loads the first 96. The formats
+// not listed have no limit.
+var formatLimits = map[string]struct {
+	name    string
+	colours int
+}{
+	"pal": {name: "JASC", colours: 256},
+	"txt": {name: "Paint.NET", colours: 96},
+}
+
+var (
+	formats = flag.String("formats", "png", "Palette file formats, comma separated: png, gpl (GIMP), pal (JASC), txt (Paint.NET), hex, or all")
+	systems = flag.String("systems", "sms,gg", "Consoles to write the colours for, comma separated: sms, gg")
+)
+
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Printf(usage, os.Args[0], version, os.Args[0])
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), usage, os.Args[0], version, os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
 		os.Exit(0)
 	}
-	outputDirectory := os.Args[1]
+	outputDirectory := flag.Arg(0)
 	if info, err := os.Stat(outputDirectory); err != nil || !info.IsDir() {
 		fmt.Println("output directory must exist")
 		os.Exit(1)
 	}
 
-	img := smsColoursToImage()
-	err := saveImageToFilename(img, filepath.Join(outputDirectory, "sms-colours.png"))
+	palettes, err := selectedPalettes(*systems)
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(2)
 	}
-
-	img = ggColoursToImage()
-	err = saveImageToFilename(img, filepath.Join(outputDirectory, "gg-colours.png"))
+	selected, err := selectedFormats(*formats)
 	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(2)
 	}
-}
-
-func smsColoursToImage() image.Image {
-	width := 16
-	height := 4
 
-	img := image.NewNRGBA(image.Rectangle{
-		Min: image.Point{X: 0, Y: 0},
-		Max: image.Point{X: width, Y: height},
-	})
+	for name, p := range palettes {
+		for _, format := range selected {
+			filename := filepath.Join(outputDirectory, name+"-colours"+formatExtensions[format])
+			if limit, ok := formatLimits[format]; ok && len(p.colours) > limit.colours {
+				fmt.Printf("skipping %s: the %s format holds at most %d colours, the %s has %d\n", filepath.Base(filename), limit.name, limit.colours, p.title, len(p.colours))
+				continue
+			}
+			if err := writePalette(p, format, filename); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+	}
+}
 
-	var x, y int
-	for _, colour := range sms.AllColours {
-		img.Set(x, y, colour.Index)
+// returns the palettes for the consoles, keyed on their filename prefix
+func selectedPalettes(systems string) (map[string]palette, error) {
+	palettes := make(map[string]palette)
+	for _, system := range strings.Split(systems, ",") {
+		switch strings.TrimSpace(system) {
+		case "sms":
+			palettes["sms"] = smsPalette()
+		case "gg":
+			palettes["gg"] = ggPalette()
+		default:
+			return nil, fmt.Errorf("unknown system '%s', must be one of: sms, gg", system)
+		}
+	}
+	return palettes, nil
+}
 
-		x++
-		if x >= width {
-			x = 0
-			y++
+// returns the formats in the comma separated list, where `all` selects every
+// format. Each format is only returned once.
+func selectedFormats(formats string) ([]string, error) {
+	var selected []string
+	added := make(map[string]bool)
+	for _, format := range strings.Split(formats, ",") {
+		format = strings.TrimSpace(format)
+		names := []string{format}
+		if format == "all" {
+			names = []string{"png", "gpl", "pal", "txt", "hex"}
+		} else if _, ok := formatExtensions[format]; !ok {
+			return nil, fmt.Errorf("unknown format '%s', must be one of: png, gpl, pal, txt, hex, all", format)
+		}
+		for _, name := range names {
+			if !added[name] {
+				selected = append(selected, name)
+				added[name] = true
+			}
 		}
 	}
+	return selected, nil
+}
 
-	return img
+func writePalette(p palette, format, filename string) error {
+	switch format {
+	case "gpl":
+		return writeGIMP(p, filename)
+	case "pal":
+		return writeJASC(p, filename)
+	case "txt":
+		return writePaintNET(p, filename)
+	case "hex":
+		return writeHex(p, filename)
+	default:
+		return saveImageToFilename(paletteToImage(p), filename)
+	}
 }
 
-func ggColoursToImage() image.Image {
-	width := 16 //
-	height := 256
+// returns the colours as a one pixel per colour image, in rows of the
+// palette columns.
+func paletteToImage(p palette) image.Image {
+	width := p.columns
+	height := len(p.colours) / p.columns
 
 	img := image.NewNRGBA(image.Rectangle{
 		Min: image.Point{X: 0, Y: 0},
@@ -83,8 +160,8 @@ func ggColoursToImage() image.Image {
 	})
 
 	var x, y int
-	for _, colour := range gg.AllColours {
-		img.Set(x, y, colour.Index)
+	for _, colour := range p.colours {
+		img.Set(x, y, color.NRGBA{R: colour.r, G: colour.g, B: colour.b, A: 255})
 
 		x++
 		if x >= width {
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mrcook/smstilemap/gg"
+	"github.com/mrcook/smstilemap/sms"
+)
+
+// paletteColour is a console colour, named using its colour RAM value.
+type paletteColour struct {
+	name    string
+	r, g, b uint8
+}
+
+// palette is the full list of colours for a console.
+type palette struct {
+	title   string // e.g. `Sega Master System`
+	columns int    // colours in each row of the PNG and GIMP palettes
+	colours []paletteColour
+}
+
+func smsPalette() palette {
+	p := palette{title: "Sega Master System", columns: 16}
+	for _, c := range sms.AllColours {
+		p.colours = append(p.colours, paletteColour{name: fmt.Sprintf("SMS $%02X", c.Index.SMS()), r: c.R, g: c.G, b: c.B})
+	}
+	return p
+}
+
+func ggPalette() palette {
+	p := palette{title: "Sega Game Gear", columns: 16}
+	for _, c := range gg.AllColours {
+		p.colours = append(p.colours, paletteColour{name: fmt.Sprintf("GG $%03X", c.Index.GG()), r: c.R, g: c.G, b: c.B})
+	}
+	return p
+}
+
+// writes a GIMP palette, with each colour named by its colour RAM value.
+func writeGIMP(p palette, filename string) error {
+	var sb strings.Builder
+
+	sb.WriteString("GIMP Palette\n")
+	sb.WriteString(fmt.Sprintf("Name: %s\n", p.title))
+	sb.WriteString(fmt.Sprintf("Columns: %d\n", p.columns))
+	sb.WriteString("#\n")
+	for _, c := range p.colours {
+		sb.WriteString(fmt.Sprintf("%3d %3d %3d\t%s\n", c.r, c.g, c.b, c.name))
+	}
+	return writeFile(filename, sb.String())
+}
+
+// writes a JASC (Paint Shop Pro) palette, which has no colour names, using
+// CRLF line endings.
+func writeJASC(p palette, filename string) error {
+	var sb strings.Builder
+
+	sb.WriteString("JASC-PAL\r\n")
+	sb.WriteString("0100\r\n")
+	sb.WriteString(fmt.Sprintf("%d\r\n", len(p.colours)))
+	for _, c := range p.colours {
+		sb.WriteString(fmt.Sprintf("%d %d %d\r\n", c.r, c.g, c.b))
+	}
+	return writeFile(filename, sb.String())
+}
+
+// writes a Paint.NET palette of AARRGGBB values, with a comment line naming
+// each colour. Paint.NET only loads the first 96 colours.
+func writePaintNET(p palette, filename string) error {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("; Paint.NET Palette File: %s\n", p.title))
+	sb.WriteString(fmt.Sprintf("; Colours: %d\n", len(p.colours)))
+	for _, c := range p.colours {
+		sb.WriteString(fmt.Sprintf("; %s\n", c.name))
+		sb.WriteString(fmt.Sprintf("FF%02X%02X%02X\n", c.r, c.g, c.b))
+	}
+	return writeFile(filename, sb.String())
+}
+
+// writes a list of RRGGBB values, one per line, as used by Lospec and
+// Aseprite. The format has no colour names.
+func writeHex(p palette, filename string) error {
+	var sb strings.Builder
+
+	for _, c := range p.colours {
+		sb.WriteString(fmt.Sprintf("%02x%02x%02x\n", c.r, c.g, c.b))
+	}
+	return writeFile(filename, sb.String())
+}
+
+func writeFile(filename, data string) error {
+	if err := os.WriteFile(filename, []byte(data), 0644); err != nil {
+		return fmt.Errorf("error writing palette file: %w", err)
+	}
+	return nil
+}
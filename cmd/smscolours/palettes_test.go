@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var testPalette = palette{
+	title:   "Test",
+	columns: 2,
+	colours: []paletteColour{
+		{name: "SMS $00", r: 0, g: 0, b: 0},
+		{name: "SMS $3F", r: 255, g: 255, b: 255},
+		{name: "SMS $03", r: 255, g: 0, b: 0},
+	},
+}
+
+func TestWritePalette(t *testing.T) {
+	table := []struct {
+		format string
+		want   string
+	}{
+		{"gpl", "GIMP Palette\nName: Test\nColumns: 2\n#\n  0   0   0\tSMS $00\n255 255 255\tSMS $3F\n255   0   0\tSMS $03\n"},
+		{"pal", "JASC-PAL\r\n0100\r\n3\r\n0 0 0\r\n255 255 255\r\n255 0 0\r\n"},
+		{"txt", "; Paint.NET Palette File: Test\n; Colours: 3\n; SMS $00\nFF000000\n; SMS $3F\nFFFFFFFF\n; SMS $03\nFFFF0000\n"},
+		{"hex", "000000\nffffff\nff0000\n"},
+	}
+
+	for _, test := range table {
+		t.Run(test.format, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "test"+formatExtensions[test.format])
+			if err := writePalette(testPalette, test.format, filename); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			data, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if string(data) != test.want {
+				t.Errorf("unexpected palette file, got:\n%q", data)
+			}
+		})
+	}
+}
+
+func TestSelectedFormats(t *testing.T) {
+	table := map[string][]string{
+		"png":         {"png"},
+		"gpl, hex":    {"gpl", "hex"},
+		"all":         {"png", "gpl", "pal", "txt", "hex"},
+		"hex,all":     {"hex", "png", "gpl", "pal", "txt"},
+		"gpl,gpl,png": {"gpl", "png"},
+	}
+	for formats, want := range table {
+		got, err := selectedFormats(formats)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %q", formats, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: expected %v, got %v", formats, want, got)
+		}
+	}
+
+	t.Run("with an unknown format", func(t *testing.T) {
+		_, err := selectedFormats("gpl,act")
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "unknown format 'act', must be one of: png, gpl, pal, txt, hex, all" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mrcook/smstilemap/cmd/smstilemap/processor"
+)
+
+// decode rebuilds a PNG image from the binary tiles, tilemap, and palette
+// files, returning the program exit code.
+func decode(args []string) int {
+	flags := flag.NewFlagSet("decode", flag.ExitOnError)
+	tilesFilename := flags.String("tiles", "", "Tile data binary filename")
+	tilemapFilename := flags.String("tilemap", "", "Tilemap binary filename, little-endian words")
+	paletteFilename := flags.String("palette", "", "Palette binary filename, 16 or 32 bytes (32 or 64 for the Game Gear)")
+	target := flags.String("target", "sms", "Target console of the palette data: sms, gg")
+	outputDir := flags.String("out", "", "Output directory for the PNG image (default: tilemap filename directory)")
+	screenHeight := flags.Int("height", 192, "Screen height in pixels: 192, 224, 240")
+	tileOffset := flags.Int("offset", 0, "Tile number of the first tile in the tile data")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of %s decode:\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	if len(*tilesFilename) == 0 || len(*tilemapFilename) == 0 || len(*paletteFilename) == 0 {
+		fmt.Println("ERROR: 'tiles', 'tilemap', and 'palette' filenames are required!")
+		fmt.Println()
+		flags.Usage()
+		return 2
+	}
+
+	pro := processor.New(*tilemapFilename, *outputDir)
+	if err := pro.SetScreenHeight(*screenHeight); err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	if err := pro.SetTarget(*target); err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	if err := pro.SetTileOffset(*tileOffset); err != nil {
+		fmt.Println(err)
+		return 2
+	}
+	if err := pro.CreateOutputDirectory(); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	if err := pro.DecodeBinary(*tilesFilename, *tilemapFilename, *paletteFilename); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	err := pro.SaveDecodedImage()
+	printWarnings(pro)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	return 0
+}
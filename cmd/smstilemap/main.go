@@ -4,6 +4,9 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/mrcook/smstilemap/cmd/smstilemap/processor"
 )
@@ -14,13 +17,65 @@ var (
 	inputFilename   *string
 	outputDirectory *string
 	outputFormat    *string
+	conversionMode  *string
+	target          *string
+	colourMatch     *string
+	spriteSize      *string
+	frameSize       *string
+	priorityMask    *string
+	priorityColour  *string
+	fixedPalette    *string
+	screenHeight    *int
+	mapStrips       *string
+	reserveTiles    *string
+	tileOffset      *int
+	tilemapRows     *int
+	compressTiles   *string
+	compressTilemap *string
+	compression     *string
+	decompressors   *string
+	asmDialect      *string
+	asmPrefix       *string
+	asmRadix        *string
+	asmValues       *int
+	asmComments     *string
+	nameTableAddr   *string
+	satAddr         *string
 	testLibrary     *bool
 )
 
 func init() {
-	inputFilename = flag.String("in", "", "Input PNG filename")
+	if isDecodeCommand() {
+		return
+	}
+
+	inputFilename = flag.String("in", "", "Input PNG, GIF, BMP, or Aseprite filename, or a Tiled .tmx map for the background and map modes")
 	outputDirectory = flag.String("out", "", "Output directory for generated files (default: input filename directory)")
-	outputFormat = flag.String("fmt", "asm", "Output format: asm, tiles")
+	outputFormat = flag.String("fmt", "asm", "Output format: asm, bin, c, json, tiled, tiles")
+	compressTiles = flag.String("compress-tiles", "none", "Tile data compression for the asm and bin output: none, psgaiden")
+	compressTilemap = flag.String("compress-tilemap", "none", "Tilemap compression for the asm and bin output: none, stm")
+	compression = flag.String("compress", "none", "Compression for the other asm and bin data blocks (tiles, tilemap, palette, sprite frames): none, zx0, zx7")
+	decompressors = flag.String("decompressors", "include", "Z80 decompression routines for the compressed asm and bin data: include, none (when included from another conversion)")
+	asmDialect = flag.String("asm-dialect", "wladx", "Assembler syntax for the asm output: wladx, sjasmplus, pasmo, z80asm")
+	asmPrefix = flag.String("asm-prefix", "", "Prefix for the asm labels and constants, e.g. Level1")
+	asmRadix = flag.String("asm-radix", "default", "Number base for the asm data values: default, hex, bin, dec")
+	asmValues = flag.Int("asm-values", 0, "Data values per line in the asm output (default: for each block)")
+	asmComments = flag.String("asm-comments", "all", "Comments in the asm output: all, brief, none")
+	tilemapRows = flag.Int("rows", 0, "Tilemap rows in the bin, c, json, and tiled output: the visible rows (24), or the full name table (28) (default: visible rows)")
+	conversionMode = flag.String("mode", "background", "Conversion mode: background, sprites, map")
+	target = flag.String("target", "sms", "Target console: sms, gg (the Game Gear, with 12-bit colours and a 160x144 screen)")
+	colourMatch = flag.String("match", "threshold", "Colour matching of the image colours to the console colours: threshold, euclidean, weighted, ciede2000")
+	spriteSize = flag.String("sprite", "8x8", "Sprite size when using the sprites mode: 8x8, 8x16")
+	frameSize = flag.String("frame", "", "Sprite frame size in pixels, e.g. 16x16 (default: the sprite size)")
+	mapStrips = flag.String("strips", "rows", "Map strips to output when using the map mode: rows, cols, both")
+	screenHeight = flag.Int("height", 192, "Screen height in pixels: 192, 224, 240")
+	tileOffset = flag.Int("offset", 0, "First tile number for the converted tiles, e.g. to load them after a font")
+	reserveTiles = flag.String("reserve", "", "Reserve VRAM tiles the background must not use, as NAME:FIRST:COUNT, e.g. Font:0:96,SpriteTiles:256:64")
+	nameTableAddr = flag.String("nametable", "", "Name table VRAM address, e.g. $3800 (default: for the screen height)")
+	satAddr = flag.String("sat", "", "Sprite attribute table VRAM address, e.g. $3F00 (default \"$3F00\")")
+	priorityMask = flag.String("priority", "", "Priority mask image filename, marking the tiles to be drawn in front of sprites")
+	priorityColour = flag.String("priority-colour", "", "Priority mask marker colour, e.g. #FF00FF (default: any non-black pixel)")
+	fixedPalette = flag.String("palette", "", "Fixed palette file, shared with other assets: a PNG strip, GIMP .gpl, or CRAM .bin (default: build the palette from the image)")
 	testLibrary = flag.Bool("test", false, "Test SMS library by generating a new PNG file")
 	v := flag.Bool("v", false, "Display version number")
 
@@ -40,6 +95,10 @@ func init() {
 }
 
 func main() {
+	if isDecodeCommand() {
+		os.Exit(decode(os.Args[2:]))
+	}
+
 	pro := processor.New(*inputFilename, *outputDirectory)
 
 	if err := pro.CreateOutputDirectory(); err != nil {
@@ -47,12 +106,108 @@ func main() {
 		os.Exit(1)
 	}
 
-	if err := pro.PngToSMS(); err != nil {
+	if err := pro.SetScreenHeight(*screenHeight); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(2)
+	}
+
+	if err := pro.SetTarget(*target); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	if err := pro.SetColourMatch(*colourMatch); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	if err := pro.SetTileOffset(*tileOffset); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	if err := pro.SetTileCompression(*compressTiles); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := pro.SetTilemapCompression(*compressTilemap); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := pro.SetCompression(*compression); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	if err := pro.SetDecompressors(*decompressors); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	if err := setVRAMLayout(pro); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	if err := setAssemblyOptions(pro); err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+
+	if len(*fixedPalette) > 0 {
+		if err := pro.SetPalette(*fixedPalette); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	if len(*priorityMask) > 0 {
+		if err := pro.SetPriorityMask(*priorityMask, *priorityColour); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 	}
 
-	if *testLibrary {
+	switch *conversionMode {
+	case "background":
+		convert := pro.PngToSMS
+		if isTiledMap() {
+			convert = pro.TmxToSMS
+		}
+		if err := convert(); err != nil {
+			printWarnings(pro)
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case "sprites":
+		if err := convertSprites(pro); err != nil {
+			printWarnings(pro)
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	case "map":
+		if err := pro.SetMapStrips(*mapStrips); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+		convert := pro.PngToMap
+		if isTiledMap() {
+			convert = pro.TmxToMap
+		}
+		if err := convert(); err != nil {
+			printWarnings(pro)
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Println("ERROR: 'mode' unknown conversion mode!")
+		fmt.Println()
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	printWarnings(pro)
+
+	if *testLibrary && *conversionMode != "sprites" {
 		if err := pro.SaveTilemapToImage(); err != nil {
 			fmt.Println(err)
 			os.Exit(1)
@@ -64,6 +219,18 @@ func main() {
 	switch *outputFormat {
 	case "asm":
 		err = pro.ToAssembly()
+	case "bin":
+		setTilemapRows(pro)
+		err = pro.ToBinary()
+	case "c":
+		setTilemapRows(pro)
+		err = pro.ToCSource()
+	case "json":
+		setTilemapRows(pro)
+		err = pro.ToJSON()
+	case "tiled":
+		setTilemapRows(pro)
+		err = pro.ToTiled()
 	case "tiles":
 		err = pro.SaveTilesToImage()
 	default:
@@ -78,3 +245,115 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+func setTilemapRows(pro *processor.Processor) {
+	if *tilemapRows > 0 {
+		if err := pro.SetTilemapRows(*tilemapRows); err != nil {
+			fmt.Println(err)
+			os.Exit(2)
+		}
+	}
+}
+
+// the decode command rebuilds an image from binary files, using its own flags
+func isDecodeCommand() bool {
+	return len(os.Args) > 1 && os.Args[1] == "decode"
+}
+
+// a Tiled map is used in place of an image for the background and map modes
+func isTiledMap() bool {
+	return strings.EqualFold(filepath.Ext(*inputFilename), ".tmx")
+}
+
+func convertSprites(pro *processor.Processor) error {
+	var spriteHeight int
+	switch *spriteSize {
+	case "8x8":
+		spriteHeight = 8
+	case "8x16":
+		spriteHeight = 16
+	default:
+		return fmt.Errorf("ERROR: 'sprite' size must be 8x8 or 8x16")
+	}
+
+	var frameWidth, frameHeight int
+	if len(*frameSize) > 0 {
+		if _, err := fmt.Sscanf(*frameSize, "%dx%d", &frameWidth, &frameHeight); err != nil {
+			return fmt.Errorf("ERROR: invalid 'frame' size, expected WIDTHxHEIGHT: %w", err)
+		}
+	}
+
+	return pro.PngToSprites(spriteHeight, frameWidth, frameHeight)
+}
+
+func setVRAMLayout(pro *processor.Processor) error {
+	if len(*nameTableAddr) > 0 {
+		address, err := parseAddress(*nameTableAddr)
+		if err != nil {
+			return fmt.Errorf("ERROR: invalid 'nametable' address: %w", err)
+		}
+		pro.SetNameTableAddress(address)
+	}
+	if len(*satAddr) > 0 {
+		address, err := parseAddress(*satAddr)
+		if err != nil {
+			return fmt.Errorf("ERROR: invalid 'sat' address: %w", err)
+		}
+		pro.SetSATAddress(address)
+	}
+
+	if len(*reserveTiles) == 0 {
+		return nil
+	}
+	for _, block := range strings.Split(*reserveTiles, ",") {
+		parts := strings.Split(block, ":")
+		if len(parts) != 3 {
+			return fmt.Errorf("ERROR: invalid 'reserve' tiles '%s', expected NAME:FIRST:COUNT", block)
+		}
+		first, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return fmt.Errorf("ERROR: invalid 'reserve' first tile: %w", err)
+		}
+		count, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return fmt.Errorf("ERROR: invalid 'reserve' tile count: %w", err)
+		}
+		if err := pro.ReserveTiles(parts[0], first, count); err != nil {
+			return fmt.Errorf("ERROR: %w", err)
+		}
+	}
+	return nil
+}
+
+// parses a VRAM address written as $3800, 0x3800, or a decimal number
+func parseAddress(s string) (int, error) {
+	if strings.HasPrefix(s, "$") {
+		s = "0x" + s[1:]
+	}
+	address, err := strconv.ParseInt(s, 0, 32)
+	return int(address), err
+}
+
+// prints the problems that did not stop the conversion
+func printWarnings(pro *processor.Processor) {
+	for _, warning := range pro.Warnings() {
+		fmt.Println("warning:", warning)
+	}
+}
+
+// sets the syntax and layout of the assembly output
+func setAssemblyOptions(pro *processor.Processor) error {
+	if err := pro.SetAssemblyDialect(*asmDialect); err != nil {
+		return err
+	}
+	if err := pro.SetLabelPrefix(*asmPrefix); err != nil {
+		return err
+	}
+	if err := pro.SetRadix(*asmRadix); err != nil {
+		return err
+	}
+	if err := pro.SetValuesPerLine(*asmValues); err != nil {
+		return err
+	}
+	return pro.SetCommentLevel(*asmComments)
+}
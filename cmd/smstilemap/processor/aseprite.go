@@ -0,0 +1,242 @@
+package processor
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"path"
+	"strings"
+	"unicode"
+
+	"github.com/mrcook/smstilemap/aseprite"
+	"github.com/mrcook/smstilemap/assembly"
+)
+
+// An Aseprite file can be used in place of an image, with its layers given a
+// role by their name:
+//
+//	Priority  - the priority mask, see SetPriorityMask
+//	Collision - the collision map, where any opaque pixel makes a solid tile
+//
+// These layers are used even when hidden, while every other visible layer is
+// drawn as the image. The background and map modes use the first frame. The
+// sprites mode uses every frame, in order, with the tags giving the named
+// animations.
+
+const (
+	priorityLayer  = "priority"
+	collisionLayer = "collision"
+)
+
+// collisionMap holds a byte for each tile cell of the image, set to 1 when
+// the cell is solid.
+type collisionMap struct {
+	width, height int // in tiles
+	cells         []uint8
+}
+
+// animation is a named run of sprite frames, from an Aseprite tag.
+type animation struct {
+	name      string
+	first     int // sprite frame
+	count     int
+	direction string
+}
+
+// returns true for Aseprite files, which are read in place of an image
+func isAsepriteFile(filename string) bool {
+	ext := strings.ToLower(path.Ext(filename))
+	return ext == ".aseprite" || ext == ".ase"
+}
+
+// reads the Aseprite file, drawing the image from its visible layers, along
+// with the priority mask and collision map layers when not converting sprites.
+func (p *Processor) readAseprite(filename string, sprites bool) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	file, err := aseprite.Decode(f)
+	if err != nil {
+		return err
+	}
+	if len(file.Frames) == 0 {
+		return fmt.Errorf("the Aseprite file has no frames")
+	}
+
+	var layers []int
+	for i, layer := range file.Layers {
+		name := strings.ToLower(layer.Name)
+		if layer.Type == aseprite.LayerImage && file.Visible(i) && name != priorityLayer && name != collisionLayer {
+			layers = append(layers, i)
+		}
+	}
+
+	if sprites {
+		p.aseprite = file
+		p.image, err = asepriteFrames(file, layers)
+		return err
+	}
+
+	if p.image, err = file.FrameImage(0, layers...); err != nil {
+		return err
+	}
+	if i, ok := file.LayerIndex(priorityLayer); ok {
+		if p.priorityMask != nil {
+			return fmt.Errorf("a priority mask can not be used with the '%s' layer of an Aseprite file", file.Layers[i].Name)
+		}
+		if p.priorityMask, err = file.FrameImage(0, i); err != nil {
+			return err
+		}
+	}
+	if i, ok := file.LayerIndex(collisionLayer); ok {
+		mask, err := file.FrameImage(0, i)
+		if err != nil {
+			return err
+		}
+		p.collision = newCollisionMap(mask)
+	}
+	return nil
+}
+
+// returns the frames drawn one below the other, so that each frame is sliced
+// into sprite frames in turn. The indexed colour frames share the palette.
+func asepriteFrames(file *aseprite.File, layers []int) (image.Image, error) {
+	var pix []uint8
+	var palette color.Palette
+
+	for i := range file.Frames {
+		img, err := file.FrameImage(i, layers...)
+		if err != nil {
+			return nil, err
+		}
+		switch img := img.(type) {
+		case *image.Paletted:
+			pix, palette = append(pix, img.Pix...), img.Palette
+		case *image.NRGBA:
+			pix = append(pix, img.Pix...)
+		}
+	}
+
+	rect := image.Rect(0, 0, file.Width, file.Height*len(file.Frames))
+	if palette != nil {
+		return &image.Paletted{Pix: pix, Stride: file.Width, Rect: rect, Palette: palette}, nil
+	}
+	return &image.NRGBA{Pix: pix, Stride: file.Width * 4, Rect: rect}, nil
+}
+
+// returns the collision map for the layer image, where a cell is solid when
+// any of its pixels are opaque.
+func newCollisionMap(img image.Image) *collisionMap {
+	bounds := img.Bounds()
+	m := &collisionMap{width: (bounds.Dx() + 7) / 8, height: (bounds.Dy() + 7) / 8}
+	m.cells = make([]uint8, m.width*m.height)
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a > 0 {
+				m.cells[(y-bounds.Min.Y)/8*m.width+(x-bounds.Min.X)/8] = 1
+			}
+		}
+	}
+	return m
+}
+
+// sets the sprite animations from the Aseprite tags, and the duration of each
+// sprite frame, where each Aseprite frame may hold several sprite frames.
+func (p *Processor) addSpriteAnimations() error {
+	perFrame := len(p.sprites.frames) / len(p.aseprite.Frames)
+	for _, frame := range p.aseprite.Frames {
+		for i := 0; i < perFrame; i++ {
+			p.sprites.durations = append(p.sprites.durations, frame.Duration)
+		}
+	}
+
+	// the tag names are used for the asm and C constants, so must be unique
+	labels := make(map[string]bool)
+	for _, tag := range p.aseprite.Tags {
+		label := animationLabel(tag.Name)
+		if len(label) == 0 {
+			return fmt.Errorf("animation tag name '%s' must contain letters or digits", tag.Name)
+		} else if labels[label] {
+			return fmt.Errorf("duplicate animation tag name '%s'", tag.Name)
+		}
+		labels[label] = true
+
+		p.sprites.animations = append(p.sprites.animations, animation{
+			name:      tag.Name,
+			first:     tag.From * perFrame,
+			count:     (tag.To - tag.From + 1) * perFrame,
+			direction: animationDirection(tag.Direction),
+		})
+	}
+	return nil
+}
+
+func animationDirection(direction int) string {
+	switch direction {
+	case aseprite.Reverse:
+		return "reverse"
+	case aseprite.PingPong:
+		return "pingpong"
+	case aseprite.PingPongReverse:
+		return "pingpong_reverse"
+	default:
+		return "forward"
+	}
+}
+
+// returns the animation name as an assembly label, e.g. `walk left` becomes
+// `WalkLeft`.
+func animationLabel(name string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) || r > unicode.MaxASCII {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// returns the sprite animations and the collision map as assembly, when set
+func (p *Processor) asepriteDataToAssembly() (string, error) {
+	var blocks []string
+	if p.sprites != nil && len(p.sprites.animations) > 0 {
+		blocks = append(blocks, p.asm.Defines("Sprite animations, the first frame and frame count", p.animationDefines()).String())
+	}
+	if p.collision != nil {
+		if p.generalCompression() {
+			description := fmt.Sprintf("Collision map, %d rows of %d tiles", p.collision.height, p.collision.width)
+			block, err := p.compressedBlockToAssembly("CollisionMap", description, p.collision.cells)
+			if err != nil {
+				return "", err
+			}
+			blocks = append(blocks, block)
+		} else {
+			blocks = append(blocks, p.asm.CollisionMap(p.collision.cells, p.collision.width).String())
+		}
+	}
+	return strings.Join(blocks, "\n"), nil
+}
+
+// returns the first frame and frame count constants for each animation
+func (p *Processor) animationDefines() (defines []assembly.Define) {
+	for _, a := range p.sprites.animations {
+		name := "Anim" + animationLabel(a.name)
+		defines = append(defines,
+			assembly.Define{Name: name + "First", Value: a.first, Comment: fmt.Sprintf("%s (%s)", a.name, a.direction)},
+			assembly.Define{Name: name + "Frames", Value: a.count},
+		)
+	}
+	return
+}
@@ -0,0 +1,179 @@
+package processor
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mrcook/smstilemap/assembly"
+	"github.com/mrcook/smstilemap/sms"
+)
+
+// SetTilemapRows sets the number of tilemap rows written to the binary
+// output: the visible screen rows (the default), or all the name table rows.
+func (p *Processor) SetTilemapRows(rows int) error {
+	visible := p.sega.HeightInTiles()
+	table := len(p.sega.TilemapData()) / p.sega.WidthInTiles()
+	if rows != visible && rows != table {
+		return fmt.Errorf("invalid tilemap rows %d, must be %d or %d for the screen height", rows, visible, table)
+	}
+	p.tilemapRows = rows
+	return nil
+}
+
+// ToBinary writes the tiles, tilemap, and palette as raw binary files, ready
+// for loading directly into VRAM/CRAM, along with an include file holding
+// their sizes:
+//
+//	name-tiles.bin   - planar tile data, 32 bytes per tile (.psgcompr when compressed)
+//	name-tilemap.bin - tilemap words in little-endian format (.stmcompr when compressed)
+//	name-palette.bin - palette data, 32 bytes
+//	name.inc         - the size constants
+//
+// The collision map of an Aseprite file is written to `name-collision.bin`, one
+// byte per tile, with the sprite animations added to the include file.
+//
+// With the general ZX0/ZX7 compression, the other files use a `.zx0` or `.zx7`
+// extension, with tile data larger than 4 KB split into chunks, written to
+// `name-tiles-0.zx0`, `name-tiles-1.zx0`, etc. No tilemap is written for
+// sprite sheets.
+func (p *Processor) ToBinary() error {
+	if err := p.validateCompression(); err != nil {
+		return err
+	}
+	tileDefines, err := p.writeTileFiles()
+	if err != nil {
+		return err
+	}
+
+	var tilemap []uint8
+	rows := 0
+	if p.levelMap != nil {
+		rows = p.levelMap.Height()
+		if tilemap, err = p.compressBlock(wordsToBytes(p.levelMap.Words())); err != nil {
+			return fmt.Errorf("error compressing tilemap: %w", err)
+		}
+	} else if p.sprites == nil {
+		rows = p.binaryTilemapRows()
+		if tilemap, err = p.compressedTilemapData(rows); err != nil {
+			return fmt.Errorf("error compressing tilemap: %w", err)
+		}
+	}
+	if p.sprites == nil {
+		if err := p.writeBinaryFile("tilemap", tilemap); err != nil {
+			return err
+		}
+	}
+
+	palette, err := p.compressBlock(p.paletteData())
+	if err != nil {
+		return fmt.Errorf("error compressing palette: %w", err)
+	}
+	if err := p.writeBinaryFile("palette", palette); err != nil {
+		return err
+	}
+
+	var collision []uint8
+	if p.collision != nil {
+		if collision, err = p.compressBlock(p.collision.cells); err != nil {
+			return fmt.Errorf("error compressing collision map: %w", err)
+		}
+		if err := p.writeBinaryFile("collision", collision); err != nil {
+			return err
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(p.asm.Defines("VRAM layout", p.vramDefines()).String())
+	sb.WriteString("\n")
+	defines := append(tileDefines, assembly.Define{Name: "TileCount", Value: len(p.sega.TileData()) / sms.TileByteSize})
+	if p.sprites == nil {
+		defines = append(defines,
+			assembly.Define{Name: "TilemapSize", Value: len(tilemap), Comment: p.binaryFilename("tilemap")},
+			assembly.Define{Name: "TilemapRows", Value: rows},
+		)
+	}
+	defines = append(defines, assembly.Define{Name: "PaletteSize", Value: len(palette), Comment: p.binaryFilename("palette")})
+	if p.collision != nil {
+		defines = append(defines,
+			assembly.Define{Name: "CollisionSize", Value: len(collision), Comment: p.binaryFilename("collision")},
+			assembly.Define{Name: "CollisionWidth", Value: p.collision.width},
+		)
+	}
+	sb.WriteString(p.asm.Defines("Binary data sizes in bytes", defines).String())
+	if p.sprites != nil && len(p.sprites.animations) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(p.asm.Defines("Sprite animations, the first frame and frame count", p.animationDefines()).String())
+	}
+
+	if err := os.WriteFile(path.Join(p.outputDirectory, p.baseFilename+".inc"), []byte(sb.String()), 0644); err != nil {
+		return fmt.Errorf("error writing include file: %w", err)
+	}
+	return p.writeDecompressors()
+}
+
+// writes the tile data, returning the size defines for the include file. With
+// the general compression, tile data larger than a chunk is written to a file
+// for each chunk, e.g. `name-tiles-0.zx0`, see tileChunks.
+func (p *Processor) writeTileFiles() ([]assembly.Define, error) {
+	chunks := p.tileChunks()
+	if !p.generalCompression() || len(chunks) == 1 {
+		tiles, err := p.compressedTileData()
+		if err != nil {
+			return nil, fmt.Errorf("error compressing tile data: %w", err)
+		}
+		if err := p.writeBinaryFile("tiles", tiles); err != nil {
+			return nil, err
+		}
+		return []assembly.Define{{Name: "TilesSize", Value: len(tiles), Comment: p.binaryFilename("tiles")}}, nil
+	}
+
+	defines := []assembly.Define{
+		{Name: "TileChunkCount", Value: len(chunks)},
+		{Name: "TileChunkSize", Value: tileChunkSize, Comment: "decompressed, the last chunk may be smaller"},
+	}
+	for i, chunk := range chunks {
+		tiles, err := p.compressBlock(chunk)
+		if err != nil {
+			return nil, fmt.Errorf("error compressing tile data: %w", err)
+		}
+		name := fmt.Sprintf("tiles-%d", i)
+		if err := p.writeBinaryFile(name, tiles); err != nil {
+			return nil, err
+		}
+		defines = append(defines, assembly.Define{Name: fmt.Sprintf("Tiles%dSize", i), Value: len(tiles), Comment: p.binaryFilename(name)})
+	}
+	return defines, nil
+}
+
+// returns the number of tilemap rows to write, defaulting to the visible rows
+func (p *Processor) binaryTilemapRows() int {
+	if p.tilemapRows == 0 {
+		return p.sega.HeightInTiles()
+	}
+	return p.tilemapRows
+}
+
+// returns the binary filename, using the `.psgcompr` extension for PSGaiden
+// compressed tiles, `.stmcompr` for STM compressed tilemaps, and `.zx0` or
+// `.zx7` for the general compression
+func (p *Processor) binaryFilename(name string) string {
+	if name == "tiles" && p.tileCompression == compressionPSGaiden {
+		return p.baseFilename + "-" + name + ".psgcompr"
+	}
+	if name == "tilemap" && p.tilemapCompression == compressionSTM {
+		return p.baseFilename + "-" + name + ".stmcompr"
+	}
+	if p.generalCompression() {
+		return p.baseFilename + "-" + name + "." + p.compression
+	}
+	return p.baseFilename + "-" + name + ".bin"
+}
+
+func (p *Processor) writeBinaryFile(name string, data []uint8) error {
+	if err := os.WriteFile(path.Join(p.outputDirectory, p.binaryFilename(name)), data, 0644); err != nil {
+		return fmt.Errorf("error writing %s binary file: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package processor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mrcook/smstilemap/assembly"
+	"github.com/mrcook/smstilemap/compress"
+	"github.com/mrcook/smstilemap/sms"
+)
+
+// Compression can be applied to the data blocks of the asm and bin output
+// formats, with the Z80 decompression routines included alongside the data:
+// in the assembly file, or written to `name-decompress.asm` for the binary
+// files. The tiles and tilemap have their own formats, written directly to
+// VRAM, while the general purpose ZX0/ZX7 compression applies to every other
+// block, and is decompressed to RAM. As the routines decompress a whole block
+// at once, each block must fit in the 8 KB of RAM, with larger tile data
+// split into chunks, each decompressed to RAM and copied to VRAM in turn.
+
+const (
+	compressionNone     = "none"
+	compressionPSGaiden = "psgaiden"
+	compressionSTM      = "stm"
+	compressionZX0      = "zx0"
+	compressionZX7      = "zx7"
+)
+
+const (
+	maxDecompressedSize = 8 * 1024 // the SMS RAM size
+	tileChunkSize       = 4 * 1024 // 128 tiles, leaving half the RAM for the program
+)
+
+// SetCompression sets the general compression used for the data blocks (tiles,
+// tilemap, palette, sprite frames) without their own compression: none, zx0,
+// or zx7.
+func (p *Processor) SetCompression(name string) error {
+	switch name {
+	case compressionNone, compressionZX0, compressionZX7:
+		p.compression = name
+		return nil
+	default:
+		return fmt.Errorf("invalid compression '%s', must be one of: none, zx0, zx7", name)
+	}
+}
+
+// SetTileCompression sets the compression used for the tile data: none, or
+// psgaiden.
+func (p *Processor) SetTileCompression(name string) error {
+	switch name {
+	case compressionNone, compressionPSGaiden:
+		p.tileCompression = name
+		return nil
+	default:
+		return fmt.Errorf("invalid tile compression '%s', must be one of: none, psgaiden", name)
+	}
+}
+
+// SetTilemapCompression sets the compression used for the tilemap: none, or
+// stm. Only the tilemap of the background mode can be compressed.
+func (p *Processor) SetTilemapCompression(name string) error {
+	switch name {
+	case compressionNone, compressionSTM:
+		p.tilemapCompression = name
+		return nil
+	default:
+		return fmt.Errorf("invalid tilemap compression '%s', must be one of: none, stm", name)
+	}
+}
+
+// SetDecompressors sets whether the Z80 decompression routines are written
+// with the compressed data: include, or none. The routine labels do not use the
+// label prefix, so when several conversions are assembled into one program,
+// only one of them should include the routines.
+func (p *Processor) SetDecompressors(name string) error {
+	switch name {
+	case "include":
+		p.omitDecompressors = false
+	case "none":
+		p.omitDecompressors = true
+	default:
+		return fmt.Errorf("invalid decompressors '%s', must be one of: include, none", name)
+	}
+	return nil
+}
+
+// compressed returns true when any of the data is to be compressed
+func (p *Processor) compressed() bool {
+	return p.tileCompression == compressionPSGaiden || p.tilemapCompression == compressionSTM || p.generalCompression()
+}
+
+// returns true when the data blocks use the general compression
+func (p *Processor) generalCompression() bool {
+	return p.compression == compressionZX0 || p.compression == compressionZX7
+}
+
+// returns true when the decompression routines are to be written
+func (p *Processor) includeDecompressors() bool {
+	return p.compressed() && !p.omitDecompressors
+}
+
+// returns the data block compressed with the general compression, if any
+func (p *Processor) compressBlock(data []uint8) ([]uint8, error) {
+	if p.generalCompression() && len(data) > maxDecompressedSize {
+		return nil, fmt.Errorf("%d bytes is too large to decompress to RAM, %s blocks must be at most %d bytes", len(data), strings.ToUpper(p.compression), maxDecompressedSize)
+	}
+	switch p.compression {
+	case compressionZX0:
+		return compress.ZX0Compress(data)
+	case compressionZX7:
+		return compress.ZX7Compress(data)
+	default:
+		return data, nil
+	}
+}
+
+// returns the data block as assembly, compressed with the general compression
+func (p *Processor) compressedBlockToAssembly(label, description string, data []uint8) (string, error) {
+	packed, err := p.compressBlock(data)
+	if err != nil {
+		return "", err
+	}
+	description = fmt.Sprintf("%s, %s compressed", description, strings.ToUpper(p.compression))
+	return p.asm.CompressedData(label, description, packed).String(), nil
+}
+
+// checks the compression options can be used with the conversion mode
+func (p *Processor) validateCompression() error {
+	if p.tilemapCompression == compressionSTM && (p.levelMap != nil || p.sprites != nil) {
+		return fmt.Errorf("tilemap compression is only supported for the background mode")
+	}
+	return nil
+}
+
+// returns the tile data, compressed when requested
+func (p *Processor) compressedTileData() ([]uint8, error) {
+	data := p.sega.TileData()
+	if p.tileCompression == compressionPSGaiden {
+		return compress.PSGaidenCompress(data)
+	}
+	return p.compressBlock(data)
+}
+
+// returns the tile data as assembly, compressed when requested
+func (p *Processor) tilesToAssembly() (string, error) {
+	description := fmt.Sprintf("Tile data, %d tiles from tile %d", len(p.sega.TileData())/sms.TileByteSize, p.tileOffset)
+	switch {
+	case p.tileCompression == compressionPSGaiden:
+		data, err := p.compressedTileData()
+		if err != nil {
+			return "", err
+		}
+		return p.asm.CompressedData("TileData", description+", PSGaiden compressed", data).String(), nil
+	case p.generalCompression():
+		return p.tileChunksToAssembly(description)
+	default:
+		return p.asm.TilesFrom(p.sega.TileData(), p.tileOffset).String(), nil
+	}
+}
+
+// returns the tile data split into the chunks used by the general compression,
+// each of up to tileChunkSize bytes, so that each can be decompressed to RAM.
+func (p *Processor) tileChunks() (chunks [][]uint8) {
+	data := p.sega.TileData()
+	for len(data) > tileChunkSize {
+		chunks = append(chunks, data[:tileChunkSize])
+		data = data[tileChunkSize:]
+	}
+	return append(chunks, data)
+}
+
+// returns the general compression tile data as assembly: a single `TileData`
+// block, or when larger than a chunk, the chunk count and size, followed by
+// the `TileData0`, `TileData1`, etc. blocks.
+func (p *Processor) tileChunksToAssembly(description string) (string, error) {
+	chunks := p.tileChunks()
+	if len(chunks) == 1 {
+		return p.compressedBlockToAssembly("TileData", description, chunks[0])
+	}
+
+	var sb strings.Builder
+	sb.WriteString(p.asm.Defines("Tile data chunks, each decompressed to RAM, then copied to VRAM", []assembly.Define{
+		{Name: "TileChunkCount", Value: len(chunks)},
+		{Name: "TileChunkSize", Value: tileChunkSize, Comment: "bytes, the last chunk may be smaller"},
+	}).String())
+	for i, chunk := range chunks {
+		firstTile := p.tileOffset + i*tileChunkSize/sms.TileByteSize
+		description := fmt.Sprintf("Tile data chunk %d, %d tiles from tile %d", i, len(chunk)/sms.TileByteSize, firstTile)
+		block, err := p.compressedBlockToAssembly(fmt.Sprintf("TileData%d", i), description, chunk)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString("\n")
+		sb.WriteString(block)
+	}
+	return sb.String(), nil
+}
+
+// returns the tilemap words for the given number of rows, compressed when requested
+func (p *Processor) compressedTilemapData(rows int) ([]uint8, error) {
+	words := p.sega.TilemapData()[:rows*p.sega.WidthInTiles()]
+	if p.tilemapCompression == compressionSTM {
+		return compress.STMCompress(words, p.sega.WidthInTiles())
+	}
+	return p.compressBlock(wordsToBytes(words))
+}
+
+// returns the tilemap as assembly, compressed when requested
+func (p *Processor) tilemapToAssembly() (string, error) {
+	rows := p.sega.HeightInTiles()
+	switch {
+	case p.tilemapCompression == compressionSTM:
+		sb, err := p.asm.TilemapSTM(p.sega.TilemapData(), rows)
+		if err != nil {
+			return "", err
+		}
+		return sb.String(), nil
+	case p.generalCompression():
+		words := p.sega.TilemapData()[:rows*p.sega.WidthInTiles()]
+		description := fmt.Sprintf("Tilemap data, %d rows and %d columns", rows, p.sega.WidthInTiles())
+		return p.compressedBlockToAssembly("Tilemap", description, wordsToBytes(words))
+	default:
+		return p.asm.Tilemap(p.sega.TilemapData(), rows).String(), nil
+	}
+}
+
+// returns the palettes as assembly, compressed when requested
+func (p *Processor) palettesToAssembly() (string, error) {
+	palette := p.paletteData()
+	if p.generalCompression() {
+		return p.compressedBlockToAssembly("PaletteData", "Palette data, two 16 colour palettes", palette)
+	}
+	if p.gameGear() {
+		return p.asm.GGPalettes([64]uint8(palette)).String(), nil
+	}
+	return p.asm.Palettes([32]uint8(palette)).String(), nil
+}
+
+// returns the sprite frames as assembly, compressed when requested
+func (p *Processor) spriteFramesToAssembly() (string, error) {
+	frames := p.sprites.frames
+	if !p.generalCompression() {
+		return p.asm.SpriteFrames(frames, p.sprites.FrameCols(), p.sprites.spriteHeight).String(), nil
+	}
+	var data []uint8
+	for _, frame := range frames {
+		data = append(data, frame...)
+	}
+	sprites := 0
+	if len(frames) > 0 {
+		sprites = len(frames[0])
+	}
+	description := fmt.Sprintf("Sprite frames, %d frames of %d 8x%d sprites", len(frames), sprites, p.sprites.spriteHeight)
+	return p.compressedBlockToAssembly("SpriteFrames", description, data)
+}
+
+// returns the Z80 decompression routines for the compressed data
+func (p *Processor) decompressorsToAssembly() string {
+	var routines []string
+	if p.tileCompression == compressionPSGaiden {
+		routines = append(routines, p.asm.PSGaidenDecompressor().String())
+	}
+	if p.tilemapCompression == compressionSTM {
+		routines = append(routines, p.asm.STMDecompressor().String())
+	}
+	switch p.compression {
+	case compressionZX0:
+		routines = append(routines, p.asm.ZX0Decompressor().String())
+	case compressionZX7:
+		routines = append(routines, p.asm.ZX7Decompressor().String())
+	}
+	return strings.Join(routines, "\n")
+}
+
+// returns the words as little-endian bytes
+func wordsToBytes(words []uint16) []uint8 {
+	var data []uint8
+	for _, word := range words {
+		data = binary.LittleEndian.AppendUint16(data, word)
+	}
+	return data
+}
+
+// writes the Z80 decompression routines for the binary output
+func (p *Processor) writeDecompressors() error {
+	if !p.includeDecompressors() {
+		return nil
+	}
+	filename := path.Join(p.outputDirectory, p.baseFilename+"-decompress.asm")
+	if err := os.WriteFile(filename, []byte(p.decompressorsToAssembly()), 0644); err != nil {
+		return fmt.Errorf("error writing decompressor file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package processor_test
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/mrcook/smstilemap/cmd/smstilemap/processor"
+	"github.com/mrcook/smstilemap/compress"
+)
+
+func TestProcessor_TileChunks(t *testing.T) {
+	dir := t.TempDir()
+	filename := path.Join(dir, "tiles.png")
+
+	// 224 unique tiles (7168 bytes), each with a red top-left pixel, so that
+	// no tile is a flipped copy of another, and its number in the second row
+	img := image.NewRGBA(image.Rect(0, 0, 256, 56))
+	for i := 0; i < 224; i++ {
+		x, y := i%32*8, i/32*8
+		for p := 0; p < 64; p++ {
+			img.Set(x+p%8, y+p/8, color.Black)
+		}
+		img.Set(x, y, color.RGBA{R: 0xFF, A: 0xFF})
+		for bit := 0; bit < 8; bit++ {
+			if i>>bit&1 == 1 {
+				img.Set(x+bit, y+1, color.White)
+			}
+		}
+	}
+	writePNG(t, filename, img)
+
+	pro := processor.New(filename, dir)
+	if err := pro.PngToSMS(); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if err := pro.ToBinary(); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	tiles := []byte(readFile(t, path.Join(dir, "tiles-tiles.bin")))
+	if len(tiles) != 224*32 {
+		t.Fatalf("expected 224 tiles, got %d bytes", len(tiles))
+	}
+
+	pro = processor.New(filename, dir)
+	if err := pro.SetCompression("zx0"); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if err := pro.PngToSMS(); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	t.Run("assembly", func(t *testing.T) {
+		if err := pro.ToAssembly(); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		asm := readFile(t, path.Join(dir, "tiles.asm"))
+		for _, want := range []string{".define TileChunkCount 2", ".define TileChunkSize 4096", "\nTileData0:\n", "\nTileData1:\n"} {
+			if !strings.Contains(asm, want) {
+				t.Errorf("expected the assembly to contain %q", want)
+			}
+		}
+		if strings.Contains(asm, "\nTileData:\n") {
+			t.Errorf("expected no single tile data block")
+		}
+	})
+
+	t.Run("binary", func(t *testing.T) {
+		if err := pro.ToBinary(); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		var unpacked []byte
+		for i, size := range []int{4096, 3072} {
+			name := fmt.Sprintf("tiles-tiles-%d.zx0", i)
+			chunk, err := compress.ZX0Decompress([]byte(readFile(t, path.Join(dir, name))))
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %q", name, err)
+			}
+			if len(chunk) != size {
+				t.Errorf("%s: expected %d bytes, got %d", name, size, len(chunk))
+			}
+			unpacked = append(unpacked, chunk...)
+		}
+		if !bytes.Equal(unpacked, tiles) {
+			t.Errorf("expected the chunks to hold the tile data")
+		}
+		inc := readFile(t, path.Join(dir, "tiles.inc"))
+		if !strings.Contains(inc, "Tiles1Size") || strings.Contains(inc, "TilesSize") {
+			t.Errorf("expected the chunk sizes in the include file, got:\n%s", inc)
+		}
+	})
+}
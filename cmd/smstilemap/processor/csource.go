@@ -0,0 +1,113 @@
+package processor
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mrcook/smstilemap/csource"
+)
+
+// cSourceFile is a C source file holding a single array.
+type cSourceFile struct {
+	suffix string // of the filename, e.g. `tiles` for `name-tiles.c`
+	array  *strings.Builder
+}
+
+// ToCSource writes the tiles, tilemap, and palette as C arrays, for use with
+// devkitSMS, along with a header file with their declarations and sizes. The
+// names are derived from the input filename, e.g. `level_tiles_bin`, with each
+// array written to its own source file, e.g. `level-tiles.c`, so that each can
+// be compiled into a separate ROM bank.
+func (p *Processor) ToCSource() error {
+	if p.compressed() {
+		return fmt.Errorf("compression is only supported by the asm and bin output formats")
+	}
+	name := csource.Identifier(p.baseFilename)
+
+	var defines []csource.Define
+	var declarations []csource.Declaration
+	var sources []cSourceFile
+
+	addSource := func(suffix string, array *strings.Builder, declaration csource.Declaration) {
+		sources = append(sources, cSourceFile{suffix: suffix, array: array})
+		declarations = append(declarations, declaration)
+	}
+
+	tiles := p.sega.TileData()
+	addSource("tiles", csource.Tiles(name+"_tiles_bin", tiles), csource.Declaration{Name: name + "_tiles_bin", Size: len(tiles)})
+	defines = append(defines,
+		csource.Define{Name: name + "_tile_offset", Value: p.tileOffset},
+		csource.Define{Name: name + "_tile_count", Value: len(tiles) / 32},
+	)
+
+	if p.sprites != nil {
+		var frames []uint8
+		for _, frame := range p.sprites.frames {
+			frames = append(frames, frame...)
+		}
+		var src strings.Builder
+		src.WriteString("// Sprite frames: the tile numbers of the sprites in each frame.\n")
+		src.WriteString(csource.Bytes(name+"_frames_bin", frames).String())
+		addSource("frames", &src, csource.Declaration{Name: name + "_frames_bin", Size: len(frames)})
+		defines = append(defines,
+			csource.Define{Name: name + "_frame_count", Value: len(p.sprites.frames)},
+			csource.Define{Name: name + "_frame_sprites", Value: len(frames) / max(len(p.sprites.frames), 1)},
+		)
+		for _, a := range p.sprites.animations {
+			anim := name + "_anim_" + csource.Identifier(strings.ReplaceAll(a.name, ".", "_"))
+			defines = append(defines,
+				csource.Define{Name: anim + "_first", Value: a.first},
+				csource.Define{Name: anim + "_frames", Value: a.count},
+			)
+		}
+	} else {
+		data, cols, rows := p.sega.TilemapData(), p.sega.WidthInTiles(), p.binaryTilemapRows()
+		var src strings.Builder
+		if p.levelMap != nil {
+			data, cols, rows = p.levelMap.Words(), p.levelMap.Width(), p.levelMap.Height()
+			src.WriteString(fmt.Sprintf("// Map of %d columns, stored row by row.\n", cols))
+			src.WriteString(csource.Words(name+"_tilemap_bin", data).String())
+		} else {
+			src.WriteString(csource.Tilemap(name+"_tilemap_bin", data, rows).String())
+		}
+		addSource("tilemap", &src, csource.Declaration{Name: name + "_tilemap_bin", Word: true, Size: cols * rows * 2})
+		defines = append(defines,
+			csource.Define{Name: name + "_tilemap_width", Value: cols},
+			csource.Define{Name: name + "_tilemap_height", Value: rows},
+		)
+	}
+
+	palette := p.paletteData()
+	if p.gameGear() {
+		addSource("palette", csource.GGPalette(name+"_palette_bin", [64]uint8(palette)), csource.Declaration{Name: name + "_palette_bin", Size: len(palette)})
+	} else {
+		addSource("palette", csource.Palette(name+"_palette_bin", [32]uint8(palette)), csource.Declaration{Name: name + "_palette_bin", Size: len(palette)})
+	}
+
+	if p.collision != nil {
+		var src strings.Builder
+		src.WriteString(fmt.Sprintf("// Collision map of %d columns, one byte per tile, where 1 is solid.\n", p.collision.width))
+		src.WriteString(csource.Bytes(name+"_collision_bin", p.collision.cells).String())
+		addSource("collision", &src, csource.Declaration{Name: name + "_collision_bin", Size: len(p.collision.cells)})
+		defines = append(defines,
+			csource.Define{Name: name + "_collision_width", Value: p.collision.width},
+			csource.Define{Name: name + "_collision_height", Value: p.collision.height},
+		)
+	}
+
+	header := csource.Header(name, defines, declarations)
+
+	for _, source := range sources {
+		src := fmt.Sprintf("#include \"%s.h\"\n\n%s", p.baseFilename, source.array.String())
+		filename := path.Join(p.outputDirectory, p.baseFilename+"-"+source.suffix+".c")
+		if err := os.WriteFile(filename, []byte(src), 0644); err != nil {
+			return fmt.Errorf("error writing C source file: %w", err)
+		}
+	}
+	if err := os.WriteFile(path.Join(p.outputDirectory, p.baseFilename+".h"), []byte(header.String()), 0644); err != nil {
+		return fmt.Errorf("error writing C header file: %w", err)
+	}
+	return nil
+}
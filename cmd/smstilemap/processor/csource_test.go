@@ -0,0 +1,49 @@
+package processor_test
+
+import (
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/mrcook/smstilemap/cmd/smstilemap/processor"
+)
+
+func TestProcessor_ToCSource(t *testing.T) {
+	dir := t.TempDir()
+	filename := path.Join(dir, "fixture.png")
+	writeFixture(t, filename)
+
+	pro := processor.New(filename, dir)
+	if err := pro.PngToSMS(); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if err := pro.ToCSource(); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	// each array is in its own file, so it can be placed in its own ROM bank
+	for suffix, array := range map[string]string{
+		"tiles":   "const unsigned char fixture_tiles_bin[32] = {",
+		"tilemap": "const unsigned int fixture_tilemap_bin[768] = {",
+		"palette": "const unsigned char fixture_palette_bin[32] = {",
+	} {
+		src := readFile(t, path.Join(dir, "fixture-"+suffix+".c"))
+		if !strings.HasPrefix(src, "#include \"fixture.h\"\n") {
+			t.Errorf("%s: expected the header to be included, got:\n%s", suffix, src)
+		}
+		if strings.Count(src, "const ") != 1 || !strings.Contains(src, array) {
+			t.Errorf("%s: expected only the %s array, got:\n%s", suffix, suffix, src)
+		}
+	}
+
+	header := readFile(t, path.Join(dir, "fixture.h"))
+	for _, declaration := range []string{
+		"extern const unsigned char fixture_tiles_bin[32];",
+		"extern const unsigned int fixture_tilemap_bin[768];",
+		"extern const unsigned char fixture_palette_bin[32];",
+	} {
+		if !strings.Contains(header, declaration) {
+			t.Errorf("expected the header to declare %q, got:\n%s", declaration, header)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package processor
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/mrcook/smstilemap/gg"
+	"github.com/mrcook/smstilemap/sms"
+)
+
+// DecodeBinary rebuilds the SMS data from the raw VRAM/CRAM binary files: the
+// tile data (32 bytes per tile, loaded from the tile offset), the tilemap
+// (little-endian words, for whole rows), and the palette (16 or 32 bytes, or
+// 32 or 64 bytes for the Game Gear).
+func (p *Processor) DecodeBinary(tilesFilename, tilemapFilename, paletteFilename string) error {
+	if err := p.decodeTiles(tilesFilename); err != nil {
+		return fmt.Errorf("tiles file error: %w", err)
+	}
+	if err := p.decodeTilemap(tilemapFilename); err != nil {
+		return fmt.Errorf("tilemap file error: %w", err)
+	}
+	if err := p.decodePalette(paletteFilename); err != nil {
+		return fmt.Errorf("palette file error: %w", err)
+	}
+	return nil
+}
+
+// SaveDecodedImage saves the decoded SMS data as a PNG image. Tilemap entries
+// for tiles not in the tile data, such as those before the tile offset, are
+// left blank, with a warning.
+func (p *Processor) SaveDecodedImage() error {
+	img, err := p.smsToImage(true)
+	if err != nil {
+		return err
+	}
+	return p.saveImageToFilename(img, path.Join(p.outputDirectory, p.baseFilename+"-decoded.png"))
+}
+
+func (p *Processor) decodeTiles(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if len(data)%sms.TileByteSize != 0 {
+		return fmt.Errorf("data size must be a multiple of %d bytes, got %d", sms.TileByteSize, len(data))
+	}
+	if p.tileOffset+len(data)/sms.TileByteSize > sms.MaxTileNumber {
+		return fmt.Errorf("too many tiles from tile offset %d (max: %d), got %d", p.tileOffset, sms.MaxTileNumber-p.tileOffset, len(data)/sms.TileByteSize)
+	}
+
+	for i := 0; i < len(data); i += sms.TileByteSize {
+		tile := &sms.Tile{}
+		if err := tile.UnmarshalBinary(data[i : i+sms.TileByteSize]); err != nil {
+			return err
+		}
+		tileId := uint16(p.tileOffset + i/sms.TileByteSize)
+		if err := p.sega.SetTileAt(tileId, tile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Processor) decodeTilemap(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	tilemap := sms.Tilemap{}
+	if err := tilemap.SetScreenHeight(p.sega.HeightInPixels()); err != nil {
+		return err
+	}
+	if err := tilemap.UnmarshalBinary(data); err != nil {
+		return err
+	}
+
+	for row := 0; row < tilemap.TableHeight(); row++ {
+		for col := 0; col < tilemap.Width(); col++ {
+			word, err := tilemap.Get(row, col)
+			if err != nil {
+				return err
+			}
+			if err := p.sega.AddTilemapEntryAt(row, col, *word); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Processor) decodePalette(filename string) error {
+	colours, err := p.readBinaryPalette(filename)
+	if err != nil {
+		return err
+	}
+	for i, colour := range colours {
+		if err := p.cram.SetColourAt(gg.PaletteId(i), colour); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package processor_test
+
+import (
+	"path"
+	"testing"
+
+	"github.com/mrcook/smstilemap/cmd/smstilemap/processor"
+)
+
+func TestProcessor_DecodeBinary(t *testing.T) {
+	dir := t.TempDir()
+	filename := path.Join(dir, "fixture.png")
+	writeFixture(t, filename)
+
+	// the 16x8 fixture converted from tile 5, leaving the rest of the screen unused
+	pro := processor.New(filename, dir)
+	if err := pro.SetTileOffset(5); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if err := pro.PngToSMS(); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if err := pro.SaveTilemapToImage(); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if err := pro.ToBinary(); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	tiles := path.Join(dir, "fixture-tiles.bin")
+	tilemap := path.Join(dir, "fixture-tilemap.bin")
+	palette := path.Join(dir, "fixture-palette.bin")
+
+	t.Run("unused entries use the first converted tile", func(t *testing.T) {
+		data := []byte(readFile(t, tilemap))
+		if len(data) != 32*24*2 {
+			t.Fatalf("expected 24 rows of tilemap words, got %d bytes", len(data))
+		}
+		if data[0] != 5 || data[2] != 5 || data[3] != 0x02 {
+			t.Errorf("expected tile 5, then tile 5 flipped horizontally, got % X", data[:4])
+		}
+		for i := 4; i < len(data); i += 2 {
+			if data[i] != 5 || data[i+1] != 0 {
+				t.Fatalf("expected the unused entry %d to use tile 5, got % X", i/2, data[i:i+2])
+			}
+		}
+	})
+
+	t.Run("decoding from the tile offset", func(t *testing.T) {
+		pro := processor.New(tilemap, dir)
+		if err := pro.SetTileOffset(5); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if err := pro.DecodeBinary(tiles, tilemap, palette); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if err := pro.SaveDecodedImage(); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if len(pro.Warnings()) != 0 {
+			t.Errorf("expected no warnings, got %q", pro.Warnings())
+		}
+	})
+
+	t.Run("entries for tiles not in the tile data are left blank", func(t *testing.T) {
+		zeroes := path.Join(dir, "zeroes-tilemap.bin")
+		writeFile(t, zeroes, make([]byte, 32*24*2))
+
+		pro := processor.New(zeroes, dir)
+		if err := pro.SetTileOffset(5); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if err := pro.DecodeBinary(tiles, zeroes, palette); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if err := pro.SaveDecodedImage(); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		want := "768 tilemap entries use tiles that are not in the tile data, and are left blank"
+		if len(pro.Warnings()) != 1 || pro.Warnings()[0] != want {
+			t.Errorf("expected a warning for the blank entries, got %q", pro.Warnings())
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/mrcook/smstilemap/assembly"
+)
+
+// The assembly output defaults to WLA-DX syntax, and can be changed to suit
+// the assembler used by the project. A label prefix allows the output for
+// more than one image to be included in the same program.
+
+var dialects = map[string]assembly.Dialect{
+	"wladx":     assembly.WLADX,
+	"sjasmplus": assembly.Sjasmplus,
+	"pasmo":     assembly.Pasmo,
+	"z80asm":    assembly.Z80asm,
+}
+
+var radixes = map[string]assembly.Radix{
+	"default": assembly.DefaultRadix,
+	"hex":     assembly.Hex,
+	"bin":     assembly.Binary,
+	"dec":     assembly.Decimal,
+}
+
+var commentLevels = map[string]assembly.CommentLevel{
+	"all":   assembly.AllComments,
+	"brief": assembly.BriefComments,
+	"none":  assembly.NoComments,
+}
+
+// SetAssemblyDialect sets the assembler syntax: wladx, sjasmplus, pasmo, or
+// z80asm (z88dk).
+func (p *Processor) SetAssemblyDialect(name string) error {
+	dialect, ok := dialects[name]
+	if !ok {
+		return fmt.Errorf("invalid assembly dialect '%s', must be one of: wladx, sjasmplus, pasmo, z80asm", name)
+	}
+	p.asm.Dialect = dialect
+	return nil
+}
+
+// SetLabelPrefix sets the prefix added to the data labels and constants.
+func (p *Processor) SetLabelPrefix(prefix string) error {
+	if prefix != "" && !labelPattern.MatchString(prefix) {
+		return fmt.Errorf("invalid label prefix '%s', must be a valid assembly label", prefix)
+	}
+	p.asm.LabelPrefix = prefix
+	return nil
+}
+
+// SetRadix sets the number base for the data values: default, hex, bin, or dec.
+// The default uses hex for tiles and sprite frames, and binary for tilemaps
+// and palettes.
+func (p *Processor) SetRadix(name string) error {
+	radix, ok := radixes[name]
+	if !ok {
+		return fmt.Errorf("invalid radix '%s', must be one of: default, hex, bin, dec", name)
+	}
+	p.asm.Radix = radix
+	return nil
+}
+
+// SetValuesPerLine sets the number of data values on each line, where 0 uses
+// the default for each block.
+func (p *Processor) SetValuesPerLine(count int) error {
+	if count < 0 {
+		return fmt.Errorf("invalid values per line %d, must be 0 or more", count)
+	}
+	p.asm.ValuesPerLine = count
+	return nil
+}
+
+// SetCommentLevel sets how much of the assembly output is commented: all,
+// brief, or none.
+func (p *Processor) SetCommentLevel(name string) error {
+	level, ok := commentLevels[name]
+	if !ok {
+		return fmt.Errorf("invalid comment level '%s', must be one of: all, brief, none", name)
+	}
+	p.asm.Comments = level
+	return nil
+}
@@ -0,0 +1,96 @@
+package processor_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"path"
+	"testing"
+
+	"golang.org/x/image/bmp"
+
+	"github.com/mrcook/smstilemap/cmd/smstilemap/processor"
+)
+
+func TestProcessor_IndexedImage(t *testing.T) {
+	// blue, red, black, and white: not in the order the colours are found
+	order := []uint8{0x30, 0x03, 0x00, 0x3F}
+	var palette color.Palette
+	for _, c := range order {
+		palette = append(palette, smsRGB(c))
+	}
+
+	tables := []struct {
+		name   string
+		encode func(w io.Writer, m image.Image) error
+	}{
+		{"image.png", png.Encode},
+		{"image.gif", func(w io.Writer, m image.Image) error { return gif.Encode(w, m, nil) }},
+		{"image.bmp", bmp.Encode},
+	}
+	for _, data := range tables {
+		t.Run(data.name, func(t *testing.T) {
+			dir := t.TempDir()
+			filename := path.Join(dir, data.name)
+			// the first tile uses white and black, the second red and blue
+			writeImage(t, filename, indexedTiles(palette, []uint8{3, 2}, []uint8{1, 0}), data.encode)
+
+			pro := processor.New(filename, dir)
+			if err := pro.PngToSMS(); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if err := pro.ToBinary(); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if len(pro.Warnings()) != 0 {
+				t.Errorf("expected no warnings, got %q", pro.Warnings())
+			}
+
+			cram := []byte(readFile(t, path.Join(dir, "image-palette.bin")))
+			if len(cram) != 32 || !bytes.Equal(cram[:4], order) {
+				t.Errorf("expected the palette to start % X, got % X", order, cram)
+			}
+			// the first row of alternating white and black pixels uses palette IDs 3 and 2
+			tiles := []byte(readFile(t, path.Join(dir, "image-tiles.bin")))
+			if want := []byte{0xAA, 0xFF, 0x00, 0x00}; !bytes.Equal(tiles[:4], want) {
+				t.Errorf("expected the first tile row to be % X, got % X", want, tiles[:4])
+			}
+		})
+	}
+
+	t.Run("with a tile using both palette halves", func(t *testing.T) {
+		mixed := append(color.Palette{}, palette...)
+		for len(mixed) < 16 {
+			mixed = append(mixed, smsRGB(uint8(0x10+len(mixed)))) // unused colours
+		}
+		mixed = append(mixed, smsRGB(0x0C)) // green, in the sprite half
+
+		dir := t.TempDir()
+		filename := path.Join(dir, "image.png")
+		writeImage(t, filename, indexedTiles(mixed, []uint8{3, 2}, []uint8{1, 16}), png.Encode)
+
+		pro := processor.New(filename, dir)
+		if err := pro.PngToSMS(); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		want := "the image palette order is not kept, as the tile at row 0, col 1 uses colours from both halves of the palette (0-15 and 16-31)"
+		if len(pro.Warnings()) != 1 || pro.Warnings()[0] != want {
+			t.Errorf("expected the mixed palette warning, got %q", pro.Warnings())
+		}
+	})
+}
+
+// returns an indexed image of 8x8 tiles in a row, each filled with its colour
+// indexes, pixel by pixel, repeating the indexes as needed
+func indexedTiles(palette color.Palette, tiles ...[]uint8) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, 8*len(tiles), 8), palette)
+	for i, indexes := range tiles {
+		for p := 0; p < 64; p++ {
+			img.SetColorIndex(i*8+p%8, p/8, indexes[p%len(indexes)])
+		}
+	}
+	return img
+}
@@ -0,0 +1,217 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/mrcook/smstilemap/cmd/smstilemap/processor/internal/tiler"
+	"github.com/mrcook/smstilemap/gg"
+)
+
+// jsonVersion is the version of the JSON schema, which is increased whenever
+// a field is changed or removed. New fields may be added within a version.
+const jsonVersion = 1
+
+// sourceTile is a converted SMS tile, along with its position in the image.
+type sourceTile struct {
+	number uint16
+	x, y   int         // of the tile in pixels
+	tile   *tiler.Tile // the tiler tile and its duplicates, nil for sprites
+}
+
+// jsonConversion is the JSON description of a conversion, see the README
+// for the schema documentation.
+type jsonConversion struct {
+	Format     string         `json:"format"`
+	Version    int            `json:"version"`
+	Mode       string         `json:"mode"`
+	Target     string         `json:"target"`
+	Image      jsonSize       `json:"image"`
+	TileOffset int            `json:"tileOffset"`
+	TileCount  int            `json:"tileCount"`
+	Tiles      []jsonTile     `json:"tiles"`
+	Palette    []jsonColour   `json:"palette"`
+	Tilemap    *jsonTilemap   `json:"tilemap,omitempty"`
+	Sprites    *jsonSprites   `json:"sprites,omitempty"`
+	Collision  *jsonCollision `json:"collision,omitempty"`
+}
+
+type jsonSize struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+type jsonTile struct {
+	Number      int             `json:"number"`
+	Palette     int             `json:"palette"`
+	X           int             `json:"x"`
+	Y           int             `json:"y"`
+	Orientation string          `json:"orientation"`
+	Duplicates  []jsonDuplicate `json:"duplicates"`
+}
+
+type jsonDuplicate struct {
+	X           int    `json:"x"`
+	Y           int    `json:"y"`
+	Orientation string `json:"orientation"`
+}
+
+// jsonColour is a CRAM colour, with either the SMS byte or the GG word.
+type jsonColour struct {
+	Index int     `json:"index"`
+	SMS   *uint8  `json:"sms,omitempty"`
+	GG    *uint16 `json:"gg,omitempty"`
+	HTML  string  `json:"html"`
+}
+
+type jsonTilemap struct {
+	Width  int      `json:"width"`
+	Height int      `json:"height"`
+	Words  []uint16 `json:"words"`
+}
+
+type jsonSprites struct {
+	SpriteHeight int     `json:"spriteHeight"`
+	FrameWidth   int     `json:"frameWidth"`
+	FrameHeight  int     `json:"frameHeight"`
+	Frames       [][]int `json:"frames"` // the sprite tile numbers of each frame
+
+	Durations  []int           `json:"durations,omitempty"` // of each frame in milliseconds
+	Animations []jsonAnimation `json:"animations,omitempty"`
+}
+
+type jsonAnimation struct {
+	Name      string `json:"name"`
+	First     int    `json:"first"`
+	Frames    int    `json:"frames"`
+	Direction string `json:"direction"`
+}
+
+type jsonCollision struct {
+	Width  int   `json:"width"`
+	Height int   `json:"height"`
+	Cells  []int `json:"cells"` // 1 for a solid tile, row by row
+}
+
+// ToJSON writes a machine-readable description of the conversion: the image
+// size, each unique tile with its source position and duplicates, the
+// palette, and the tilemap or sprite frames, with the collision map and sprite
+// animations of an Aseprite file.
+func (p *Processor) ToJSON() error {
+	if p.compressed() {
+		return fmt.Errorf("compression is only supported by the asm and bin output formats")
+	}
+
+	conversion := jsonConversion{
+		Format:     "smstilemap",
+		Version:    jsonVersion,
+		Mode:       "background",
+		Target:     p.target,
+		Image:      jsonSize{Width: p.image.Bounds().Dx(), Height: p.image.Bounds().Dy()},
+		TileOffset: p.tileOffset,
+		TileCount:  len(p.sourceTiles),
+		Tiles:      []jsonTile{},
+	}
+
+	for _, source := range p.sourceTiles {
+		tile := jsonTile{
+			Number:      int(source.number),
+			Palette:     p.tilePalettes[source.number],
+			X:           source.x,
+			Y:           source.y,
+			Orientation: jsonOrientation(tiler.OrientationNormal),
+			Duplicates:  []jsonDuplicate{},
+		}
+		if source.tile != nil {
+			tile.Orientation = jsonOrientation(source.tile.Orientation())
+			for did := 0; did < source.tile.DuplicateCount(); did++ {
+				inf, err := source.tile.GetDuplicateInfo(did)
+				if err != nil {
+					return err
+				}
+				tile.Duplicates = append(tile.Duplicates, jsonDuplicate{
+					X:           inf.Col() * source.tile.Size(),
+					Y:           inf.Row() * source.tile.Size(),
+					Orientation: jsonOrientation(inf.Orientation()),
+				})
+			}
+		}
+		conversion.Tiles = append(conversion.Tiles, tile)
+	}
+
+	for i := 0; i < 2*gg.PaletteColourCount; i++ {
+		colour, _ := p.cram.ColourAt(gg.PaletteId(i)) // unset colours are black
+		entry := jsonColour{Index: i, HTML: colour.HTML()}
+		if p.gameGear() {
+			word := colour.GG()
+			entry.GG = &word
+		} else {
+			b := smsColour(colour).SMS()
+			entry.SMS = &b
+		}
+		conversion.Palette = append(conversion.Palette, entry)
+	}
+
+	if p.sprites != nil {
+		conversion.Mode = "sprites"
+		conversion.Sprites = &jsonSprites{
+			SpriteHeight: p.sprites.spriteHeight,
+			FrameWidth:   p.sprites.frameWidth,
+			FrameHeight:  p.sprites.frameHeight,
+		}
+		for _, frame := range p.sprites.frames {
+			numbers := make([]int, len(frame)) // a []uint8 would be encoded as base64
+			for i, number := range frame {
+				numbers[i] = int(number)
+			}
+			conversion.Sprites.Frames = append(conversion.Sprites.Frames, numbers)
+		}
+		conversion.Sprites.Durations = p.sprites.durations
+		for _, a := range p.sprites.animations {
+			conversion.Sprites.Animations = append(conversion.Sprites.Animations, jsonAnimation{
+				Name:      a.name,
+				First:     a.first,
+				Frames:    a.count,
+				Direction: a.direction,
+			})
+		}
+	} else if p.levelMap != nil {
+		conversion.Mode = "map"
+		conversion.Tilemap = &jsonTilemap{Width: p.levelMap.Width(), Height: p.levelMap.Height(), Words: p.levelMap.Words()}
+	} else {
+		cols, rows := p.sega.WidthInTiles(), p.binaryTilemapRows()
+		conversion.Tilemap = &jsonTilemap{Width: cols, Height: rows, Words: p.sega.TilemapData()[:cols*rows]}
+	}
+
+	if p.collision != nil {
+		conversion.Collision = &jsonCollision{Width: p.collision.width, Height: p.collision.height}
+		for _, cell := range p.collision.cells {
+			conversion.Collision.Cells = append(conversion.Collision.Cells, int(cell))
+		}
+	}
+
+	data, err := json.MarshalIndent(conversion, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding JSON: %w", err)
+	}
+	if err := os.WriteFile(path.Join(p.outputDirectory, p.baseFilename+".json"), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing JSON file: %w", err)
+	}
+	return nil
+}
+
+// returns the JSON name for the tile orientation
+func jsonOrientation(or tiler.Orientation) string {
+	switch or {
+	case tiler.OrientationFlippedV:
+		return "vflip"
+	case tiler.OrientationFlippedH:
+		return "hflip"
+	case tiler.OrientationFlippedVH:
+		return "vhflip"
+	default:
+		return "normal"
+	}
+}
@@ -0,0 +1,115 @@
+package processor_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/mrcook/smstilemap/cmd/smstilemap/processor"
+)
+
+func TestProcessor_ToJSON(t *testing.T) {
+	dir := t.TempDir()
+	filename := path.Join(dir, "fixture.png")
+	writeFixture(t, filename)
+
+	pro := processor.New(filename, dir)
+	if err := pro.PngToSMS(); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if err := pro.ToJSON(); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	data, err := os.ReadFile(path.Join(dir, "fixture.json"))
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	var conversion map[string]json.RawMessage
+	if err := json.Unmarshal(data, &conversion); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	t.Run("header", func(t *testing.T) {
+		for field, want := range map[string]string{
+			"format":     `"smstilemap"`,
+			"version":    `1`,
+			"mode":       `"background"`,
+			"target":     `"sms"`,
+			"image":      `{"width":16,"height":8}`,
+			"tileOffset": `0`,
+			"tileCount":  `1`,
+		} {
+			assertJSON(t, field, conversion[field], want)
+		}
+	})
+
+	t.Run("tiles with their duplicates", func(t *testing.T) {
+		want := `[{"number":0,"palette":0,"x":0,"y":0,"orientation":"normal","duplicates":[{"x":8,"y":0,"orientation":"hflip"}]}]`
+		assertJSON(t, "tiles", conversion["tiles"], want)
+	})
+
+	t.Run("palette", func(t *testing.T) {
+		var palette []json.RawMessage
+		if err := json.Unmarshal(conversion["palette"], &palette); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if len(palette) != 32 {
+			t.Fatalf("expected 32 palette colours, got %d", len(palette))
+		}
+		assertJSON(t, "palette[0]", palette[0], `{"index":0,"sms":3,"html":"#FF0000"}`)
+		assertJSON(t, "palette[1]", palette[1], `{"index":1,"sms":0,"html":"#000000"}`)
+	})
+
+	t.Run("tilemap", func(t *testing.T) {
+		var tilemap struct {
+			Width  int      `json:"width"`
+			Height int      `json:"height"`
+			Words  []uint16 `json:"words"`
+		}
+		if err := json.Unmarshal(conversion["tilemap"], &tilemap); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if tilemap.Width != 32 || tilemap.Height != 24 || len(tilemap.Words) != 32*24 {
+			t.Fatalf("expected a 32x24 tilemap, got %dx%d with %d words", tilemap.Width, tilemap.Height, len(tilemap.Words))
+		}
+		if tilemap.Words[0] != 0x0000 || tilemap.Words[1] != 0x0200 {
+			t.Errorf("expected tile 0, then tile 0 flipped horizontally, got $%04X, $%04X", tilemap.Words[0], tilemap.Words[1])
+		}
+	})
+}
+
+// writes a 16x8 image of a red triangle on black, followed by its mirror image
+func writeFixture(t *testing.T, filename string) {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			img.Set(x, y, color.Black)
+		}
+		for x := 0; x <= y/2; x++ {
+			img.Set(x, y, color.RGBA{R: 0xFF, A: 0xFF})
+			img.Set(15-x, y, color.RGBA{R: 0xFF, A: 0xFF})
+		}
+	}
+
+	writePNG(t, filename, img)
+}
+
+// compares the JSON value with the expected JSON, ignoring the whitespace
+func assertJSON(t *testing.T, field string, got json.RawMessage, want string) {
+	t.Helper()
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, got); err != nil {
+		t.Fatalf("%s: unexpected error: %q", field, err)
+	}
+	if compact.String() != want {
+		t.Errorf("%s: expected %s, got %s", field, want, compact.String())
+	}
+}
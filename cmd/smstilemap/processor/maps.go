@@ -0,0 +1,90 @@
+package processor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mrcook/smstilemap/assembly"
+	"github.com/mrcook/smstilemap/cmd/smstilemap/processor/internal/tiler"
+	"github.com/mrcook/smstilemap/sms"
+)
+
+// PngToMap converts an image of any size, such as a level larger than the
+// screen, to the SMS tiles and palette, along with a full-size scrolling map.
+// All tiles are de-duplicated across the whole map, and must fit within the
+// 448 tile limit.
+func (p *Processor) PngToMap() error {
+	if err := p.readImage(p.inputFilename, false); err != nil {
+		return fmt.Errorf("input image error: %w", err)
+	}
+	if err := p.imageToMap(); err != nil {
+		return fmt.Errorf("PNG to SMS map error: %w", err)
+	}
+	return nil
+}
+
+// SetMapStrips sets which map strips are written to the assembly output:
+// rows (for vertical scrolling, the default), cols (for horizontal scrolling),
+// or both, which stores the map twice.
+func (p *Processor) SetMapStrips(strips string) error {
+	switch strips {
+	case "rows", "cols", "both":
+		p.mapStrips = strips
+		return nil
+	default:
+		return fmt.Errorf("invalid map strips '%s', must be one of: rows, cols, both", strips)
+	}
+}
+
+// convert the image to an SMS map
+func (p *Processor) imageToMap() error {
+	if p.image == nil {
+		return fmt.Errorf("source image is nil")
+	}
+	width, height := p.image.Bounds().Dx(), p.image.Bounds().Dy()
+	if width%8 != 0 || height%8 != 0 {
+		return fmt.Errorf("map image size (%dx%d) must be a multiple of 8 pixels", width, height)
+	}
+	tiled := tiler.FromImage(p.image, 8)
+	p.levelMap = sms.NewMap(width/8, height/8)
+
+	return p.tiledToSMS(tiled)
+}
+
+// writes the map size and strips as assembly
+func (p *Processor) mapToAssembly() (string, error) {
+	var sb strings.Builder
+
+	sb.WriteString(p.asm.Defines("Map size in tiles", []assembly.Define{
+		{Name: "MapWidth", Value: p.levelMap.Width()},
+		{Name: "MapHeight", Value: p.levelMap.Height()},
+	}).String())
+
+	if p.mapStrips != "cols" {
+		sb.WriteString("\n")
+		if p.generalCompression() {
+			description := fmt.Sprintf("Map data as row strips, %d rows of %d tiles", p.levelMap.Height(), p.levelMap.Width())
+			rows, err := p.compressedBlockToAssembly("MapRows", description, wordsToBytes(p.levelMap.Words()))
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(rows)
+		} else {
+			sb.WriteString(p.asm.MapRows(p.levelMap.Words(), p.levelMap.Width()).String())
+		}
+	}
+	if p.mapStrips == "cols" || p.mapStrips == "both" {
+		sb.WriteString("\n")
+		if p.generalCompression() {
+			description := fmt.Sprintf("Map data as column strips, %d columns of %d tiles", p.levelMap.Width(), p.levelMap.Height())
+			columns, err := p.compressedBlockToAssembly("MapColumns", description, wordsToBytes(p.levelMap.ColumnWords()))
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(columns)
+		} else {
+			sb.WriteString(p.asm.MapColumns(p.levelMap.ColumnWords(), p.levelMap.Height()).String())
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/mrcook/smstilemap/gg"
+	"github.com/mrcook/smstilemap/match"
+)
+
+// The image colours are matched to the nearest colour of the target console.
+// By default each RGB channel is matched on its own, using fixed thresholds,
+// which is fast but not always the closest looking colour, so the matcher can
+// be changed to one measuring the distance between the whole colours.
+
+var matchers = map[string]match.Matcher{
+	"threshold": match.Threshold{},
+	"euclidean": match.Euclidean{},
+	"weighted":  match.Weighted{},
+	"ciede2000": match.CIEDE2000{},
+}
+
+// SetColourMatch sets how the image colours are matched to the console
+// colours: threshold, euclidean, weighted, or ciede2000.
+func (p *Processor) SetColourMatch(name string) error {
+	matcher, ok := matchers[name]
+	if !ok {
+		return fmt.Errorf("invalid colour match '%s', must be one of: threshold, euclidean, weighted, ciede2000", name)
+	}
+	p.matcher = matcher
+	p.matched = nil
+	return nil
+}
+
+// returns the image pixel colour as its nearest colour on the target console.
+// The matches are kept, as an image uses few colours compared to its pixels.
+func (p *Processor) colourFor(c color.Color) gg.Colour {
+	r, g, b, _ := c.RGBA()
+	rgb := [3]uint8{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8)}
+	if colour, ok := p.matched[rgb]; ok {
+		return colour
+	}
+
+	var colour gg.Colour
+	if p.gameGear() {
+		colour = p.matcher.GG(rgb[0], rgb[1], rgb[2])
+	} else {
+		colour = ggColour(p.matcher.SMS(rgb[0], rgb[1], rgb[2]))
+	}
+	if p.matched == nil {
+		p.matched = make(map[[3]uint8]gg.Colour)
+	}
+	p.matched[rgb] = colour
+	return colour
+}
@@ -0,0 +1,265 @@
+package processor
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mrcook/smstilemap/gg"
+	"github.com/mrcook/smstilemap/sms"
+)
+
+const maxPaletteColours = 2 * sms.PaletteColourCount
+
+// SetPalette loads a fixed palette, so that the assets of a program can share
+// the same CRAM layout. The colours are kept in the order of the file, with the
+// first 16 being the background palette, and the rest the sprite palette.
+// The image pixels are indexed against this palette, rather than building one.
+//
+// The file can be a PNG image (a strip of colours, read row by row), a GIMP
+// `.gpl` palette, or a `.bin` file of CRAM data for the target: 16 or 32 bytes
+// for the SMS, or 32 or 64 bytes for the Game Gear. The target must be set first.
+func (p *Processor) SetPalette(filename string) error {
+	var colours []gg.Colour
+	var err error
+
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".png":
+		colours, err = p.readPNGPalette(filename)
+	case ".gpl":
+		colours, err = p.readGIMPPalette(filename)
+	case ".bin":
+		colours, err = p.readBinaryPalette(filename)
+	default:
+		return fmt.Errorf("unsupported palette file '%s', must be a .png, .gpl, or .bin file", filename)
+	}
+	if err != nil {
+		return fmt.Errorf("palette file error: %w", err)
+	}
+	if len(colours) == 0 {
+		return fmt.Errorf("palette file error: no colours found")
+	} else if len(colours) > maxPaletteColours {
+		return fmt.Errorf("palette file error: too many colours, got %d, max is %d", len(colours), maxPaletteColours)
+	}
+
+	p.fixedPalette = &gg.Palette{}
+	for i, colour := range colours {
+		if err := p.fixedPalette.SetColourAt(gg.PaletteId(i), colour); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns the colours of the two fixed palettes, in index order
+func (p *Processor) fixedPaletteColours() (palettes [2][]gg.Colour) {
+	for i := 0; i < maxPaletteColours; i++ {
+		colour, err := p.fixedPalette.ColourAt(gg.PaletteId(i))
+		if err != nil {
+			break // the colours are set from index 0, without gaps
+		}
+		palettes[i/sms.PaletteColourCount] = append(palettes[i/sms.PaletteColourCount], colour)
+	}
+	return
+}
+
+// sets the partition options to use the fixed palette, when given
+func (p *Processor) applyFixedPalette(opts *sms.PartitionOptions[gg.Colour]) {
+	if p.fixedPalette != nil {
+		opts.Seed = p.fixedPaletteColours()
+		opts.Fixed = true
+	}
+}
+
+// useImagePalette sets the fixed palette from an indexed colour image, so the
+// palette order chosen by the artist is kept as the SMS palette IDs. The image
+// palette is used when no fixed palette was given, and the pixels only use
+// its first 32 colours, or the first 16 for sprites, which are placed in the
+// sprite palette with index 0 as the transparent colour. As the first 16
+// colours are the background palette, and the rest the sprite palette, each
+// tile must only use the colours from one half. Otherwise, the palette is
+// built from the image colours, with a warning.
+func (p *Processor) useImagePalette(sprites bool) error {
+	img, ok := p.image.(*image.Paletted)
+	if !ok || p.fixedPalette != nil {
+		return nil
+	}
+
+	used := 0 // the number of palette colours, up to the last one used
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			used = max(used, int(img.ColorIndexAt(x, y))+1)
+		}
+	}
+	offset := 0
+	if sprites {
+		offset = sms.PaletteColourCount
+	}
+	if used > maxPaletteColours-offset {
+		p.warn("the image palette order is not kept, as the pixels use %d of its colours, max is %d", used, maxPaletteColours-offset)
+		return nil
+	}
+	if row, col, found := mixedPaletteTile(img); found {
+		p.warn("the image palette order is not kept, as the tile at row %d, col %d uses colours from both halves of the palette (0-15 and 16-31)", row, col)
+		return nil
+	}
+
+	palette := img.Palette[:used]
+	if sprites && used > 0 {
+		// a copy of the image, so the pixels using index 0 are transparent,
+		// without changing the palette of the decoded image
+		transparent := *img
+		transparent.Palette = append(color.Palette{color.Transparent}, img.Palette[1:]...)
+		p.image = &transparent
+		palette = transparent.Palette[:used]
+	}
+
+	colours := make([]gg.Colour, offset) // the unused background palette, for sprites
+	for _, c := range palette {
+		colours = append(colours, p.colourFor(c))
+	}
+	p.fixedPalette = &gg.Palette{}
+	for i, colour := range colours {
+		if err := p.fixedPalette.SetColourAt(gg.PaletteId(i), colour); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// returns the location of the first 8x8 tile using colour indexes from both
+// the background (0-15) and sprite (16-31) halves of the image palette.
+func mixedPaletteTile(img *image.Paletted) (row, col int, found bool) {
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += 8 {
+		for x := bounds.Min.X; x < bounds.Max.X; x += 8 {
+			var halves [2]bool
+			for ty := y; ty < min(y+8, bounds.Max.Y); ty++ {
+				for tx := x; tx < min(x+8, bounds.Max.X); tx++ {
+					halves[min(int(img.ColorIndexAt(tx, ty))/sms.PaletteColourCount, 1)] = true
+				}
+			}
+			if halves[0] && halves[1] {
+				return (y - bounds.Min.Y) / 8, (x - bounds.Min.X) / 8, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// validateFixedPalette checks every pixel colour of the image is in the fixed
+// palette, reporting the first pixel using each missing colour.
+func (p *Processor) validateFixedPalette(img image.Image) error {
+	if p.fixedPalette == nil {
+		return nil
+	}
+
+	var missing []string
+	seen := make(map[gg.Colour]bool)
+
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			colour := p.colourFor(img.At(x, y))
+			if seen[colour] {
+				continue
+			}
+			seen[colour] = true
+			if _, err := p.fixedPalette.PaletteIdFor(colour); err != nil {
+				missing = append(missing, fmt.Sprintf("%s at pixel (%d, %d)", colour.HTML(), x, y))
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%d colour(s) are not in the fixed palette:\n  %s", len(missing), strings.Join(missing, "\n  "))
+	}
+	return nil
+}
+
+func (p *Processor) readPNGPalette(filename string) (colours []gg.Colour, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	if bounds.Dx()*bounds.Dy() > maxPaletteColours {
+		return nil, fmt.Errorf("palette image has %d pixels, max is %d", bounds.Dx()*bounds.Dy(), maxPaletteColours)
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			colours = append(colours, p.colourFor(img.At(x, y)))
+		}
+	}
+	return colours, nil
+}
+
+// reads the colours of a GIMP palette, ignoring the header and comment lines
+func (p *Processor) readGIMPPalette(filename string) (colours []gg.Colour, err error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == 1:
+			if text != "GIMP Palette" {
+				return nil, fmt.Errorf("not a GIMP palette file")
+			}
+			continue
+		case len(text) == 0, strings.HasPrefix(text, "#"), strings.HasPrefix(text, "Name:"), strings.HasPrefix(text, "Columns:"):
+			continue
+		}
+
+		var r, g, b uint8
+		if _, err := fmt.Sscanf(text, "%d %d %d", &r, &g, &b); err != nil {
+			return nil, fmt.Errorf("invalid colour on line %d: %w", line, err)
+		}
+		colours = append(colours, p.colourFor(color.NRGBA{R: r, G: g, B: b, A: 255}))
+	}
+	return colours, scanner.Err()
+}
+
+// reads the CRAM data of the target, a byte per SMS colour, or a word per GG colour
+func (p *Processor) readBinaryPalette(filename string) (colours []gg.Colour, err error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if p.gameGear() {
+		palette := gg.Palette{}
+		if err := palette.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+		for i := 0; i < len(data)/2; i++ {
+			colour, _ := palette.ColourAt(gg.PaletteId(i))
+			colours = append(colours, colour)
+		}
+		return colours, nil
+	}
+
+	palette := sms.Palette{}
+	if err := palette.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	for _, b := range data {
+		colours = append(colours, ggColour(sms.Colour(b)))
+	}
+	return colours, nil
+}
@@ -0,0 +1,82 @@
+package processor_test
+
+import (
+	"bytes"
+	"image"
+	"path"
+	"testing"
+
+	"github.com/mrcook/smstilemap/cmd/smstilemap/processor"
+)
+
+func TestProcessor_SetPalette(t *testing.T) {
+	// black, white, red, and blue, with the image only using red and blue
+	palette := []uint8{0x00, 0x3F, 0x03, 0x30}
+
+	strip := image.NewRGBA(image.Rect(0, 0, len(palette), 1))
+	for i, c := range palette {
+		strip.Set(i, 0, smsRGB(c))
+	}
+	gpl := "GIMP Palette\nName: Test\nColumns: 4\n# black, white, red, blue\n  0   0   0\n255 255 255\n255   0   0\n  0   0 255\n"
+	cram := make([]byte, 32)
+	copy(cram, palette)
+
+	tables := []struct {
+		name  string
+		write func(t *testing.T, filename string)
+	}{
+		{"palette.gpl", func(t *testing.T, filename string) { writeFile(t, filename, []byte(gpl)) }},
+		{"palette.png", func(t *testing.T, filename string) { writePNG(t, filename, strip) }},
+		{"palette.bin", func(t *testing.T, filename string) { writeFile(t, filename, cram) }},
+	}
+	for _, data := range tables {
+		t.Run(data.name, func(t *testing.T) {
+			dir := t.TempDir()
+			filename := path.Join(dir, "image.png")
+			writePNG(t, filename, tileRow(smsColours(0x03, 0x30)))
+			paletteFilename := path.Join(dir, data.name)
+			data.write(t, paletteFilename)
+
+			pro := processor.New(filename, dir)
+			if err := pro.SetPalette(paletteFilename); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if err := pro.PngToSMS(); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if err := pro.ToBinary(); err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+
+			cram := []byte(readFile(t, path.Join(dir, "image-palette.bin")))
+			if len(cram) != 32 || !bytes.Equal(cram[:4], palette) {
+				t.Errorf("expected the palette to start % X, got % X", palette, cram)
+			}
+			// the first row of alternating red and blue pixels uses palette IDs 2 and 3
+			tiles := []byte(readFile(t, path.Join(dir, "image-tiles.bin")))
+			if want := []byte{0x55, 0xFF, 0x00, 0x00}; !bytes.Equal(tiles[:4], want) {
+				t.Errorf("expected the first tile row to be % X, got % X", want, tiles[:4])
+			}
+		})
+	}
+
+	t.Run("with an image colour missing from the palette", func(t *testing.T) {
+		dir := t.TempDir()
+		filename := path.Join(dir, "image.png")
+		img := tileRow(smsColours(0x03, 0x30), smsColours(0x30))
+		img.Set(11, 3, smsRGB(0x0C)) // green
+		writePNG(t, filename, img)
+		paletteFilename := path.Join(dir, "palette.gpl")
+		writeFile(t, paletteFilename, []byte(gpl))
+
+		pro := processor.New(filename, dir)
+		if err := pro.SetPalette(paletteFilename); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		err := pro.PngToSMS()
+		want := "PNG to SMS data error: 1 colour(s) are not in the fixed palette:\n  #00FF00 at pixel (11, 3)"
+		if err == nil || err.Error() != want {
+			t.Errorf("expected error %q, got %v", want, err)
+		}
+	})
+}
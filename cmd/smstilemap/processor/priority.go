@@ -0,0 +1,215 @@
+package processor
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+
+	"github.com/mrcook/smstilemap/cmd/smstilemap/processor/internal/tiler"
+	"github.com/mrcook/smstilemap/gg"
+	"github.com/mrcook/smstilemap/sms"
+)
+
+// A priority mask is a second image, the same size as the source image, in
+// which the pixels to be drawn in front of sprites are marked. A pixel is
+// marked when it is opaque and not black, or when a marker colour is given,
+// when it matches that colour.
+//
+// Any tile containing a marked pixel gets the priority bit set in its tilemap
+// entry. The VDP draws pixels with palette index 0 behind sprites, so the
+// unmarked pixels of a priority tile are its background and must all use the
+// same colour, which is placed at index 0 of the tile palette.
+
+// cell is the location of a tile in the image, in 8x8 tile rows/cols.
+type cell struct {
+	row, col int
+}
+
+// priorityTile holds the colours used by a unique tile where it is marked for
+// priority, combined from all the cells it is marked in.
+type priorityTile struct {
+	background []gg.Colour // colours of the unmarked pixels
+	foreground []gg.Colour // colours of the marked pixels
+	cells      []priorityCell
+}
+
+// priorityCell holds the colours of a single cell marked for priority.
+type priorityCell struct {
+	cell
+	background []gg.Colour
+	foreground []gg.Colour
+}
+
+// SetPriorityMask reads the priority mask image (PNG, GIF, or BMP) to use
+// during conversion. The marker colour, as a hex value (#RRGGBB), is optional.
+func (p *Processor) SetPriorityMask(filename, markerColour string) error {
+	var err error
+	p.priorityMask, err = decodeImage(filename)
+	if err != nil {
+		return fmt.Errorf("priority mask file error: %w", err)
+	}
+
+	if len(markerColour) > 0 {
+		var c color.NRGBA
+		if _, err := fmt.Sscanf(markerColour, "#%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+			return fmt.Errorf("invalid priority marker colour, expected #RRGGBB: %w", err)
+		}
+		c.A = 255
+		p.priorityMarker = c
+	}
+	return nil
+}
+
+// returns true when the mask pixel marks a pixel for priority.
+func (p *Processor) isPriorityPixel(c color.Color) bool {
+	r, g, b, a := c.RGBA()
+	if a == 0 {
+		return false
+	}
+	if p.priorityMarker != nil {
+		mr, mg, mb, _ := p.priorityMarker.RGBA()
+		return r>>8 == mr>>8 && g>>8 == mg>>8 && b>>8 == mb>>8
+	}
+	return r|g|b > 0
+}
+
+// reads the priority mask for each tile cell of the image, returning the
+// priority colour info for each unique tile that is marked in at least one
+// of its locations.
+func (p *Processor) priorityTiles(tiled *tiler.Tiled) (map[int]*priorityTile, error) {
+	p.priorityCells = make(map[cell]bool)
+	tiles := make(map[int]*priorityTile)
+
+	if p.priorityMask == nil {
+		return tiles, nil
+	}
+	if p.priorityMask.Bounds().Size() != p.image.Bounds().Size() {
+		return nil, fmt.Errorf("priority mask size (%v) does not match the image size (%v)", p.priorityMask.Bounds().Size(), p.image.Bounds().Size())
+	}
+
+	for i := 0; i < tiled.TileCount(); i++ {
+		tile, _ := tiled.GetTile(i)
+
+		cells := []cell{{row: tile.Row(), col: tile.Col()}}
+		for did := 0; did < tile.DuplicateCount(); did++ {
+			inf, err := tile.GetDuplicateInfo(did)
+			if err != nil {
+				return nil, err
+			}
+			cells = append(cells, cell{row: inf.Row(), col: inf.Col()})
+		}
+
+		for _, c := range cells {
+			background, foreground, marked := p.priorityCellColours(c, tile.Size())
+			if !marked {
+				continue
+			}
+			p.priorityCells[c] = true
+
+			pt, ok := tiles[i]
+			if !ok {
+				pt = &priorityTile{}
+				tiles[i] = pt
+			}
+			pt.background = uniqueSmsColours(append(pt.background, background...))
+			pt.foreground = uniqueSmsColours(append(pt.foreground, foreground...))
+			pt.cells = append(pt.cells, priorityCell{cell: c, background: background, foreground: foreground})
+		}
+	}
+
+	return tiles, nil
+}
+
+// returns the colours of the unmarked and marked pixels of the tile cell, and
+// whether any pixels are marked.
+func (p *Processor) priorityCellColours(c cell, tileSize int) (background, foreground []gg.Colour, marked bool) {
+	imgMin := p.image.Bounds().Min
+	maskMin := p.priorityMask.Bounds().Min
+
+	for y := c.row * tileSize; y < (c.row+1)*tileSize; y++ {
+		for x := c.col * tileSize; x < (c.col+1)*tileSize; x++ {
+			colour := p.colourFor(p.image.At(imgMin.X+x, imgMin.Y+y))
+			if p.isPriorityPixel(p.priorityMask.At(maskMin.X+x, maskMin.Y+y)) {
+				foreground = append(foreground, colour)
+				marked = true
+			} else {
+				background = append(background, colour)
+			}
+		}
+	}
+	return uniqueSmsColours(background), uniqueSmsColours(foreground), marked
+}
+
+// returns the palette partitioning options for the priority tiles, placing
+// their background colours at palette index 0. As there are only two
+// palettes, at most two different background colours can be used.
+func priorityPartitionOptions(tiles map[int]*priorityTile) (sms.PartitionOptions[gg.Colour], error) {
+	opts := sms.PartitionOptions[gg.Colour]{FirstColour: make(map[int]gg.Colour)}
+
+	// process in tile order so the palette ordering is consistent between runs
+	var ids []int
+	for i := range tiles {
+		ids = append(ids, i)
+	}
+	sort.Ints(ids)
+
+	var backgrounds []gg.Colour
+	for _, i := range ids {
+		pt := tiles[i]
+		if len(pt.background) != 1 {
+			continue
+		}
+		if !containsColour(backgrounds, pt.background[0]) {
+			backgrounds = append(backgrounds, pt.background[0])
+		}
+		opts.FirstColour[i] = pt.background[0]
+	}
+
+	if len(backgrounds) > len(opts.Seed) {
+		return opts, fmt.Errorf("priority tiles use %d different background colours, max is %d (one per palette)", len(backgrounds), len(opts.Seed))
+	}
+	for pal, c := range backgrounds {
+		opts.Seed[pal] = []gg.Colour{c}
+	}
+	return opts, nil
+}
+
+// validatePriorityTiles checks each priority tile has a single background
+// colour, at palette index 0, and that no foreground pixels use that colour.
+// A cell is reported when its own colours are invalid, or when the cells
+// sharing the same tile use different background colours.
+func validatePriorityTiles(tiles map[int]*priorityTile, partition *sms.Partition[gg.Colour]) error {
+	colourErr := &TileColourError{}
+
+	for i, pt := range tiles {
+		palette := partition.Palettes[partition.Selected[i]]
+
+		// the background colours of the cells which are otherwise valid
+		var backgrounds []gg.Colour
+		for _, pc := range pt.cells {
+			if len(pc.background) == 1 {
+				backgrounds = uniqueSmsColours(append(backgrounds, pc.background...))
+			}
+		}
+
+		for _, pc := range pt.cells {
+			cellErr := CellColourError{Row: pc.row, Col: pc.col, ColourCount: len(pc.background)}
+
+			if len(pc.background) > 1 {
+				cellErr.Problem = PriorityBackgroundColour
+				cellErr.Colours = pc.background
+			} else if len(backgrounds) > 1 {
+				cellErr.Problem = PriorityBackgroundColour
+				cellErr.Colours = backgrounds
+			} else if len(palette) > 0 && containsColour(pc.foreground, palette[0]) {
+				cellErr.Problem = PriorityForegroundColour
+				cellErr.Colours = []gg.Colour{palette[0]}
+			} else {
+				continue
+			}
+			colourErr.Cells = append(colourErr.Cells, cellErr)
+		}
+	}
+
+	return colourErr.orNil()
+}
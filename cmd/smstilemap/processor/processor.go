@@ -3,33 +3,104 @@ package processor
 import (
 	"fmt"
 	"image"
+	"image/color"
+	_ "image/gif"
 	"image/png"
 	"os"
 	"path"
 	"strings"
 
+	"github.com/mrcook/smstilemap/aseprite"
 	"github.com/mrcook/smstilemap/assembly"
 	"github.com/mrcook/smstilemap/cmd/smstilemap/processor/internal/tiler"
+	"github.com/mrcook/smstilemap/gg"
+	"github.com/mrcook/smstilemap/match"
 	"github.com/mrcook/smstilemap/sms"
+	_ "golang.org/x/image/bmp"
 )
 
 type Processor struct {
-	pngInputFilename string
-	outputDirectory  string
-	baseFilename     string
-
-	image image.Image
-	sega  sms.SMS
+	inputFilename   string
+	outputDirectory string
+	baseFilename    string
+
+	target       string // the console to convert for, see target.go
+	image        image.Image
+	sega         sms.SMS
+	cram         gg.Palette             // the palette colours, for either console
+	matcher      match.Matcher          // matches the image colours to the console colours
+	matched      map[[3]uint8]gg.Colour // the matched colour of each RGB colour, see match.go
+	vram         sms.VRAMLayout
+	tileOffset   int                    // first tile number for the converted tiles
+	tilePalettes [sms.MaxTileNumber]int // palette selected for each SMS tile
+	fixedPalette *gg.Palette            // set when using a fixed palette, see palette.go
+	sourceTiles  []sourceTile           // the converted tiles with their image positions
+	sprites      *spriteSheet           // set when converting a sprite sheet
+	levelMap     *sms.Map               // set when converting a scrolling map
+	collision    *collisionMap          // set by the collision layer of an Aseprite file
+	aseprite     *aseprite.File         // set when converting Aseprite sprite frames, see aseprite.go
+	mapStrips    string                 // map strips to output: rows (default), cols, or both
+	tilemapRows  int                    // tilemap rows in the binary output, default: visible rows
+
+	tileCompression    string // compression for the tile data, see compress.go
+	tilemapCompression string // compression for the tilemap
+	compression        string // general compression for the other data blocks
+	omitDecompressors  bool   // leave out the Z80 decompression routines
+
+	asm assembly.Options // syntax and layout of the assembly output, see dialect.go
+
+	priorityMask   image.Image
+	priorityMarker color.Color   // optional mask colour marking priority pixels
+	priorityCells  map[cell]bool // tile cells with the priority bit set
+
+	warnings []string // problems that did not stop the conversion
 }
 
 func New(srcFilename, outputDir string) *Processor {
 	return &Processor{
-		pngInputFilename: srcFilename,
-		outputDirectory:  outputDirectory(outputDir, srcFilename),
-		baseFilename:     baseFilename(srcFilename),
+		inputFilename:   srcFilename,
+		outputDirectory: outputDirectory(outputDir, srcFilename),
+		baseFilename:    baseFilename(srcFilename),
+		vram:            sms.DefaultVRAMLayout(),
+		target:          targetSMS,
+		matcher:         match.Threshold{},
 	}
 }
 
+// Warnings returns the problems found during the conversion that did not stop
+// it, such as an image palette that could not be kept.
+func (p *Processor) Warnings() []string {
+	return p.warnings
+}
+
+func (p *Processor) warn(format string, a ...any) {
+	p.warnings = append(p.warnings, fmt.Sprintf(format, a...))
+}
+
+// SetScreenHeight sets the SMS screen mode height in pixels: 192, 224, or 240.
+// The extended 224 and 240-line modes use the larger 32x32 name table layout.
+// The name table location in the VRAM layout is updated to match the mode.
+func (p *Processor) SetScreenHeight(height int) error {
+	if err := p.sega.SetScreenHeight(height); err != nil {
+		return err
+	}
+	layout := p.sega.VRAMLayout()
+	p.vram.Patterns = layout.Patterns
+	p.vram.NameTable = layout.NameTable
+	return nil
+}
+
+// SetTileOffset sets the first tile number for the converted tiles, so they
+// can be loaded into VRAM after others, such as a font or HUD. The tilemap
+// entries reference the shifted tile numbers.
+func (p *Processor) SetTileOffset(offset int) error {
+	if offset < 0 || offset >= sms.MaxTileNumber {
+		return fmt.Errorf("invalid tile offset %d, must be within 0..%d", offset, sms.MaxTileNumber-1)
+	}
+	p.tileOffset = offset
+	return nil
+}
+
 func (p *Processor) CreateOutputDirectory() error {
 	if err := os.MkdirAll(p.outputDirectory, 0755); err != nil {
 		return fmt.Errorf("error creating output directory: %w", err)
@@ -38,8 +109,8 @@ func (p *Processor) CreateOutputDirectory() error {
 }
 
 func (p *Processor) PngToSMS() error {
-	if err := p.readPNG(p.pngInputFilename); err != nil {
-		return fmt.Errorf("PNG input file error: %w", err)
+	if err := p.readImage(p.inputFilename, false); err != nil {
+		return fmt.Errorf("input image error: %w", err)
 	}
 	if err := p.imageToSMS(); err != nil {
 		return fmt.Errorf("PNG to SMS data error: %w", err)
@@ -48,13 +119,50 @@ func (p *Processor) PngToSMS() error {
 }
 
 func (p *Processor) ToAssembly() error {
+	if err := p.validateCompression(); err != nil {
+		return err
+	}
 	var sb strings.Builder
 
-	sb.WriteString(assembly.Tilemap(p.sega.TilemapData()).String())
+	sb.WriteString(p.asm.Defines("VRAM layout", p.vramDefines()).String())
 	sb.WriteString("\n")
-	sb.WriteString(assembly.Palettes(p.sega.PaletteData()).String())
+	var tilemap string
+	var err error
+	if p.sprites != nil {
+		tilemap, err = p.spriteFramesToAssembly()
+	} else if p.levelMap != nil {
+		tilemap, err = p.mapToAssembly()
+	} else {
+		tilemap, err = p.tilemapToAssembly()
+	}
+	if err != nil {
+		return fmt.Errorf("error compressing tilemap: %w", err)
+	}
+	sb.WriteString(tilemap)
+	sb.WriteString("\n")
+	asepriteData, err := p.asepriteDataToAssembly()
+	if err != nil {
+		return fmt.Errorf("error compressing collision map: %w", err)
+	}
+	if len(asepriteData) > 0 {
+		sb.WriteString(asepriteData)
+		sb.WriteString("\n")
+	}
+	palettes, err := p.palettesToAssembly()
+	if err != nil {
+		return fmt.Errorf("error compressing palette: %w", err)
+	}
+	sb.WriteString(palettes)
 	sb.WriteString("\n")
-	sb.WriteString(assembly.Tiles(p.sega.TileData()).String())
+	tiles, err := p.tilesToAssembly()
+	if err != nil {
+		return fmt.Errorf("error compressing tile data: %w", err)
+	}
+	sb.WriteString(tiles)
+	if p.includeDecompressors() {
+		sb.WriteString("\n")
+		sb.WriteString(p.decompressorsToAssembly())
+	}
 
 	f, err := os.Create(path.Join(p.outputDirectory, p.baseFilename+".asm"))
 	if err != nil {
@@ -79,26 +187,40 @@ func (p *Processor) SaveTilesToImage() error {
 
 // SaveTilemapToImage converts the SMS tilemap data back to a normal image
 func (p *Processor) SaveTilemapToImage() error {
-	dstImage, err := p.smsToImage()
+	if p.sprites != nil {
+		return fmt.Errorf("no tilemap is generated for sprite sheets")
+	}
+	dstImage, err := p.smsToImage(false)
 	if err != nil {
 		return err
 	}
 	return p.saveImageToFilename(dstImage, p.pngFilename())
 }
 
-func (p *Processor) readPNG(filename string) error {
-	f, err := os.Open(filename)
+// reads the input image, which can be a PNG, GIF, BMP, or Aseprite file. For
+// indexed colour images, the palette order is kept, see useImagePalette.
+func (p *Processor) readImage(filename string, sprites bool) error {
+	var err error
+	if isAsepriteFile(filename) {
+		err = p.readAseprite(filename, sprites)
+	} else {
+		p.image, err = decodeImage(filename)
+	}
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	return p.useImagePalette(sprites)
+}
 
-	p.image, err = png.Decode(f)
+func decodeImage(filename string) (image.Image, error) {
+	f, err := os.Open(filename)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer f.Close()
 
-	return nil
+	img, _, err := image.Decode(f)
+	return img, err
 }
 
 // convert the PNG image to an SMS representation
@@ -106,32 +228,87 @@ func (p *Processor) imageToSMS() error {
 	// validate image is suitable for conversion to the SMS
 	if p.image == nil {
 		return fmt.Errorf("source image is nil")
-	} else if p.image.Bounds().Dx() > sms.ScreenWidth || p.image.Bounds().Dy() > sms.ScreenHeight {
-		return fmt.Errorf("image size too big for SMS screen (%d x %d)", sms.ScreenWidth, sms.ScreenHeight)
+	}
+	if width, height := p.screenSize(); p.image.Bounds().Dx() > width || p.image.Bounds().Dy() > height {
+		return fmt.Errorf("image size too big for %s screen (%d x %d)", p.consoleName(), width, height)
 	}
 	tiled := tiler.FromImage(p.image, 8)
 
+	return p.tiledToSMS(tiled)
+}
+
+// convert the tiled image to the SMS tiles, palette, and tilemap
+func (p *Processor) tiledToSMS(tiled *tiler.Tiled) error {
 	// check there are too many colours for the SMS
 	if tiled.ColourCount() > sms.MaxColourCount {
 		return fmt.Errorf("too many unique colours for SMS (max: %d)", sms.MaxColourCount)
 	}
 
+	// check there are too many tiles for the SMS
+	if tiled.TileCount() > sms.MaxTileCount {
+		return fmt.Errorf("too many unique tiles for SMS (max: %d), got %d", sms.MaxTileCount, tiled.TileCount())
+	}
+	if p.tileOffset+tiled.TileCount() > sms.MaxTileCount {
+		return fmt.Errorf("tile offset %d plus %d unique tiles is more than the SMS supports (max: %d)", p.tileOffset, tiled.TileCount(), sms.MaxTileCount)
+	}
+	if err := p.validateVRAMLayout(tiled.TileCount()); err != nil {
+		return err
+	}
+	if err := p.sega.SetTileOffset(p.tileOffset); err != nil {
+		return err
+	}
+
+	if err := p.validateFixedPalette(p.image); err != nil {
+		return err
+	}
+
+	// assign each tile to one of the two palettes
+	colours := make([][]gg.Colour, tiled.TileCount())
+	for i := 0; i < tiled.TileCount(); i++ {
+		tile, _ := tiled.GetTile(i)
+		tileColours, err := p.smsTileColours(tile)
+		if err != nil {
+			return err
+		}
+		colours[i] = tileColours
+	}
+	priority, err := p.priorityTiles(tiled)
+	if err != nil {
+		return err
+	}
+	opts, err := priorityPartitionOptions(priority)
+	if err != nil {
+		return err
+	}
+	p.applyFixedPalette(&opts)
+	partition, err := sms.PartitionColoursWith(colours, opts)
+	if err := validateTileColours(tiled, colours, partition, err); err != nil {
+		return err
+	}
+	if err := validatePriorityTiles(priority, partition); err != nil {
+		return err
+	}
+	if err := p.addPartitionToSmsPalette(partition); err != nil {
+		return fmt.Errorf("error adding colours to SMS palette: %w", err)
+	}
+
+	// the first image tile is added at the tile offset
+	if tiled.TileCount() > 0 {
+		p.fillTilemap(partition.Selected[0])
+	}
+
 	// add the image tiles to the SMS
 	for i := 0; i < tiled.TileCount(); i++ {
 		tile, _ := tiled.GetTile(i)
-		if err := p.convertAndAddTileToSms(tile); err != nil {
+		if err := p.convertAndAddTileToSms(tile, i, partition); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (p *Processor) convertAndAddTileToSms(tile *tiler.Tile) error {
-	if err := p.addTileColoursToSmsPalette(tile); err != nil {
-		return fmt.Errorf("error adding colours to SMS palette: %w", err)
-	}
-
-	smsTile, err := p.convertToSmsTile(tile)
+func (p *Processor) convertAndAddTileToSms(tile *tiler.Tile, tileIndex int, partition *sms.Partition[gg.Colour]) error {
+	smsTile, err := p.convertToSmsTile(tile, tileIndex, partition)
 	if err != nil {
 		return fmt.Errorf("error converting image tile to SMS tile: %w", err)
 	}
@@ -140,28 +317,46 @@ func (p *Processor) convertAndAddTileToSms(tile *tiler.Tile) error {
 	if err != nil {
 		return err
 	}
+	p.tilePalettes[tid] = partition.Selected[tileIndex]
+	p.sourceTiles = append(p.sourceTiles, sourceTile{number: tid, x: tile.ColPosInPixels(), y: tile.RowPosInPixels(), tile: tile})
 
-	if err := p.addTileToTilemap(tile, tid); err != nil {
+	if err := p.addTileToTilemap(tile, tid, partition.Selected[tileIndex]); err != nil {
 		return fmt.Errorf("error adding tile to SMS tilemap: %w", err)
 	}
 
 	return nil
 }
 
-// make sure all tile colours are added to the SMS palette
-func (p *Processor) addTileColoursToSmsPalette(tile *tiler.Tile) error {
-	for _, c := range tile.Palette() {
-		r, g, b, _ := c.RGBA()
-		data := sms.ColourDataForNearestRGB(uint8(r), uint8(g), uint8(b))
-		if _, err := p.sega.AddPaletteColour(data.Index); err != nil {
-			return err
+// returns the target colours used by the tile, in pixel order
+func (p *Processor) smsTileColours(tile *tiler.Tile) (colours []gg.Colour, err error) {
+	for row := 0; row < tile.Size(); row++ {
+		for col := 0; col < tile.Size(); col++ {
+			c, err := tile.OrientationAt(row, col, tile.Orientation())
+			if err != nil {
+				return nil, err
+			}
+			colours = append(colours, p.colourFor(c))
+		}
+	}
+	return
+}
+
+// sets the CRAM colours from the partitioned palettes
+func (p *Processor) addPartitionToSmsPalette(partition *sms.Partition[gg.Colour]) error {
+	for pal, colours := range partition.Palettes {
+		for i, colour := range colours {
+			pid := gg.PaletteId(pal*gg.PaletteColourCount + i)
+			if err := p.cram.SetColourAt(pid, colour); err != nil {
+				return err
+			}
 		}
 	}
 	return nil
 }
 
-// convert to an SMS tile, matching colours to SMS palette colours
-func (p *Processor) convertToSmsTile(tile *tiler.Tile) (*sms.Tile, error) {
+// convert to an SMS tile, with the pixels indexing the colours of the palette
+// selected for the tile
+func (p *Processor) convertToSmsTile(tile *tiler.Tile, tileIndex int, partition *sms.Partition[gg.Colour]) (*sms.Tile, error) {
 	smsTile := sms.Tile{}
 
 	for row := 0; row < tile.Size(); row++ {
@@ -171,11 +366,8 @@ func (p *Processor) convertToSmsTile(tile *tiler.Tile) (*sms.Tile, error) {
 			if err != nil {
 				return nil, err
 			}
-			r, g, b, _ := c.RGBA()
-			data := sms.ColourDataForNearestRGB(uint8(r), uint8(g), uint8(b))
-
 			// find the palette ID for the colour
-			pid, err := p.sega.PaletteIdForColour(data.Index)
+			pid, err := partition.PaletteIdFor(tileIndex, p.colourFor(c))
 			if err != nil {
 				return nil, err
 			}
@@ -191,12 +383,13 @@ func (p *Processor) convertToSmsTile(tile *tiler.Tile) (*sms.Tile, error) {
 }
 
 // update tilemap with the tile+duplicate locations
-func (p *Processor) addTileToTilemap(tile *tiler.Tile, tileId uint16) error {
-	word := sms.Word{TileNumber: tileId}
+func (p *Processor) addTileToTilemap(tile *tiler.Tile, tileId uint16, palette int) error {
+	word := sms.Word{TileNumber: tileId, PaletteSelect: palette == 1}
 
 	// the tile
 	word.SetFlippedStateFromOrientation(p.smsOrientation(tile.Orientation()))
-	if err := p.sega.AddTilemapEntryAt(tile.Row(), tile.Col(), word); err != nil {
+	word.Priority = p.priorityCells[cell{row: tile.Row(), col: tile.Col()}]
+	if err := p.setTilemapEntry(tile.Row(), tile.Col(), word); err != nil {
 		return err
 	}
 
@@ -208,13 +401,43 @@ func (p *Processor) addTileToTilemap(tile *tiler.Tile, tileId uint16) error {
 		}
 
 		word.SetFlippedStateFromOrientation(p.smsOrientation(inf.Orientation()))
-		if err := p.sega.AddTilemapEntryAt(inf.Row(), inf.Col(), word); err != nil {
+		word.Priority = p.priorityCells[cell{row: inf.Row(), col: inf.Col()}]
+		if err := p.setTilemapEntry(inf.Row(), inf.Col(), word); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// points every name table entry at the first converted tile, before the
+// image tiles are added, so the entries outside the image do not use tile 0,
+// which is not one of the converted tiles when using a tile offset. A
+// scrolling map is always fully covered by its image.
+func (p *Processor) fillTilemap(palette int) {
+	if p.levelMap != nil {
+		return
+	}
+	p.sega.FillTilemap(sms.Word{TileNumber: uint16(p.tileOffset), PaletteSelect: palette == 1})
+}
+
+// sets the tilemap entry, using the scrolling map when converting a map. The
+// screen entries are placed from the visible window of the target.
+func (p *Processor) setTilemapEntry(row, col int, word sms.Word) error {
+	if p.levelMap != nil {
+		return p.levelMap.Set(row, col, word)
+	}
+	originRow, originCol := p.screenOrigin()
+	return p.sega.AddTilemapEntryAt(originRow+row, originCol+col, word)
+}
+
+// returns the tilemap entry, using the scrolling map when converting a map.
+func (p *Processor) tilemapEntryAt(row, col int) (*sms.Word, error) {
+	if p.levelMap != nil {
+		return p.levelMap.Get(row, col)
+	}
+	return p.sega.TilemapEntryAt(row, col)
+}
+
 // converts a tiler orientation to an SMS orientation.
 func (p *Processor) smsOrientation(or tiler.Orientation) sms.Orientation {
 	switch or {
@@ -239,31 +462,18 @@ func (p *Processor) smsTilesToImage() (image.Image, error) {
 		Max: image.Point{X: width, Y: height},
 	})
 
-	errorMessage := "drawing tile to image"
 	rowOffset := 0
 	colOffset := 0
 
-	for i := uint16(0); i < sms.MaxTileCount; i++ {
+	for i := uint16(p.sega.TileOffset()); i < sms.MaxTileNumber; i++ {
 		tile, err := p.sega.TileAt(i)
 		if err != nil {
 			return nil, err
 		} else if tile == nil {
 			break
 		}
-
-		// draw the tile to the image
-		for y := 0; y < tileSize; y++ {
-			for x := 0; x < tileSize; x++ {
-				paletteId, err := tile.PaletteIdAt(y, x)
-				if err != nil {
-					return nil, fmt.Errorf("%s: %w", errorMessage, err)
-				}
-				colour, err := p.sega.PaletteColour(paletteId)
-				if err != nil {
-					return nil, fmt.Errorf("%s: %w", errorMessage, err)
-				}
-				img.Set(colOffset+x, rowOffset+y, colour)
-			}
+		if err := p.drawTile(img, tile, i, colOffset, rowOffset); err != nil {
+			return nil, err
 		}
 
 		// next column
@@ -279,9 +489,31 @@ func (p *Processor) smsTilesToImage() (image.Image, error) {
 	return img, nil
 }
 
+// draws the tile to the image at the pixel position, using the palette
+// selected for the tile
+func (p *Processor) drawTile(img *image.NRGBA, tile *sms.Tile, tileId uint16, pxOffsetX, pxOffsetY int) error {
+	errorMessage := "drawing tile to image"
+
+	for y := 0; y < tile.Size(); y++ {
+		for x := 0; x < tile.Size(); x++ {
+			paletteId, err := tile.PaletteIdAt(y, x)
+			if err != nil {
+				return fmt.Errorf("%s: %w", errorMessage, err)
+			}
+			colour, err := p.cram.ColourAt(gg.PaletteId(p.paletteIdForTile(tileId, paletteId)))
+			if err != nil {
+				return fmt.Errorf("%s: %w", errorMessage, err)
+			}
+			img.Set(pxOffsetX+x, pxOffsetY+y, colour)
+		}
+	}
+	return nil
+}
+
 func (p *Processor) tileSheetSizeInPixels(tileSize int) (int, int) {
-	height := sms.MaxTileCount / p.sega.WidthInTiles()
-	if sms.MaxTileCount%p.sega.WidthInTiles() > 0 {
+	tileCount := p.sega.TileLimit() - p.sega.TileOffset()
+	height := tileCount / p.sega.WidthInTiles()
+	if tileCount%p.sega.WidthInTiles() > 0 {
 		height += 1 // make up missing row
 	}
 	height *= tileSize // set height in pixels
@@ -291,27 +523,51 @@ func (p *Processor) tileSheetSizeInPixels(tileSize int) (int, int) {
 }
 
 // smsToImage converts the SMS data to a new NRGBA image, with the tile layout
-// as defined in the tilemap name table.
-func (p *Processor) smsToImage() (image.Image, error) {
+// as defined in the tilemap name table. When blankMissing is set, entries for
+// tiles that are not loaded, such as a font in decoded data, are left blank.
+func (p *Processor) smsToImage(blankMissing bool) (image.Image, error) {
+	rows, cols := p.sega.HeightInTiles(), p.sega.WidthInTiles()
+	if p.levelMap != nil {
+		rows, cols = p.levelMap.Height(), p.levelMap.Width()
+	}
+
 	img := image.NewNRGBA(image.Rectangle{
 		Min: image.Point{X: 0, Y: 0},
-		Max: image.Point{X: p.sega.WidthInPixels(), Y: p.sega.HeightInPixels()},
+		Max: image.Point{X: cols * 8, Y: rows * 8},
 	})
 
-	for row := 0; row < p.sega.HeightInTiles(); row++ {
-		for col := 0; col < p.sega.WidthInTiles(); col++ {
+	missing := 0
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if blankMissing && !p.tilemapEntryLoaded(row, col) {
+				missing++
+				continue
+			}
 			if err := p.drawTilemapEntry(img, row, col); err != nil {
 				return nil, err
 			}
 		}
 	}
+	if missing > 0 {
+		p.warn("%d tilemap entries use tiles that are not in the tile data, and are left blank", missing)
+	}
 
 	return img, nil
 }
 
+// returns true when the tile of the tilemap entry is loaded
+func (p *Processor) tilemapEntryLoaded(row, col int) bool {
+	mapEntry, err := p.tilemapEntryAt(row, col)
+	if err != nil {
+		return false
+	}
+	tile, err := p.sega.TileAt(mapEntry.TileNumber)
+	return err == nil && tile != nil
+}
+
 // draws a tile to the image using the tilemap entry data
 func (p *Processor) drawTilemapEntry(img *image.NRGBA, row, col int) error {
-	tile, err := p.smsTileForTilemapEntryAt(row, col)
+	tile, palette, err := p.smsTileForTilemapEntryAt(row, col)
 	if err != nil {
 		return err
 	}
@@ -326,7 +582,10 @@ func (p *Processor) drawTilemapEntry(img *image.NRGBA, row, col int) error {
 			if err != nil {
 				return fmt.Errorf("%s: %w", errorMessage, err)
 			}
-			colour, err := p.sega.PaletteColour(paletteId)
+			if palette == 1 {
+				paletteId += sms.PaletteColourCount
+			}
+			colour, err := p.cram.ColourAt(gg.PaletteId(paletteId))
 			if err != nil {
 				return fmt.Errorf("%s: %w", errorMessage, err)
 			}
@@ -336,21 +595,36 @@ func (p *Processor) drawTilemapEntry(img *image.NRGBA, row, col int) error {
 	return nil
 }
 
-// returns the mapped tile for the given row/col.
-func (p *Processor) smsTileForTilemapEntryAt(row, col int) (*sms.Tile, error) {
+// returns the mapped tile for the given row/col, along with its selected palette.
+func (p *Processor) smsTileForTilemapEntryAt(row, col int) (*sms.Tile, int, error) {
 	processingErrorMessage := "converting tilemap tile to correctly flipped tile"
 
-	mapEntry, err := p.sega.TilemapEntryAt(row, col)
+	mapEntry, err := p.tilemapEntryAt(row, col)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", processingErrorMessage, err)
+		return nil, 0, fmt.Errorf("%s: %w", processingErrorMessage, err)
 	}
 	tile, err := p.sega.TileAt(mapEntry.TileNumber)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", processingErrorMessage, err)
+		return nil, 0, fmt.Errorf("%s: %w", processingErrorMessage, err)
+	} else if tile == nil {
+		return nil, 0, fmt.Errorf("%s: no tile found for tile number %d at (%d,%d)", processingErrorMessage, mapEntry.TileNumber, row, col)
+	}
+
+	palette := 0
+	if mapEntry.PaletteSelect {
+		palette = 1
 	}
 
 	// set the correct orientation based on tilemap entry.
-	return tile.AsTilemap(mapEntry), nil
+	return tile.AsTilemap(mapEntry), palette, nil
+}
+
+// returns the CRAM palette ID for a tile pixel, using the palette selected for the tile.
+func (p *Processor) paletteIdForTile(tileId uint16, pid sms.PaletteId) sms.PaletteId {
+	if p.tilePalettes[tileId] == 1 {
+		return pid + sms.PaletteColourCount
+	}
+	return pid
 }
 
 func (p *Processor) saveImageToFilename(i image.Image, filename string) error {
@@ -0,0 +1,71 @@
+package processor_test
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"os"
+	"testing"
+)
+
+// returns the RGB colour of an SMS colour number, %00bbggrr
+func smsRGB(c uint8) color.RGBA {
+	return color.RGBA{R: c & 3 * 85, G: c >> 2 & 3 * 85, B: c >> 4 & 3 * 85, A: 0xFF}
+}
+
+// returns an image of 8x8 tiles in a row, each filled with its tile colours,
+// pixel by pixel, repeating the colours as needed
+func tileRow(tiles ...[]color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 8*len(tiles), 8))
+	for i, colours := range tiles {
+		for p := 0; p < 64; p++ {
+			img.Set(i*8+p%8, p/8, colours[p%len(colours)])
+		}
+	}
+	return img
+}
+
+// returns the SMS colours of the colour numbers
+func smsColours(numbers ...uint8) (colours []color.Color) {
+	for _, n := range numbers {
+		colours = append(colours, smsRGB(n))
+	}
+	return
+}
+
+func writePNG(t *testing.T, filename string, img image.Image) {
+	t.Helper()
+	writeImage(t, filename, img, png.Encode)
+}
+
+func writeImage(t *testing.T, filename string, img image.Image, encode func(w io.Writer, m image.Image) error) {
+	t.Helper()
+
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	defer f.Close()
+	if err := encode(f, img); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+}
+
+func writeFile(t *testing.T, filename string, data []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+}
+
+func readFile(t *testing.T, filename string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,249 @@
+package processor
+
+import (
+	"fmt"
+	"image/color"
+
+	"github.com/mrcook/smstilemap/gg"
+	"github.com/mrcook/smstilemap/sms"
+)
+
+const (
+	spriteWidth          = 8                      // SMS sprites are always 8 pixels wide
+	spritePaletteOffset  = sms.PaletteColourCount // sprites use the second palette, CRAM entries 16..31
+	spritePaletteColours = 15                     // palette index 0 is transparent, leaving 15 colours
+	maxSpriteTileCount   = 256                    // the SAT tile number is 8-bits wide
+)
+
+// spriteSheet holds the settings and converted frame data for a sprite sheet.
+type spriteSheet struct {
+	spriteHeight int // 8 or 16 pixels (8x16 sprite mode)
+	frameWidth   int // frame size in pixels, multiples of the sprite size
+	frameHeight  int
+
+	colours []gg.Colour // sprite palette colours, starting at palette index 1
+	frames  [][]uint8   // the sprite tile numbers making up each frame
+
+	durations  []int       // of each frame in milliseconds, from an Aseprite file
+	animations []animation // from the Aseprite tags
+}
+
+// FrameCols returns the number of sprites in each row of a frame.
+func (s *spriteSheet) FrameCols() int {
+	return s.frameWidth / spriteWidth
+}
+
+// PngToSprites converts a sprite sheet image to SMS sprite tiles and palette.
+//
+// The sheet is sliced into frames of the given size, which are then sliced
+// into 8x8 or 8x16 sprites. Colours are mapped to the sprite palette (CRAM
+// entries 16-31) with transparent pixels using palette index 0. Frame sizes
+// of zero default to the sprite size, or the canvas size of an Aseprite file.
+func (p *Processor) PngToSprites(spriteHeight, frameWidth, frameHeight int) error {
+	if p.priorityMask != nil {
+		return fmt.Errorf("a priority mask can not be used with sprites, as the priority bit is only in the tilemap")
+	}
+	if err := p.readImage(p.inputFilename, true); err != nil {
+		return fmt.Errorf("input image error: %w", err)
+	}
+	if err := p.imageToSprites(spriteHeight, frameWidth, frameHeight); err != nil {
+		return fmt.Errorf("PNG to SMS sprites error: %w", err)
+	}
+	return nil
+}
+
+// convert the sprite sheet image to SMS sprite tiles
+func (p *Processor) imageToSprites(spriteHeight, frameWidth, frameHeight int) error {
+	if spriteHeight != 8 && spriteHeight != 16 {
+		return fmt.Errorf("invalid sprite size, must be 8x8 or 8x16")
+	}
+	// the VDP ignores bit 0 of the tile number in 8x16 mode, drawing the
+	// even tile and the one after it, and the pattern bases are even too
+	if spriteHeight == 16 && p.tileOffset%2 != 0 {
+		return fmt.Errorf("8x16 sprite tiles must start at an even tile number, got offset %d", p.tileOffset)
+	}
+	if err := p.vram.Validate(); err != nil {
+		return err
+	}
+	if limit := p.spriteTileLimit(); p.tileOffset >= limit {
+		return fmt.Errorf("sprite tiles from tile %d overlap the name table or SAT at tile %d", p.tileOffset, limit)
+	} else if err := p.sega.SetTileLimit(limit); err != nil {
+		return err
+	}
+	if err := p.sega.SetTileOffset(p.tileOffset); err != nil {
+		return err
+	}
+	if p.aseprite != nil && frameWidth == 0 && frameHeight == 0 {
+		frameWidth, frameHeight = p.aseprite.Width, p.aseprite.Height // each Aseprite frame is a sprite frame
+	}
+	if frameWidth == 0 {
+		frameWidth = spriteWidth
+	}
+	if frameHeight == 0 {
+		frameHeight = spriteHeight
+	}
+	if frameWidth%spriteWidth != 0 || frameHeight%spriteHeight != 0 {
+		return fmt.Errorf("frame size (%dx%d) must be a multiple of the sprite size (%dx%d)", frameWidth, frameHeight, spriteWidth, spriteHeight)
+	}
+	// each Aseprite frame must hold whole sprite frames, for the animations
+	if p.aseprite != nil && (p.aseprite.Width%frameWidth != 0 || p.aseprite.Height%frameHeight != 0) {
+		return fmt.Errorf("the Aseprite canvas size (%dx%d) must be a multiple of the frame size (%dx%d)", p.aseprite.Width, p.aseprite.Height, frameWidth, frameHeight)
+	}
+
+	if p.image == nil {
+		return fmt.Errorf("source image is nil")
+	}
+	bounds := p.image.Bounds()
+	if bounds.Dx()%frameWidth != 0 || bounds.Dy()%frameHeight != 0 {
+		return fmt.Errorf("image size (%dx%d) must be a multiple of the frame size (%dx%d)", bounds.Dx(), bounds.Dy(), frameWidth, frameHeight)
+	}
+
+	p.sprites = &spriteSheet{
+		spriteHeight: spriteHeight,
+		frameWidth:   frameWidth,
+		frameHeight:  frameHeight,
+	}
+
+	// tile numbers for sprites already converted, keyed on their tile data
+	converted := make(map[string]uint8)
+
+	for frameY := bounds.Min.Y; frameY < bounds.Max.Y; frameY += frameHeight {
+		for frameX := bounds.Min.X; frameX < bounds.Max.X; frameX += frameWidth {
+			var frame []uint8
+
+			for y := frameY; y < frameY+frameHeight; y += spriteHeight {
+				for x := frameX; x < frameX+frameWidth; x += spriteWidth {
+					tileNumber, err := p.convertAndAddSprite(x, y, converted)
+					if err != nil {
+						return err
+					}
+					frame = append(frame, tileNumber)
+				}
+			}
+
+			p.sprites.frames = append(p.sprites.frames, frame)
+		}
+	}
+
+	if p.aseprite != nil {
+		if err := p.addSpriteAnimations(); err != nil {
+			return err
+		}
+	}
+	return p.addSpriteColoursToSmsPalette()
+}
+
+// converts the sprite at the pixel location, adding its tiles to the SMS,
+// and returning the tile number for the sprite. Sprites with identical tile
+// data are only added once.
+func (p *Processor) convertAndAddSprite(x, y int, converted map[string]uint8) (uint8, error) {
+	var tiles []*sms.Tile
+	var key []uint8
+
+	for tileY := y; tileY < y+p.sprites.spriteHeight; tileY += spriteWidth {
+		tile, err := p.convertToSmsSpriteTile(x, tileY)
+		if err != nil {
+			return 0, fmt.Errorf("error converting sprite at (%d,%d): %w", x, y, err)
+		}
+		tiles = append(tiles, tile)
+		key = append(key, tile.Bytes()...)
+	}
+
+	if tileNumber, found := converted[string(key)]; found {
+		return tileNumber, nil
+	}
+
+	var tileNumber uint16
+	for i, tile := range tiles {
+		tid, err := p.sega.AddTile(tile)
+		if err != nil {
+			return 0, fmt.Errorf("too many sprite tiles, they must fit below the name table and SAT at tile %d: %w", p.sega.TileLimit(), err)
+		}
+		p.tilePalettes[tid] = 1
+		p.sourceTiles = append(p.sourceTiles, sourceTile{number: tid, x: x, y: y + i*spriteWidth})
+		if i == 0 {
+			tileNumber = tid
+		}
+	}
+	// SAT tile numbers are relative to the sprite pattern base, $0000 or $2000
+	base := p.spritePatternBase()
+	if int(tileNumber)+len(tiles) > base+maxSpriteTileCount {
+		return 0, fmt.Errorf("too many sprite tiles (max: %d from tile %d)", base+maxSpriteTileCount-p.tileOffset, p.tileOffset)
+	}
+
+	converted[string(key)] = uint8(int(tileNumber) - base)
+	return uint8(int(tileNumber) - base), nil
+}
+
+// returns the first tile of the sprite pattern area containing the tile
+// offset: tile 0 ($0000) or tile 256 ($2000).
+func (p *Processor) spritePatternBase() int {
+	return p.tileOffset / maxSpriteTileCount * maxSpriteTileCount
+}
+
+// convert an 8x8 pixel area of the image to an SMS tile using the sprite palette
+func (p *Processor) convertToSmsSpriteTile(x, y int) (*sms.Tile, error) {
+	smsTile := sms.Tile{}
+
+	for row := 0; row < smsTile.Size(); row++ {
+		for col := 0; col < smsTile.Size(); col++ {
+			pid, err := p.spritePaletteIdFor(p.image.At(x+col, y+row))
+			if err != nil {
+				return nil, fmt.Errorf("pixel (%d, %d): %w", x+col, y+row, err)
+			}
+			if err := smsTile.SetPaletteIdAt(row, col, pid); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &smsTile, nil
+}
+
+// returns the sprite palette index for the colour, adding it to the sprite
+// colours when not yet present, or from the sprite half of the fixed palette
+// when given. Transparent pixels always use index 0.
+func (p *Processor) spritePaletteIdFor(c color.Color) (sms.PaletteId, error) {
+	if _, _, _, a := c.RGBA(); a == 0 {
+		return 0, nil
+	}
+	spriteColour := p.colourFor(c)
+
+	if p.fixedPalette != nil {
+		for i, colour := range p.fixedPaletteColours()[1] {
+			if i > 0 && colour.Equal(spriteColour) {
+				return sms.PaletteId(i), nil
+			}
+		}
+		return 0, fmt.Errorf("colour %s is not in the fixed sprite palette (CRAM entries 17-31)", spriteColour.HTML())
+	}
+
+	for i, colour := range p.sprites.colours {
+		if colour.Equal(spriteColour) {
+			return sms.PaletteId(i + 1), nil
+		}
+	}
+	if len(p.sprites.colours) >= spritePaletteColours {
+		return 0, fmt.Errorf("too many colours for the sprite palette (max: %d plus transparency)", spritePaletteColours)
+	}
+	p.sprites.colours = append(p.sprites.colours, spriteColour)
+
+	return sms.PaletteId(len(p.sprites.colours)), nil
+}
+
+// sets the sprite palette in CRAM entries 16..31, with the transparent
+// colour at entry 16 set to black. A fixed palette is used as given.
+func (p *Processor) addSpriteColoursToSmsPalette() error {
+	if p.fixedPalette != nil {
+		return p.addPartitionToSmsPalette(&sms.Partition[gg.Colour]{Palettes: p.fixedPaletteColours()})
+	}
+	if err := p.cram.SetColourAt(spritePaletteOffset, gg.Colour(0)); err != nil {
+		return err
+	}
+	for i, colour := range p.sprites.colours {
+		if err := p.cram.SetColourAt(gg.PaletteId(spritePaletteOffset+i+1), colour); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package processor_test
+
+import (
+	"image"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/mrcook/smstilemap/cmd/smstilemap/processor"
+)
+
+func TestProcessor_PngToSprites(t *testing.T) {
+	dir := t.TempDir()
+	filename := path.Join(dir, "sprites.png")
+
+	// two 8x16 sprites, each a tile of one colour over a tile of another
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i, colours := range [][]uint8{{0x03, 0x0C}, {0x30, 0x3F}} {
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 8; x++ {
+				img.Set(i*8+x, y, smsRGB(colours[y/8]))
+			}
+		}
+	}
+	writePNG(t, filename, img)
+
+	t.Run("8x16 sprites from an even offset", func(t *testing.T) {
+		pro := processor.New(filename, dir)
+		if err := pro.SetTileOffset(96); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if err := pro.PngToSprites(16, 8, 16); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if err := pro.ToAssembly(); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		asm := readFile(t, path.Join(dir, "sprites.asm"))
+		if !strings.Contains(asm, "; frame 000\n.db $60\n; frame 001\n.db $62\n") {
+			t.Errorf("expected the frames to use tiles $60 and $62, got:\n%s", asm)
+		}
+	})
+
+	t.Run("8x16 sprites from an odd offset", func(t *testing.T) {
+		pro := processor.New(filename, dir)
+		if err := pro.SetTileOffset(97); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		err := pro.PngToSprites(16, 8, 16)
+		if err == nil || !strings.Contains(err.Error(), "even tile number") {
+			t.Errorf("expected an odd tile offset error, got %v", err)
+		}
+	})
+
+	t.Run("8x8 sprites from an odd offset", func(t *testing.T) {
+		pro := processor.New(filename, dir)
+		if err := pro.SetTileOffset(97); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if err := pro.PngToSprites(8, 8, 16); err != nil {
+			t.Errorf("unexpected error: %q", err)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/mrcook/smstilemap/gg"
+	"github.com/mrcook/smstilemap/sms"
+)
+
+// The data can be converted for the Master System, or the Game Gear. The Game
+// Gear uses the same VDP layout, with two differences:
+//
+//   - CRAM holds 12-bit colours, two bytes per colour, giving a 64 byte
+//     palette of 4096 possible colours.
+//   - The LCD shows a 160x144 window of the 256x192 screen, starting at row 3,
+//     column 6 of the name table.
+//
+// The Game Gear colours include the 64 SMS colours, so the palette is held as
+// GG colours for both targets.
+
+const (
+	targetSMS = "sms"
+	targetGG  = "gg"
+)
+
+const (
+	ggScreenWidth  = 160 // visible Game Gear screen width in pixels
+	ggScreenHeight = 144 // visible Game Gear screen height in pixels
+	ggWindowRow    = 3   // name table row of the visible window
+	ggWindowCol    = 6   // name table column of the visible window
+)
+
+// SetTarget sets the console the data is converted for: sms, or gg.
+// The Game Gear only supports the 192-line screen height.
+func (p *Processor) SetTarget(target string) error {
+	switch target {
+	case targetSMS:
+	case targetGG:
+		if p.sega.HeightInPixels() != sms.ScreenHeight {
+			return fmt.Errorf("the Game Gear target only supports a screen height of %d", sms.ScreenHeight)
+		}
+	default:
+		return fmt.Errorf("unknown target '%s', must be one of: sms, gg", target)
+	}
+	p.target = target
+	p.matched = nil
+	return nil
+}
+
+func (p *Processor) gameGear() bool {
+	return p.target == targetGG
+}
+
+// returns the name of the target console, for the error messages
+func (p *Processor) consoleName() string {
+	if p.gameGear() {
+		return "Game Gear"
+	}
+	return "SMS"
+}
+
+// returns the visible screen size in pixels
+func (p *Processor) screenSize() (width, height int) {
+	if p.gameGear() {
+		return ggScreenWidth, ggScreenHeight
+	}
+	return p.sega.WidthInPixels(), p.sega.HeightInPixels()
+}
+
+// returns the name table position of the top left tile of the screen image
+func (p *Processor) screenOrigin() (row, col int) {
+	if p.gameGear() {
+		return ggWindowRow, ggWindowCol
+	}
+	return 0, 0
+}
+
+// returns the CRAM data for the target: a byte for each SMS colour, or a
+// little-endian word for each GG colour.
+func (p *Processor) paletteData() []uint8 {
+	if p.gameGear() {
+		data := p.cram.Bytes()
+		return data[:]
+	}
+	data := make([]uint8, 2*sms.PaletteColourCount)
+	for i := range data {
+		if colour, err := p.cram.ColourAt(gg.PaletteId(i)); err == nil {
+			data[i] = smsColour(colour).SMS()
+		}
+	}
+	return data
+}
+
+// returns the GG colour of an SMS colour, where each 2-bit channel value of
+// 0..3 is the 4-bit value 0, 5, 10, or 15.
+func ggColour(c sms.Colour) gg.Colour {
+	r, g, b := uint16(c.SMS()&3), uint16(c.SMS()>>2&3), uint16(c.SMS()>>4&3)
+	return gg.Colour(b*5<<8 | g*5<<4 | r*5)
+}
+
+// returns the SMS colour of a GG colour, or its nearest SMS colour when it is
+// not one of the 64 shared colours.
+func smsColour(c gg.Colour) sms.Colour {
+	r, g, b := c.RGB()
+	return sms.ColourDataForNearestRGB(r, g, b).Index
+}
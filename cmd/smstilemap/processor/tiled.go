@@ -0,0 +1,106 @@
+package processor
+
+import (
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path"
+
+	"github.com/mrcook/smstilemap/sms"
+	"github.com/mrcook/smstilemap/tiled"
+)
+
+const tilesetColumns = 32 // tiles in each row of the tileset image
+
+// ToTiled writes the unique tiles as a tileset image with its Tiled `.tsx`
+// tileset, along with a `.tmx` map of the tilemap, for editing in Tiled. Each
+// map cell uses the tile and flip flags of its name table entry, so the map
+// draws the same image as the SMS.
+func (p *Processor) ToTiled() error {
+	if p.sprites != nil {
+		return fmt.Errorf("no tilemap is generated for sprite sheets")
+	}
+	tileCount := len(p.sega.TileData()) / 32
+
+	img, err := p.tilesetImage(tileCount)
+	if err != nil {
+		return err
+	}
+	imageFilename := p.baseFilename + "-tileset.png"
+	if err := p.saveImageToFilename(img, path.Join(p.outputDirectory, imageFilename)); err != nil {
+		return fmt.Errorf("error writing tileset image: %w", err)
+	}
+
+	tileset := tiled.NewTileset(p.baseFilename, 8, tileCount, tilesetColumns, tiled.Image{
+		Source: imageFilename,
+		Width:  img.Bounds().Dx(),
+		Height: img.Bounds().Dy(),
+	})
+	if err := p.writeTiledFile(p.baseFilename+".tsx", tileset.Encode); err != nil {
+		return err
+	}
+
+	cols, rows := p.sega.WidthInTiles(), p.binaryTilemapRows()
+	if p.levelMap != nil {
+		cols, rows = p.levelMap.Width(), p.levelMap.Height()
+	}
+	var gids []uint32
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			word, err := p.tilemapEntryAt(row, col)
+			if err != nil {
+				return err
+			}
+			gids = append(gids, p.tiledGID(word, tileCount))
+		}
+	}
+	tmx, err := tiled.NewMap(cols, rows, 8, p.baseFilename+".tsx", "Tilemap", gids)
+	if err != nil {
+		return err
+	}
+	return p.writeTiledFile(p.baseFilename+".tmx", tmx.Encode)
+}
+
+// returns the Tiled GID for the tilemap entry, where the tileset starts at
+// the tile offset. Entries for tiles not in the tileset are left empty.
+func (p *Processor) tiledGID(word *sms.Word, tileCount int) uint32 {
+	tile := int(word.TileNumber) - p.tileOffset
+	if tile < 0 || tile >= tileCount {
+		return 0
+	}
+	return tiled.GID(1, tile, word.HorizontalFlip, word.VerticalFlip)
+}
+
+// draws the tiles, from the tile offset, in rows of the tileset columns
+func (p *Processor) tilesetImage(tileCount int) (*image.NRGBA, error) {
+	rows := max((tileCount+tilesetColumns-1)/tilesetColumns, 1)
+	img := image.NewNRGBA(image.Rect(0, 0, tilesetColumns*8, rows*8))
+
+	for i := 0; i < tileCount; i++ {
+		tileId := uint16(p.tileOffset + i)
+		tile, err := p.sega.TileAt(tileId)
+		if err != nil {
+			return nil, err
+		} else if tile == nil {
+			return nil, fmt.Errorf("no tile found for tile number %d", tileId)
+		}
+		if err := p.drawTile(img, tile, tileId, i%tilesetColumns*8, i/tilesetColumns*8); err != nil {
+			return nil, err
+		}
+	}
+	return img, nil
+}
+
+func (p *Processor) writeTiledFile(filename string, encode func(w io.Writer) error) error {
+	f, err := os.Create(path.Join(p.outputDirectory, filename))
+	if err != nil {
+		return fmt.Errorf("error creating Tiled file: %w", err)
+	}
+	defer f.Close()
+
+	if err := encode(f); err != nil {
+		return fmt.Errorf("error writing Tiled file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package processor
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path"
+
+	"github.com/mrcook/smstilemap/gg"
+	"github.com/mrcook/smstilemap/sms"
+	"github.com/mrcook/smstilemap/tiled"
+)
+
+// TmxToSMS builds the SMS screen from a Tiled `.tmx` map, instead of a PNG
+// image, keeping the tile numbers and flip flags of the map. See tmxToSMS.
+func (p *Processor) TmxToSMS() error {
+	if err := p.tmxToSMS(false); err != nil {
+		return fmt.Errorf("TMX to SMS data error: %w", err)
+	}
+	return nil
+}
+
+// TmxToMap builds a scrolling map, of any size, from a Tiled `.tmx` map.
+func (p *Processor) TmxToMap() error {
+	if err := p.tmxToSMS(true); err != nil {
+		return fmt.Errorf("TMX to SMS map error: %w", err)
+	}
+	return nil
+}
+
+// convert the map's first tile layer, and its tileset, to the SMS.
+//
+// Every tile of the tileset is added, in order from the tile offset, so the
+// tile numbers match those of the tileset, with each tile assigned to one of
+// the two palettes. The name table entries use the tiles and flip flags of
+// the map cells, where empty cells, and those outside a map smaller than the
+// screen, use the first tile.
+func (p *Processor) tmxToSMS(scrolling bool) error {
+	if p.priorityMask != nil {
+		return fmt.Errorf("a priority mask can not be used with a TMX map")
+	}
+	m, err := readTMX(p.inputFilename)
+	if err != nil {
+		return err
+	}
+	if m.TileWidth != 8 || m.TileHeight != 8 {
+		return fmt.Errorf("map tile size must be 8x8, got %dx%d", m.TileWidth, m.TileHeight)
+	} else if len(m.Tilesets) != 1 {
+		return fmt.Errorf("map must use a single tileset, got %d", len(m.Tilesets))
+	} else if len(m.Layers) == 0 {
+		return fmt.Errorf("map has no tile layers")
+	}
+
+	if scrolling {
+		p.levelMap = sms.NewMap(m.Width, m.Height)
+	} else if width, height := p.screenSize(); m.Width > width/8 || m.Height > height/8 {
+		return fmt.Errorf("map size too big for %s screen (%d x %d tiles)", p.consoleName(), width/8, height/8)
+	}
+
+	tileset, filename, err := readTMXTileset(p.inputFilename, m.Tilesets[0])
+	if err != nil {
+		return err
+	}
+	if p.image, err = decodeImage(filename); err != nil {
+		return fmt.Errorf("tileset image error: %w", err)
+	}
+	if err := p.useImagePalette(false); err != nil {
+		return err
+	}
+	tileCount, err := p.addTilesetToSms(tileset, filename)
+	if err != nil {
+		return err
+	}
+
+	gids, err := m.Layers[0].GIDs()
+	if err != nil {
+		return err
+	}
+	if tileCount > 0 {
+		p.fillTilemap(p.tilePalettes[p.tileOffset])
+	}
+	firstGID := m.Tilesets[0].FirstGID
+	for i, gid := range gids {
+		row, col := i/m.Width, i%m.Width
+		if gid&tiled.FlippedDiagonally != 0 {
+			return fmt.Errorf("tile at row %d, col %d: rotated tiles are not supported by the SMS", row, col)
+		}
+		id, horizontal, vertical := tiled.SplitGID(gid)
+		tile := 0
+		if id != 0 {
+			tile = int(id) - firstGID
+		}
+		if tile < 0 || tile >= tileCount {
+			return fmt.Errorf("tile at row %d, col %d: GID %d is not in the tileset", row, col, id)
+		}
+
+		tileId := uint16(p.tileOffset + tile)
+		word := sms.Word{
+			TileNumber:     tileId,
+			PaletteSelect:  p.tilePalettes[tileId] == 1,
+			HorizontalFlip: horizontal,
+			VerticalFlip:   vertical,
+		}
+		if err := p.setTilemapEntry(row, col, word); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// converts the tiles of the tileset image, and adds them to the SMS, returning
+// the number of tiles added.
+func (p *Processor) addTilesetToSms(tileset *tiled.Tileset, filename string) (int, error) {
+	if tileset.TileWidth != 8 || tileset.TileHeight != 8 {
+		return 0, fmt.Errorf("tileset tile size must be 8x8, got %dx%d", tileset.TileWidth, tileset.TileHeight)
+	}
+	if tileset.Margin < 0 || tileset.Spacing < 0 {
+		return 0, fmt.Errorf("invalid tileset margin %d, or spacing %d", tileset.Margin, tileset.Spacing)
+	}
+	// the tiles are separated by the spacing, inside the margin around the image
+	stride := 8 + tileset.Spacing
+	columns := (p.image.Bounds().Dx() - 2*tileset.Margin + tileset.Spacing) / stride
+	rows := (p.image.Bounds().Dy() - 2*tileset.Margin + tileset.Spacing) / stride
+	if tileset.Columns > columns {
+		return 0, fmt.Errorf("tileset has %d columns, but the %dx%d image only holds %d", tileset.Columns, p.image.Bounds().Dx(), p.image.Bounds().Dy(), max(columns, 0))
+	} else if tileset.Columns > 0 {
+		columns = tileset.Columns
+	}
+	tileCount := max(columns*rows, 0)
+	if tileset.TileCount > 0 {
+		tileCount = min(tileset.TileCount, tileCount)
+	}
+
+	if tileCount > sms.MaxTileCount {
+		return 0, fmt.Errorf("too many tiles in the tileset for SMS (max: %d), got %d", sms.MaxTileCount, tileCount)
+	}
+	if p.tileOffset+tileCount > sms.MaxTileCount {
+		return 0, fmt.Errorf("tile offset %d plus %d tileset tiles is more than the SMS supports (max: %d)", p.tileOffset, tileCount, sms.MaxTileCount)
+	}
+	if err := p.validateVRAMLayout(tileCount); err != nil {
+		return 0, err
+	}
+	if err := p.sega.SetTileOffset(p.tileOffset); err != nil {
+		return 0, err
+	}
+
+	// the pixel position of each tile in the tileset image
+	positions := make([]image.Point, tileCount)
+	colours := make([][]gg.Colour, tileCount)
+	for i := range positions {
+		positions[i] = image.Pt(
+			p.image.Bounds().Min.X+tileset.Margin+i%columns*stride,
+			p.image.Bounds().Min.Y+tileset.Margin+i/columns*stride,
+		)
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				colours[i] = append(colours[i], p.colourFor(p.image.At(positions[i].X+x, positions[i].Y+y)))
+			}
+		}
+	}
+
+	if err := p.validateFixedPalette(p.image); err != nil {
+		return 0, fmt.Errorf("tileset image %s: %w", filename, err)
+	}
+	opts := sms.PartitionOptions[gg.Colour]{}
+	p.applyFixedPalette(&opts)
+	partition, err := sms.PartitionColoursWith(colours, opts)
+	problems, err := tileColourProblems(colours, partition, err)
+	if err != nil {
+		return 0, err
+	}
+	colourErr := &TileColourError{}
+	for i, cell := range problems {
+		cell.Row, cell.Col = i/columns, i%columns
+		colourErr.Cells = append(colourErr.Cells, cell)
+	}
+	if err := colourErr.orNil(); err != nil {
+		return 0, fmt.Errorf("tileset image %s: %w", filename, err)
+	}
+	if err := p.addPartitionToSmsPalette(partition); err != nil {
+		return 0, fmt.Errorf("error adding colours to SMS palette: %w", err)
+	}
+
+	for i, pos := range positions {
+		smsTile := sms.Tile{}
+		for y := 0; y < 8; y++ {
+			for x := 0; x < 8; x++ {
+				pid, err := partition.PaletteIdFor(i, colours[i][y*8+x])
+				if err != nil {
+					return 0, err
+				}
+				if err := smsTile.SetPaletteIdAt(y, x, pid); err != nil {
+					return 0, err
+				}
+			}
+		}
+		tid, err := p.sega.AddTile(&smsTile)
+		if err != nil {
+			return 0, err
+		}
+		p.tilePalettes[tid] = partition.Selected[i]
+		p.sourceTiles = append(p.sourceTiles, sourceTile{number: tid, x: pos.X, y: pos.Y})
+	}
+	return tileCount, nil
+}
+
+func readTMX(filename string) (*tiled.Map, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return tiled.DecodeMap(f)
+}
+
+// returns the map's tileset, reading it from its TSX file when not embedded,
+// along with the path of the tileset image.
+func readTMXTileset(mapFilename string, mapTileset tiled.MapTileset) (*tiled.Tileset, string, error) {
+	if len(mapTileset.Source) == 0 {
+		tileset := mapTileset.Tileset()
+		return tileset, path.Join(path.Dir(mapFilename), tileset.Image.Source), nil
+	}
+
+	filename := path.Join(path.Dir(mapFilename), mapTileset.Source)
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	tileset, err := tiled.DecodeTileset(f)
+	if err != nil {
+		return nil, "", err
+	}
+	return tileset, path.Join(path.Dir(filename), tileset.Image.Source), nil
+}
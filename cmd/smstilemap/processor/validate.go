@@ -0,0 +1,198 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mrcook/smstilemap/cmd/smstilemap/processor/internal/tiler"
+	"github.com/mrcook/smstilemap/gg"
+	"github.com/mrcook/smstilemap/sms"
+)
+
+// TileColourError is returned when one or more 8x8 tile cells of the image
+// can not be displayed using the two 16 colour SMS palettes.
+type TileColourError struct {
+	Cells []CellColourError // sorted by row, then column
+}
+
+func (e *TileColourError) Error() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d tile(s) can not be displayed using the SMS palettes:", len(e.Cells)))
+	for _, cell := range e.Cells {
+		sb.WriteString("\n  ")
+		sb.WriteString(cell.String())
+	}
+	return sb.String()
+}
+
+// CellProblem identifies the type of colour problem found in a tile cell.
+type CellProblem int
+
+const (
+	TooManyColours           CellProblem = iota // the tile uses more than 16 colours
+	NoMatchingPalette                           // the tile colours are not all in either palette
+	PriorityBackgroundColour                    // a priority tile background uses more than one colour
+	PriorityForegroundColour                    // a priority tile foreground uses the palette index 0 colour
+	MixedPalettes                               // the tile colours are in the palettes, but not all in the same one
+)
+
+// CellColourError describes the colour problem of a single tile cell.
+type CellColourError struct {
+	Row, Col    int         // tile location in the image
+	ColourCount int         // number of unique colours used by the tile
+	Colours     []gg.Colour // the offending colours
+	Problem     CellProblem
+}
+
+func (e CellColourError) String() string {
+	var colours []string
+	for _, c := range e.Colours {
+		colours = append(colours, c.HTML())
+	}
+	list := strings.Join(colours, ", ")
+
+	switch e.Problem {
+	case TooManyColours:
+		return fmt.Sprintf("tile at row %d, col %d: uses %d colours, max is %d: %s", e.Row, e.Col, e.ColourCount, sms.PaletteColourCount, list)
+	case PriorityBackgroundColour:
+		return fmt.Sprintf("tile at row %d, col %d: priority tile background must use a single colour (palette index 0), got: %s", e.Row, e.Col, list)
+	case PriorityForegroundColour:
+		return fmt.Sprintf("tile at row %d, col %d: priority tile foreground uses the palette index 0 colour, which is drawn behind sprites: %s", e.Row, e.Col, list)
+	case MixedPalettes:
+		return fmt.Sprintf("tile at row %d, col %d: uses colours from both palettes: %s", e.Row, e.Col, list)
+	default:
+		return fmt.Sprintf("tile at row %d, col %d: colours missing from both palettes: %s", e.Row, e.Col, list)
+	}
+}
+
+// validateTileColours checks every tile of the image can be displayed using
+// one of the two palettes. The colours are those used by each unique tile,
+// and partitionErr the result from partitioning them.
+// Every cell of the image using an invalid tile is reported.
+func validateTileColours(tiled *tiler.Tiled, colours [][]gg.Colour, partition *sms.Partition[gg.Colour], partitionErr error) error {
+	problems, err := tileColourProblems(colours, partition, partitionErr)
+	if err != nil {
+		return err
+	}
+
+	colourErr := &TileColourError{}
+
+	for i, cell := range problems {
+		tile, _ := tiled.GetTile(i)
+		cell.Row, cell.Col = tile.Row(), tile.Col()
+		colourErr.Cells = append(colourErr.Cells, cell)
+		for did := 0; did < tile.DuplicateCount(); did++ {
+			inf, err := tile.GetDuplicateInfo(did)
+			if err != nil {
+				return err
+			}
+			cell.Row, cell.Col = inf.Row(), inf.Col()
+			colourErr.Cells = append(colourErr.Cells, cell)
+		}
+	}
+
+	return colourErr.orNil()
+}
+
+// returns the colour problem of each tile that can not be displayed using
+// either palette, keyed on the tile index, without the cell location.
+func tileColourProblems(colours [][]gg.Colour, partition *sms.Partition[gg.Colour], partitionErr error) (map[int]CellColourError, error) {
+	var unplaced []int
+	var perr *sms.PartitionError
+	if errors.As(partitionErr, &perr) {
+		unplaced = perr.Tiles
+	} else if partitionErr != nil {
+		return nil, partitionErr
+	}
+
+	problems := make(map[int]CellColourError)
+
+	for i := range colours {
+		unique := uniqueSmsColours(colours[i])
+
+		cell := CellColourError{ColourCount: len(unique)}
+		if len(unique) > sms.PaletteColourCount {
+			cell.Problem = TooManyColours
+			cell.Colours = unique
+		} else if containsInt(unplaced, i) {
+			cell.Problem = NoMatchingPalette
+			if inPalettes(partition, unique) {
+				cell.Problem = MixedPalettes
+			}
+			cell.Colours = closestPaletteMissingColours(partition, unique)
+		} else {
+			continue
+		}
+		problems[i] = cell
+	}
+	return problems, nil
+}
+
+// returns the error with its cells sorted, or nil when no cells were reported.
+func (e *TileColourError) orNil() error {
+	if len(e.Cells) == 0 {
+		return nil
+	}
+	sort.Slice(e.Cells, func(a, b int) bool {
+		if e.Cells[a].Row != e.Cells[b].Row {
+			return e.Cells[a].Row < e.Cells[b].Row
+		}
+		return e.Cells[a].Col < e.Cells[b].Col
+	})
+	return e
+}
+
+// returns the tile colours missing from the palette which is the closest match.
+func closestPaletteMissingColours(partition *sms.Partition[gg.Colour], colours []gg.Colour) (missing []gg.Colour) {
+	for pal, palette := range partition.Palettes {
+		var m []gg.Colour
+		for _, c := range colours {
+			if !containsColour(palette, c) {
+				m = append(m, c)
+			}
+		}
+		if pal == 0 || len(m) < len(missing) {
+			missing = m
+		}
+	}
+	return
+}
+
+// returns true when each of the colours is in one of the palettes
+func inPalettes(partition *sms.Partition[gg.Colour], colours []gg.Colour) bool {
+	for _, c := range colours {
+		if !containsColour(partition.Palettes[0], c) && !containsColour(partition.Palettes[1], c) {
+			return false
+		}
+	}
+	return true
+}
+
+func uniqueSmsColours(colours []gg.Colour) (unique []gg.Colour) {
+	for _, c := range colours {
+		if !containsColour(unique, c) {
+			unique = append(unique, c)
+		}
+	}
+	return
+}
+
+func containsColour(colours []gg.Colour, colour gg.Colour) bool {
+	for _, c := range colours {
+		if c.Equal(colour) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
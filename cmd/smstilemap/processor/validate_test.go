@@ -0,0 +1,68 @@
+package processor_test
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/mrcook/smstilemap/cmd/smstilemap/processor"
+)
+
+func TestProcessor_TileColourError(t *testing.T) {
+	var first, second, tooMany []uint8
+	for c := uint8(0); c < 16; c++ {
+		first = append(first, c)
+		second = append(second, 16+c)
+	}
+	tooMany = append(append(tooMany, first...), 32)
+
+	// the first two tiles fill both palettes, so the third tile, which uses
+	// a colour from each, can not be displayed, nor the fourth with 17 colours
+	dir := t.TempDir()
+	filename := path.Join(dir, "colours.png")
+	writePNG(t, filename, tileRow(smsColours(first...), smsColours(second...), smsColours(1, 17), smsColours(tooMany...)))
+
+	err := processor.New(filename, dir).PngToSMS()
+	var colourErr *processor.TileColourError
+	if !errors.As(err, &colourErr) {
+		t.Fatalf("expected a TileColourError, got %v", err)
+	}
+	if len(colourErr.Cells) != 2 {
+		t.Fatalf("expected 2 cells, got %d: %s", len(colourErr.Cells), err)
+	}
+
+	t.Run("with colours from both palettes", func(t *testing.T) {
+		cell := colourErr.Cells[0]
+		if cell.Row != 0 || cell.Col != 2 || cell.Problem != processor.MixedPalettes || cell.ColourCount != 2 {
+			t.Errorf("unexpected cell: %+v", cell)
+		}
+		// colour 17 is missing from the palette holding colour 1, or colour 1 from the other
+		if got := htmlColours(cell); !reflect.DeepEqual(got, []string{"#550055"}) && !reflect.DeepEqual(got, []string{"#550000"}) {
+			t.Errorf("expected the colour missing from the closest palette, got %v", got)
+		}
+	})
+
+	t.Run("with too many colours", func(t *testing.T) {
+		cell := colourErr.Cells[1]
+		if cell.Row != 0 || cell.Col != 3 || cell.Problem != processor.TooManyColours || cell.ColourCount != 17 {
+			t.Errorf("unexpected cell: %+v", cell)
+		}
+		var want []string
+		for _, c := range tooMany {
+			rgb := smsRGB(c)
+			want = append(want, fmt.Sprintf("#%02X%02X%02X", rgb.R, rgb.G, rgb.B))
+		}
+		if got := htmlColours(cell); !reflect.DeepEqual(got, want) {
+			t.Errorf("expected colours %v, got %v", want, got)
+		}
+	})
+}
+
+func htmlColours(cell processor.CellColourError) (colours []string) {
+	for _, c := range cell.Colours {
+		colours = append(colours, c.HTML())
+	}
+	return
+}
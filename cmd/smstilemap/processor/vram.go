@@ -0,0 +1,104 @@
+package processor
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/mrcook/smstilemap/assembly"
+	"github.com/mrcook/smstilemap/sms"
+)
+
+// The VRAM layout describes where the background tiles, name table, and SAT
+// live in VRAM. It starts as the typical layout for the screen mode, and can
+// be changed to move the tables, or reserve blocks of tiles (a font, the
+// sprite tiles, etc.) that the background tiles must not use. The layout is
+// written to the assembly output as a set of `.define` constants.
+
+var labelPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ReserveTiles reserves a block of tiles, which the background must not use.
+// The name is used as the assembly label for the block.
+func (p *Processor) ReserveTiles(name string, first, count int) error {
+	if !labelPattern.MatchString(name) {
+		return fmt.Errorf("invalid reserved tiles name '%s', must be a valid assembly label", name)
+	}
+	return p.vram.ReserveTiles(name, first, count)
+}
+
+// SetNameTableAddress sets the VRAM address of the name table (tilemap).
+func (p *Processor) SetNameTableAddress(address int) {
+	p.vram.NameTable.Start = address
+}
+
+// SetSATAddress sets the VRAM address of the sprite attribute table.
+func (p *Processor) SetSATAddress(address int) {
+	p.vram.SAT.Start = address
+}
+
+// BackgroundTileCount returns the number of tiles the VRAM layout leaves
+// available for the background.
+func (p *Processor) BackgroundTileCount() int {
+	return p.vram.BackgroundTileCount()
+}
+
+// checks the VRAM layout is valid and has space for the unique tiles,
+// starting at the tile offset
+func (p *Processor) validateVRAMLayout(tileCount int) error {
+	if err := p.vram.Validate(); err != nil {
+		return err
+	}
+	available := p.vram.BackgroundTiles()
+	if tileCount > len(available) {
+		return fmt.Errorf("too many unique tiles for the VRAM layout (available: %d), got %d", len(available), tileCount)
+	}
+
+	free := make(map[int]bool, len(available))
+	for _, id := range available {
+		free[id] = true
+	}
+	for id := p.tileOffset; id < p.tileOffset+tileCount; id++ {
+		if !free[id] {
+			return fmt.Errorf("tiles %d..%d do not fit the VRAM layout, tile %d is not available for the background", p.tileOffset, p.tileOffset+tileCount-1, id)
+		}
+	}
+	return nil
+}
+
+// returns the tile number that sprite tiles are added below: the first tile
+// used by a name table or SAT located after the tile offset, so the tiles
+// never overwrite either table.
+func (p *Processor) spriteTileLimit() int {
+	limit := sms.MaxTileNumber
+	for _, table := range []sms.VRAMRange{p.vram.NameTable, p.vram.SAT} {
+		if table.End() > p.tileOffset*sms.TileByteSize {
+			limit = min(limit, table.Start/sms.TileByteSize)
+		}
+	}
+	return limit
+}
+
+// returns the VRAM layout constants for the assembly output
+func (p *Processor) vramDefines() []assembly.Define {
+	defines := []assembly.Define{
+		{Name: "NameTableAddress", Value: p.vram.NameTable.Start, Hex: true},
+		{Name: "SATAddress", Value: p.vram.SAT.Start, Hex: true},
+	}
+	for _, r := range p.vram.Reserved {
+		first := r.Start / sms.TileByteSize
+		last := r.End()/sms.TileByteSize - 1
+		defines = append(defines,
+			assembly.Define{Name: r.Name + "Address", Value: r.Start, Hex: true, Comment: fmt.Sprintf("reserved tiles %d..%d", first, last)},
+			assembly.Define{Name: r.Name + "FirstTile", Value: first},
+		)
+	}
+	defines = append(defines, assembly.Define{
+		Name:    "TileOffset",
+		Value:   p.tileOffset,
+		Comment: "tile number of the first tile in TileData",
+	}, assembly.Define{
+		Name:    "BackgroundTileCount",
+		Value:   p.vram.BackgroundTileCount(),
+		Comment: "tiles available for the background",
+	})
+	return defines
+}
@@ -0,0 +1,185 @@
+package compress
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Helpers shared by the ZX0 and ZX7 formats, which are both LZ77 formats
+// mixing a stream of bits (flags and Elias gamma coded values) with whole
+// bytes (literals and offsets) in the one buffer. A byte for the next 8 bits
+// is reserved in the output when the previous one is full, so the decoder
+// reads them in the same order as they were written.
+
+// lzChainLimit is the number of earlier positions checked for each match.
+const lzChainLimit = 256
+
+// lzMatch is a match for the data at a position, copying the length bytes
+// from offset bytes back.
+type lzMatch struct {
+	offset int
+	length int
+}
+
+// lzMatcher finds matches for each position of the data, using chains of the
+// earlier positions starting with the same two bytes.
+type lzMatcher struct {
+	data      []uint8
+	prev      []int
+	maxOffset int
+	maxLength int
+}
+
+func newLZMatcher(data []uint8, maxOffset, maxLength int) *lzMatcher {
+	m := &lzMatcher{data: data, prev: make([]int, len(data)), maxOffset: maxOffset, maxLength: maxLength}
+
+	last := make(map[uint16]int)
+	for pos := range data {
+		m.prev[pos] = -1
+		if pos+1 < len(data) {
+			key := uint16(data[pos])<<8 | uint16(data[pos+1])
+			if p, ok := last[key]; ok {
+				m.prev[pos] = p
+			}
+			last[key] = pos
+		}
+	}
+	return m
+}
+
+// matches returns the matches of at least two bytes for the position, nearest
+// first, with each match longer than the previous one.
+func (m *lzMatcher) matches(pos int) []lzMatch {
+	var matches []lzMatch
+	longest := 1
+	limit := min(len(m.data)-pos, m.maxLength)
+
+	for p, count := m.prev[pos], 0; p >= 0 && pos-p <= m.maxOffset && count < lzChainLimit; p, count = m.prev[p], count+1 {
+		length := m.length(pos, pos-p, limit)
+		if length > longest {
+			matches = append(matches, lzMatch{offset: pos - p, length: length})
+			longest = length
+		}
+		if length == limit {
+			break
+		}
+	}
+	return matches
+}
+
+// length returns the number of bytes at the position matching those at the
+// offset, up to the limit.
+func (m *lzMatcher) length(pos, offset, limit int) int {
+	length := 0
+	for length < limit && m.data[pos+length] == m.data[pos+length-offset] {
+		length++
+	}
+	return length
+}
+
+// lzLengths returns the copy lengths to try for a match: each length from the
+// minimum, up to a limit, and the full length of the match.
+func lzLengths(from, to int) []int {
+	const limit = 256
+
+	var lengths []int
+	for length := from; length <= to && length < from+limit; length++ {
+		lengths = append(lengths, length)
+	}
+	if to >= from+limit {
+		lengths = append(lengths, to)
+	}
+	return lengths
+}
+
+// eliasGammaSize returns the number of bits used for the Elias gamma code of
+// the value, in either the ZX7 or the interlaced ZX0 form.
+func eliasGammaSize(value int) int {
+	return bits.Len(uint(value))*2 - 1
+}
+
+// bitWriter writes the mixed stream of bits and bytes.
+type bitWriter struct {
+	out       []uint8
+	mask      uint8
+	index     int  // index of the byte holding the current bits
+	backtrack bool // the next bit is stored in bit 0 of the last byte (ZX0)
+}
+
+func (w *bitWriter) writeByte(value uint8) {
+	w.out = append(w.out, value)
+}
+
+func (w *bitWriter) writeBit(bit int) {
+	if w.backtrack {
+		w.out[len(w.out)-1] |= uint8(bit)
+		w.backtrack = false
+		return
+	}
+	if w.mask == 0 {
+		w.mask = 0x80
+		w.index = len(w.out)
+		w.out = append(w.out, 0)
+	}
+	if bit != 0 {
+		w.out[w.index] |= w.mask
+	}
+	w.mask >>= 1
+}
+
+// writes the bits of the value, from the most significant bit
+func (w *bitWriter) writeBits(value, count int) {
+	for i := count - 1; i >= 0; i-- {
+		w.writeBit(value >> i & 1)
+	}
+}
+
+// bitReader reads the mixed stream of bits and bytes.
+type bitReader struct {
+	format    string
+	data      []uint8
+	pos       int
+	mask      uint8
+	value     uint8
+	backtrack bool // the next bit is bit 0 of the last byte read (ZX0)
+}
+
+func (r *bitReader) readByte() (uint8, error) {
+	if r.pos >= len(r.data) {
+		return 0, fmt.Errorf("%s: unexpected end of data at offset %d", r.format, r.pos)
+	}
+	r.pos++
+	return r.data[r.pos-1], nil
+}
+
+func (r *bitReader) readBit() (int, error) {
+	if r.backtrack {
+		r.backtrack = false
+		return int(r.data[r.pos-1] & 1), nil
+	}
+	r.mask >>= 1
+	if r.mask == 0 {
+		value, err := r.readByte()
+		if err != nil {
+			return 0, err
+		}
+		r.mask = 0x80
+		r.value = value
+	}
+	if r.value&r.mask != 0 {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// lzCopy appends length bytes to the output, copied from offset bytes back,
+// where the copy may overlap the bytes being written.
+func lzCopy(format string, out []uint8, offset, length int) ([]uint8, error) {
+	if offset < 1 || offset > len(out) {
+		return nil, fmt.Errorf("%s: invalid offset %d at output position %d", format, offset, len(out))
+	}
+	for i := 0; i < length; i++ {
+		out = append(out, out[len(out)-offset])
+	}
+	return out, nil
+}
@@ -0,0 +1,238 @@
+// Package compress implements compressors, and matching decompressors, for
+// reducing the ROM space used by the SMS data.
+package compress
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// The PSGaiden format compresses SMS tile data, one bitplane at a time, and is
+// named after the Phantasy Star Gaiden game it was first used in.
+//
+// Data format:
+//
+// The data starts with the number of tiles as a 16-bit little-endian value.
+// Each tile is then split into its 4 bitplanes, of 8 bytes each (one for each
+// row of pixels), which are stored with a method byte followed by the data for
+// each bitplane. The method byte holds a 2-bit method for each bitplane, with
+// bitplane 0 in bits 7-6, through to bitplane 3 in bits 1-0:
+//
+//   %00 - all bytes are $00, no data follows
+//   %01 - all bytes are $FF, no data follows
+//   %10 - raw, the 8 bytes follow
+//   %11 - compressed, followed by a byte:
+//         $00-$03: a copy of that (earlier) bitplane of the tile
+//         $10-$13: an inverted copy of bitplane (n & 3)
+//         otherwise: a bitmask of the bytes using a common value (bit 7 for
+//         row 0), followed by the common value, then the other bytes in order
+//
+// On decompression, the bitplanes are interleaved back into the 32 bytes of
+// planar tile data.
+
+const (
+	psgBitplanes     = 4
+	psgBitplaneSize  = 8
+	psgTileSize      = psgBitplanes * psgBitplaneSize
+	psgMethodZero    = 0b00
+	psgMethodOnes    = 0b01
+	psgMethodRaw     = 0b10
+	psgMethodPacked  = 0b11
+	psgCopyInverted  = 0x10
+	psgMaxTileNumber = 0xFFFF
+)
+
+// PSGaidenCompress compresses planar tile data, 32 bytes per tile, using the
+// PSGaiden format.
+func PSGaidenCompress(data []uint8) ([]uint8, error) {
+	if len(data)%psgTileSize != 0 {
+		return nil, fmt.Errorf("psgaiden: tile data must be a multiple of %d bytes, got %d", psgTileSize, len(data))
+	}
+	tileCount := len(data) / psgTileSize
+	if tileCount > psgMaxTileNumber {
+		return nil, fmt.Errorf("psgaiden: too many tiles, got %d", tileCount)
+	}
+
+	out := binary.LittleEndian.AppendUint16(nil, uint16(tileCount))
+	for i := 0; i < len(data); i += psgTileSize {
+		planes := psgDeinterleave(data[i : i+psgTileSize])
+
+		var method uint8
+		var encoded []uint8
+		for p, plane := range planes {
+			m, bytes := psgEncodeBitplane(plane, planes[:p])
+			method |= m << (6 - p*2)
+			encoded = append(encoded, bytes...)
+		}
+		out = append(out, method)
+		out = append(out, encoded...)
+	}
+	return out, nil
+}
+
+// PSGaidenDecompress decompresses PSGaiden data back to planar tile data.
+func PSGaidenDecompress(data []uint8) ([]uint8, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("psgaiden: missing tile count header")
+	}
+	tileCount := int(binary.LittleEndian.Uint16(data))
+	pos := 2
+
+	// returns the next n bytes of the input
+	next := func(n int) ([]uint8, error) {
+		if pos+n > len(data) {
+			return nil, fmt.Errorf("psgaiden: unexpected end of data at offset %d", pos)
+		}
+		b := data[pos : pos+n]
+		pos += n
+		return b, nil
+	}
+
+	out := make([]uint8, 0, tileCount*psgTileSize)
+	for tile := 0; tile < tileCount; tile++ {
+		b, err := next(1)
+		if err != nil {
+			return nil, err
+		}
+		method := b[0]
+
+		var planes [psgBitplanes][psgBitplaneSize]uint8
+		for p := 0; p < psgBitplanes; p++ {
+			switch (method >> (6 - p*2)) & 0b11 {
+			case psgMethodZero:
+			case psgMethodOnes:
+				for row := range planes[p] {
+					planes[p][row] = 0xFF
+				}
+			case psgMethodRaw:
+				raw, err := next(psgBitplaneSize)
+				if err != nil {
+					return nil, err
+				}
+				copy(planes[p][:], raw)
+			case psgMethodPacked:
+				b, err := next(1)
+				if err != nil {
+					return nil, err
+				}
+				if err := psgDecodePacked(b[0], p, &planes, next); err != nil {
+					return nil, fmt.Errorf("psgaiden: tile %d: %w", tile, err)
+				}
+			}
+		}
+		out = append(out, psgInterleave(planes)...)
+	}
+	return out, nil
+}
+
+// decodes a compressed bitplane, given its first byte
+func psgDecodePacked(value uint8, p int, planes *[psgBitplanes][psgBitplaneSize]uint8, next func(int) ([]uint8, error)) error {
+	switch {
+	case value <= 0x03, value >= psgCopyInverted && value <= psgCopyInverted+0x03:
+		src := int(value & 0x03)
+		if src >= p {
+			return fmt.Errorf("bitplane %d copies bitplane %d, which has not been decoded", p, src)
+		}
+		for row := range planes[p] {
+			planes[p][row] = planes[src][row]
+			if value >= psgCopyInverted {
+				planes[p][row] ^= 0xFF
+			}
+		}
+	default:
+		common, err := next(1)
+		if err != nil {
+			return err
+		}
+		for row := range planes[p] {
+			if value&(0x80>>row) != 0 {
+				planes[p][row] = common[0]
+			} else {
+				b, err := next(1)
+				if err != nil {
+					return err
+				}
+				planes[p][row] = b[0]
+			}
+		}
+	}
+	return nil
+}
+
+// returns the encoding method and data for the bitplane, using the smallest
+// encoding available
+func psgEncodeBitplane(plane [psgBitplaneSize]uint8, previous [][psgBitplaneSize]uint8) (uint8, []uint8) {
+	if plane == [psgBitplaneSize]uint8{} {
+		return psgMethodZero, nil
+	}
+	if plane == [psgBitplaneSize]uint8{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF} {
+		return psgMethodOnes, nil
+	}
+	for i, prev := range previous {
+		if plane == prev {
+			return psgMethodPacked, []uint8{uint8(i)}
+		}
+	}
+	for i, prev := range previous {
+		inverted := true
+		for row := range plane {
+			if plane[row] != prev[row]^0xFF {
+				inverted = false
+				break
+			}
+		}
+		if inverted {
+			return psgMethodPacked, []uint8{psgCopyInverted | uint8(i)}
+		}
+	}
+
+	// find the most common byte value to use with a bitmask
+	var common uint8
+	var commonCount int
+	for _, value := range plane {
+		count := 0
+		for _, b := range plane {
+			if b == value {
+				count++
+			}
+		}
+		if count > commonCount {
+			common, commonCount = value, count
+		}
+	}
+	var mask uint8
+	var others []uint8
+	for row, b := range plane {
+		if b == common {
+			mask |= 0x80 >> row
+		} else {
+			others = append(others, b)
+		}
+	}
+	// the bitmask must not clash with the copy values, and be smaller than raw
+	clash := mask <= 0x03 || (mask >= psgCopyInverted && mask <= psgCopyInverted+0x03)
+	if !clash && 2+len(others) < psgBitplaneSize {
+		return psgMethodPacked, append([]uint8{mask, common}, others...)
+	}
+
+	return psgMethodRaw, plane[:]
+}
+
+// splits the 32 bytes of planar tile data into its 4 bitplanes
+func psgDeinterleave(tile []uint8) (planes [psgBitplanes][psgBitplaneSize]uint8) {
+	for i, b := range tile {
+		planes[i%psgBitplanes][i/psgBitplanes] = b
+	}
+	return
+}
+
+// joins the 4 bitplanes back into the 32 bytes of planar tile data
+func psgInterleave(planes [psgBitplanes][psgBitplaneSize]uint8) []uint8 {
+	tile := make([]uint8, psgTileSize)
+	for p, plane := range planes {
+		for row, b := range plane {
+			tile[row*psgBitplanes+p] = b
+		}
+	}
+	return tile
+}
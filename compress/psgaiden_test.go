@@ -0,0 +1,131 @@
+package compress_test
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/mrcook/smstilemap/compress"
+)
+
+func TestPSGaiden_RoundTrip(t *testing.T) {
+	tiles := map[string][]uint8{
+		"blank tile":  make([]uint8, 32),
+		"solid tile":  bytes.Repeat([]uint8{0xFF}, 32),
+		"raw tile":    sequence(32),
+		"mixed tiles": append(append(sequence(32), bytes.Repeat([]uint8{0x0F, 0xF0, 0x0F, 0xF0}, 8)...), make([]uint8, 32)...),
+		"random":      randomTiles(100),
+	}
+
+	for name, data := range tiles {
+		t.Run(name, func(t *testing.T) {
+			packed, err := compress.PSGaidenCompress(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			unpacked, err := compress.PSGaidenDecompress(packed)
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if !bytes.Equal(unpacked, data) {
+				t.Errorf("expected decompressed data to match the original")
+			}
+		})
+	}
+}
+
+func TestPSGaiden_Compress(t *testing.T) {
+	t.Run("bitplane encodings", func(t *testing.T) {
+		tile := make([]uint8, 32)
+		for row := 0; row < 8; row++ {
+			tile[row*4+0] = 0x00        // plane 0: all zero
+			tile[row*4+1] = uint8(row)  // plane 1: mostly unique, raw
+			tile[row*4+2] = uint8(row)  // plane 2: copy of plane 1
+			tile[row*4+3] = ^uint8(row) // plane 3: inverted copy of plane 1
+		}
+
+		got, err := compress.PSGaidenCompress(tile)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		want := []uint8{
+			0x01, 0x00, // tile count
+			0b00101111,                                     // methods: zero, raw, packed, packed
+			0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, // plane 1 raw
+			0x01, // plane 2 copy of plane 1
+			0x11, // plane 3 inverted copy of plane 1
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("unexpected compressed data, got % X", got)
+		}
+	})
+
+	t.Run("common value bitmask", func(t *testing.T) {
+		tile := bytes.Repeat([]uint8{0xFF, 0x00, 0x00, 0x00}, 8)
+		tile[0] = 0x42
+		tile[28] = 0x24
+
+		got, _ := compress.PSGaidenCompress(tile)
+		want := []uint8{0x01, 0x00, 0b11000000, 0b01111110, 0xFF, 0x42, 0x24}
+		if !bytes.Equal(got, want) {
+			t.Errorf("unexpected compressed data, got % X", got)
+		}
+	})
+
+	t.Run("with partial tile data", func(t *testing.T) {
+		_, err := compress.PSGaidenCompress(make([]uint8, 33))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "psgaiden: tile data must be a multiple of 32 bytes, got 33" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
+
+func TestPSGaiden_Decompress(t *testing.T) {
+	t.Run("with truncated data", func(t *testing.T) {
+		_, err := compress.PSGaidenDecompress([]uint8{0x01, 0x00, 0b10000000, 0x01})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "psgaiden: unexpected end of data at offset 3" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+
+	t.Run("with copy of a later bitplane", func(t *testing.T) {
+		_, err := compress.PSGaidenDecompress([]uint8{0x01, 0x00, 0b11000000, 0x02})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "psgaiden: tile 0: bitplane 0 copies bitplane 2, which has not been decoded" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
+
+func sequence(n int) []uint8 {
+	data := make([]uint8, n)
+	for i := range data {
+		data[i] = uint8(i)
+	}
+	return data
+}
+
+// returns tiles using a few palette colours, similar to real tile data
+func randomTiles(count int) []uint8 {
+	rnd := rand.New(rand.NewSource(1))
+	data := make([]uint8, count*32)
+	for i := range data {
+		switch rnd.Intn(4) {
+		case 0:
+			data[i] = 0x00
+		case 1:
+			data[i] = 0xFF
+		default:
+			data[i] = uint8(rnd.Intn(256))
+		}
+	}
+	return data
+}
@@ -0,0 +1,159 @@
+package compress
+
+import "fmt"
+
+// The STM (Sverx's TileMap) format compresses tilemap entries, taking
+// advantage of the high byte of each entry (the tile flags and bit 8 of the
+// tile number) rarely changing, and of runs of the same, or consecutive,
+// tile numbers.
+//
+// Data format:
+//
+// The data starts with the map width in entries (e.g. 32), followed by a
+// stream of commands. The decoder keeps a current high byte (HH), initially
+// $00, which is combined with each low byte to give the tilemap entry. Each
+// command byte holds its type in bits 1-0, and a value in bits 7-2 (n):
+//
+//   %00 - raw: n low bytes follow, using HH; a $00 command ends the data
+//   %01 - run: the next low byte is repeated n+2 times
+//   %10 - increment: the next low byte is used n+2 times, adding one each time
+//   %11 - set HH to n
+//
+// The entries are written in order, left-to-right, top-to-bottom, so a map
+// the width of the name table can be written directly to VRAM.
+
+const (
+	stmRaw       = 0b00
+	stmRun       = 0b01
+	stmIncrement = 0b10
+	stmSetHigh   = 0b11
+	stmEnd       = 0x00
+	stmMaxRaw    = 63
+	stmMaxRun    = 63 + 2
+)
+
+// STMCompress compresses the tilemap entries, which are stored row by row for
+// a map of the given width.
+func STMCompress(words []uint16, width int) ([]uint8, error) {
+	if width <= 0 || width > 255 {
+		return nil, fmt.Errorf("stm: invalid map width %d, must be within 1..255", width)
+	}
+	if len(words)%width != 0 {
+		return nil, fmt.Errorf("stm: tilemap size %d must be a multiple of the map width %d", len(words), width)
+	}
+
+	out := []uint8{uint8(width)}
+	high := uint8(0)
+
+	for i := 0; i < len(words); {
+		if hh := uint8(words[i] >> 8); hh != high {
+			if hh > 0b00111111 {
+				return nil, fmt.Errorf("stm: invalid tilemap entry $%04X at %d", words[i], i)
+			}
+			out = append(out, hh<<2|stmSetHigh)
+			high = hh
+		}
+
+		run := stmRunLength(words[i:], 0)
+		inc := stmRunLength(words[i:], 1)
+		switch {
+		case run >= 2 && run >= inc:
+			out = append(out, uint8(run-2)<<2|stmRun, uint8(words[i]))
+			i += run
+		case inc >= 2:
+			out = append(out, uint8(inc-2)<<2|stmIncrement, uint8(words[i]))
+			i += inc
+		default:
+			// raw entries, until the high byte changes, or a run is worth using
+			count := 1
+			for i+count < len(words) && count < stmMaxRaw {
+				next := words[i+count:]
+				if uint8(next[0]>>8) != high || stmRunLength(next, 0) >= 3 || stmRunLength(next, 1) >= 3 {
+					break
+				}
+				count++
+			}
+			out = append(out, uint8(count)<<2|stmRaw)
+			for _, word := range words[i : i+count] {
+				out = append(out, uint8(word))
+			}
+			i += count
+		}
+	}
+
+	return append(out, stmEnd), nil
+}
+
+// STMDecompress decompresses STM data, returning the tilemap entries, row by
+// row, along with the map width.
+func STMDecompress(data []uint8) ([]uint16, int, error) {
+	if len(data) < 1 {
+		return nil, 0, fmt.Errorf("stm: missing map width")
+	}
+	width := int(data[0])
+	pos := 1
+
+	// returns the next byte of the input
+	next := func() (uint8, error) {
+		if pos >= len(data) {
+			return 0, fmt.Errorf("stm: unexpected end of data at offset %d", pos)
+		}
+		pos++
+		return data[pos-1], nil
+	}
+
+	var words []uint16
+	high := uint16(0)
+	for {
+		command, err := next()
+		if err != nil {
+			return nil, 0, err
+		}
+		n := int(command >> 2)
+
+		switch command & 0b11 {
+		case stmRaw:
+			if command == stmEnd {
+				if width == 0 || len(words)%width != 0 {
+					return nil, 0, fmt.Errorf("stm: tilemap size %d is not a multiple of the map width %d", len(words), width)
+				}
+				return words, width, nil
+			}
+			for i := 0; i < n; i++ {
+				low, err := next()
+				if err != nil {
+					return nil, 0, err
+				}
+				words = append(words, high|uint16(low))
+			}
+		case stmRun, stmIncrement:
+			low, err := next()
+			if err != nil {
+				return nil, 0, err
+			}
+			for i := 0; i < n+2; i++ {
+				words = append(words, high|uint16(low))
+				if command&0b11 == stmIncrement {
+					low++
+				}
+			}
+		case stmSetHigh:
+			high = uint16(n) << 8
+		}
+	}
+}
+
+// returns the number of entries at the start of the words that form a run,
+// with each entry adding the step to the previous one. The high byte must not
+// change during the run.
+func stmRunLength(words []uint16, step uint16) int {
+	count := 1
+	for count < len(words) && count < stmMaxRun {
+		prev, word := words[count-1], words[count]
+		if word != prev+step || word>>8 != prev>>8 {
+			break
+		}
+		count++
+	}
+	return count
+}
@@ -0,0 +1,156 @@
+package compress_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/mrcook/smstilemap/compress"
+)
+
+func TestSTM_RoundTrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	random := make([]uint16, 32*28)
+	for i := range random {
+		random[i] = uint16(rnd.Intn(0x2000))
+		if rnd.Intn(3) == 0 && i > 0 {
+			random[i] = random[i-1] + uint16(rnd.Intn(2))
+		}
+	}
+	sequential := make([]uint16, 32*24)
+	for i := range sequential {
+		sequential[i] = uint16(i) | 0x0800
+	}
+
+	tilemaps := map[string][]uint16{
+		"blank":      make([]uint16, 32*24),
+		"sequential": sequential,
+		"random":     random,
+	}
+
+	for name, words := range tilemaps {
+		t.Run(name, func(t *testing.T) {
+			packed, err := compress.STMCompress(words, 32)
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			unpacked, width, err := compress.STMDecompress(packed)
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if width != 32 {
+				t.Errorf("expected map width of 32, got %d", width)
+			}
+			if !reflect.DeepEqual(unpacked, words) {
+				t.Errorf("expected decompressed data to match the original")
+			}
+		})
+	}
+}
+
+func TestSTM_Compress(t *testing.T) {
+	words := []uint16{
+		0x0000, 0x0000, 0x0000, 0x0000, // run
+		0x0005, 0x0006, 0x0007, // increment
+		0x0101, 0x0120, // set HH, raw
+	}
+
+	got, err := compress.STMCompress(words, 9)
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	want := []uint8{
+		0x09,             // width
+		0b00001001, 0x00, // run of 4
+		0b00000110, 0x05, // increment of 3
+		0b00000111,             // HH = $01
+		0b00001000, 0x01, 0x20, // 2 raw
+		0x00, // end
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected compressed data, got % X", got)
+	}
+
+	t.Run("with invalid width", func(t *testing.T) {
+		_, err := compress.STMCompress(words, 4)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "stm: tilemap size 9 must be a multiple of the map width 4" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
+
+func TestSTM_Decompress(t *testing.T) {
+	// hand assembled data, following the command format
+	fixture := []uint8{
+		0x04,             // width of 4 entries
+		0b00001010, 0x10, // increment: 2+2 entries, from $10
+		0b00100011,       // HH = $08
+		0b00001001, 0x20, // run: 2+2 entries of $20
+		0b00010000, 0x01, 0x30, 0x02, 0x40, // raw: 4 entries
+		0b11111111,       // HH = $3F
+		0b00000001, 0xFF, // run: 0+2 entries of $FF
+		0b00000110, 0xFE, // increment: 1+2 entries, from $FE, wrapping the low byte
+		0b00000011,                   // HH = $00
+		0b00001100, 0x00, 0x01, 0x00, // raw: 3 entries
+		0x00, // end
+	}
+	want := []uint16{
+		0x0010, 0x0011, 0x0012, 0x0013,
+		0x0820, 0x0820, 0x0820, 0x0820,
+		0x0801, 0x0830, 0x0802, 0x0840,
+		0x3FFF, 0x3FFF, 0x3FFE, 0x3FFF,
+		0x3F00, 0x0000, 0x0001, 0x0000,
+	}
+
+	words, width, err := compress.STMDecompress(fixture)
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if width != 4 {
+		t.Errorf("expected map width of 4, got %d", width)
+	}
+	if !reflect.DeepEqual(words, want) {
+		t.Errorf("unexpected tilemap entries, got %04X", words)
+	}
+
+	t.Run("with the maximum command lengths", func(t *testing.T) {
+		fixture := []uint8{0x02, 0b11111100} // width of 2, raw: 63 entries
+		want := make([]uint16, 0, 128)
+		for i := 0; i < 63; i++ {
+			fixture = append(fixture, uint8(i*2))
+			want = append(want, uint16(i*2))
+		}
+		fixture = append(fixture, 0b11111101, 0x07, 0x00) // run: 63+2 entries of $07, end
+		for i := 0; i < 65; i++ {
+			want = append(want, 0x0007)
+		}
+
+		words, width, err := compress.STMDecompress(fixture)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if width != 2 || !reflect.DeepEqual(words, want) {
+			t.Errorf("unexpected tilemap, got width %d, entries %04X", width, words)
+		}
+		packed, err := compress.STMCompress(want, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if !reflect.DeepEqual(packed, fixture) {
+			t.Errorf("expected the compressed data to match the fixture, got % X", packed)
+		}
+	})
+
+	t.Run("with missing end marker", func(t *testing.T) {
+		_, _, err := compress.STMDecompress([]uint8{0x02, 0b00000101, 0x01})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "stm: unexpected end of data at offset 3" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
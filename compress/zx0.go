@@ -0,0 +1,300 @@
+package compress
+
+import (
+	"fmt"
+)
+
+// The ZX0 format, by Einar Saukas, is a general purpose LZ77 format with a
+// very small and fast Z80 decompressor, suited to any kind of data.
+//
+// Data format:
+//
+// The data is a sequence of blocks, always starting with literals:
+//
+//   literals:         Elias(length), followed by the bytes
+//   copy last offset: 0, Elias(length)
+//   copy new offset:  1, Elias(MSB), LSB, Elias(length-1)
+//
+// After literals, the next block is a copy, selected by its first bit; after
+// a copy, a 0 bit selects literals, and a 1 bit a copy from a new offset.
+// The last offset starts as 1.
+//
+// Elias values use the interlaced Elias gamma code: for each bit below the
+// most significant, a 0 followed by the bit, then a final 1. For the offset
+// MSB, (offset-1)/128+1, the value bits are inverted, and an MSB of 256 marks
+// the end of the data. The LSB byte holds 127-(offset-1)%128 in its upper 7
+// bits, with bit 0 holding the first bit of the length that follows.
+
+const (
+	zx0MaxOffset     = 32640
+	zx0MaxLength     = 65535
+	zx0InitialOffset = 1
+	zx0EndMarker     = 256
+	zx0LiteralWindow = 256 // literal block starts checked for each position
+)
+
+const (
+	zx0Literals = iota
+	zx0CopyLast
+	zx0CopyNew
+)
+
+// zx0Block is the last block of the cheapest encoding found up to a position.
+type zx0Block struct {
+	valid       bool
+	cost        int // encoding size in bits
+	kind        int
+	start       int  // position of the first byte of the block
+	fromLiteral bool // the previous block is literals
+	offset      int  // last offset used by the encoding
+}
+
+// ZX0Compress compresses the data using the ZX0 format.
+func ZX0Compress(data []uint8) ([]uint8, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("zx0: no data to compress")
+	}
+	n := len(data)
+
+	// the cheapest encodings ending with literals, or a copy, at each position
+	literals := make([]zx0Block, n+1)
+	copies := make([]zx0Block, n+1)
+	copies[0] = zx0Block{valid: true, offset: zx0InitialOffset}
+
+	update := func(blocks []zx0Block, pos int, block zx0Block) {
+		if !blocks[pos].valid || block.cost < blocks[pos].cost {
+			block.valid = true
+			blocks[pos] = block
+		}
+	}
+
+	matcher := newLZMatcher(data, zx0MaxOffset, zx0MaxLength)
+	far := -1 // the cheapest start for literals outside the window
+
+	for pos := 1; pos <= n; pos++ {
+		// literals can only follow a copy, or the start of the data
+		if j := pos - zx0LiteralWindow - 1; j >= 0 && copies[j].valid {
+			if far < 0 || zx0LiteralBase(copies, j) < zx0LiteralBase(copies, far) {
+				far = j
+			}
+		}
+		starts := make([]int, 0, zx0LiteralWindow+1)
+		for j := pos - 1; j >= 0 && j >= pos-zx0LiteralWindow; j-- {
+			starts = append(starts, j)
+		}
+		if far >= 0 {
+			starts = append(starts, far)
+		}
+		for _, j := range starts {
+			length := pos - j
+			if !copies[j].valid || length > zx0MaxLength {
+				continue
+			}
+			cost := zx0LiteralBase(copies, j) + 8*pos + eliasGammaSize(length)
+			update(literals, pos, zx0Block{cost: cost, kind: zx0Literals, start: j, offset: copies[j].offset})
+		}
+
+		if pos == n {
+			break
+		}
+
+		// copy from the last offset, which can only follow literals
+		if lit := literals[pos]; lit.valid && lit.offset <= pos {
+			limit := min(n-pos, zx0MaxLength)
+			for _, length := range lzLengths(1, matcher.length(pos, lit.offset, limit)) {
+				cost := lit.cost + 1 + eliasGammaSize(length)
+				update(copies, pos+length, zx0Block{cost: cost, kind: zx0CopyLast, start: pos, fromLiteral: true, offset: lit.offset})
+			}
+		}
+
+		// copy from a new offset, following the cheapest previous block
+		prev, fromLiteral := copies[pos], false
+		if literals[pos].valid && (!prev.valid || literals[pos].cost < prev.cost) {
+			prev, fromLiteral = literals[pos], true
+		}
+		if !prev.valid {
+			continue
+		}
+		shortest := 2
+		for _, match := range matcher.matches(pos) {
+			for _, length := range lzLengths(shortest, match.length) {
+				cost := prev.cost + 1 + eliasGammaSize((match.offset-1)/128+1) + 8 + eliasGammaSize(length-1)
+				update(copies, pos+length, zx0Block{cost: cost, kind: zx0CopyNew, start: pos, fromLiteral: fromLiteral, offset: match.offset})
+			}
+			shortest = match.length + 1
+		}
+	}
+
+	// collect the blocks of the cheapest encoding
+	var blocks []zx0Block
+	block := copies[n]
+	if literals[n].valid && (!block.valid || literals[n].cost <= block.cost) {
+		block = literals[n]
+	}
+	for end := n; end > 0; {
+		blocks = append(blocks, block)
+		end = block.start
+		if block.fromLiteral {
+			block = literals[end]
+		} else {
+			block = copies[end]
+		}
+	}
+
+	w := &bitWriter{}
+	end := 0
+	for i := len(blocks) - 1; i >= 0; i-- {
+		block := blocks[i]
+		length := zx0BlockLength(blocks, i, n)
+		switch block.kind {
+		case zx0Literals:
+			if end > 0 {
+				w.writeBit(0)
+			}
+			zx0WriteEliasGamma(w, length, false)
+			for _, b := range data[end : end+length] {
+				w.writeByte(b)
+			}
+		case zx0CopyLast:
+			w.writeBit(0)
+			zx0WriteEliasGamma(w, length, false)
+		case zx0CopyNew:
+			w.writeBit(1)
+			zx0WriteEliasGamma(w, (block.offset-1)/128+1, true)
+			w.writeByte(uint8(127-(block.offset-1)%128) << 1)
+			w.backtrack = true
+			zx0WriteEliasGamma(w, length-1, false)
+		}
+		end += length
+	}
+	w.writeBit(1)
+	zx0WriteEliasGamma(w, zx0EndMarker, true)
+
+	return w.out, nil
+}
+
+// ZX0Decompress decompresses ZX0 data.
+func ZX0Decompress(data []uint8) ([]uint8, error) {
+	r := &bitReader{format: "zx0", data: data}
+	var out []uint8
+	offset := zx0InitialOffset
+
+	for {
+		// literals
+		length, err := zx0ReadEliasGamma(r, false)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < length; i++ {
+			b, err := r.readByte()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, b)
+		}
+
+		bit, err := r.readBit()
+		if err != nil {
+			return nil, err
+		}
+		if bit == 0 {
+			// copy from the last offset
+			if length, err = zx0ReadEliasGamma(r, false); err != nil {
+				return nil, err
+			}
+			if out, err = lzCopy("zx0", out, offset, length); err != nil {
+				return nil, err
+			}
+			if bit, err = r.readBit(); err != nil {
+				return nil, err
+			}
+		}
+
+		// copies from new offsets, until the next literals
+		for bit == 1 {
+			msb, err := zx0ReadEliasGamma(r, true)
+			if err != nil {
+				return nil, err
+			}
+			if msb == zx0EndMarker {
+				return out, nil
+			} else if msb > zx0EndMarker {
+				return nil, fmt.Errorf("zx0: invalid offset at offset %d", r.pos)
+			}
+			lsb, err := r.readByte()
+			if err != nil {
+				return nil, err
+			}
+			offset = msb*128 - int(lsb>>1)
+			r.backtrack = true
+			if length, err = zx0ReadEliasGamma(r, false); err != nil {
+				return nil, err
+			}
+			if out, err = lzCopy("zx0", out, offset, length+1); err != nil {
+				return nil, err
+			}
+			if bit, err = r.readBit(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// returns the cost of the encoding up to the start of literals at the
+// position, less the cost of the literal bytes before it
+func zx0LiteralBase(copies []zx0Block, pos int) int {
+	cost := copies[pos].cost - 8*pos
+	if pos > 0 {
+		cost++ // the flag selecting literals
+	}
+	return cost
+}
+
+// returns the number of bytes encoded by the block at the index, where the
+// blocks are in reverse order
+func zx0BlockLength(blocks []zx0Block, index, total int) int {
+	if index == 0 {
+		return total - blocks[0].start
+	}
+	return blocks[index-1].start - blocks[index].start
+}
+
+// writes the value using the interlaced Elias gamma code, optionally with
+// the value bits inverted
+func zx0WriteEliasGamma(w *bitWriter, value int, inverted bool) {
+	bit := 1
+	for bit<<1 <= value {
+		bit <<= 1
+	}
+	for bit >>= 1; bit > 0; bit >>= 1 {
+		w.writeBit(0)
+		if (value&bit != 0) != inverted {
+			w.writeBit(1)
+		} else {
+			w.writeBit(0)
+		}
+	}
+	w.writeBit(1)
+}
+
+func zx0ReadEliasGamma(r *bitReader, inverted bool) (int, error) {
+	value := 1
+	for {
+		bit, err := r.readBit()
+		if err != nil {
+			return 0, err
+		} else if bit == 1 {
+			return value, nil
+		}
+		if bit, err = r.readBit(); err != nil {
+			return 0, err
+		}
+		if inverted {
+			bit ^= 1
+		}
+		value = value<<1 | bit
+		if value > zx0MaxLength {
+			return 0, fmt.Errorf("zx0: invalid Elias gamma value at offset %d", r.pos)
+		}
+	}
+}
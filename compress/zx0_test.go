@@ -0,0 +1,68 @@
+package compress_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mrcook/smstilemap/compress"
+)
+
+func TestZX0_RoundTrip(t *testing.T) {
+	inputs := map[string][]uint8{
+		"single byte": {0x2A},
+		"zeros":       make([]uint8, 5000),
+		"sequence":    sequence(256),
+		"repeated":    bytes.Repeat(sequence(40), 50),
+		"tiles":       randomTiles(200),
+	}
+
+	for name, data := range inputs {
+		t.Run(name, func(t *testing.T) {
+			packed, err := compress.ZX0Compress(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			unpacked, err := compress.ZX0Decompress(packed)
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if !bytes.Equal(unpacked, data) {
+				t.Errorf("expected decompressed data to match the original")
+			}
+		})
+	}
+}
+
+func TestZX0_Compress(t *testing.T) {
+	got, err := compress.ZX0Compress([]uint8{0x41, 0x41, 0x41, 0x41})
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	// literal, copy of 3 from the last offset, end marker
+	want := []uint8{0b10011101, 0x41, 0b01010101, 0b01010110}
+	if !bytes.Equal(got, want) {
+		t.Errorf("unexpected compressed data, got % X", got)
+	}
+
+	t.Run("with no data", func(t *testing.T) {
+		_, err := compress.ZX0Compress(nil)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "zx0: no data to compress" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
+
+func TestZX0_Decompress(t *testing.T) {
+	t.Run("with truncated data", func(t *testing.T) {
+		_, err := compress.ZX0Decompress([]uint8{0b10011101, 0x41})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "zx0: unexpected end of data at offset 2" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
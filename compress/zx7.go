@@ -0,0 +1,184 @@
+package compress
+
+import (
+	"fmt"
+)
+
+// The ZX7 format, by Einar Saukas, is the predecessor of ZX0, and is still
+// used by many existing projects.
+//
+// Data format:
+//
+// The first byte is a literal, which is followed by a sequence of blocks,
+// each starting with a flag bit:
+//
+//   0 - literal: a single byte follows
+//   1 - copy: Elias(length-1), followed by the offset
+//
+// The length uses the Elias gamma code: a 0 bit for each bit below the most
+// significant, then the value bits. The offset is stored as offset-1: values
+// below 128 are stored in a single byte, otherwise the byte holds bit 7 set
+// and the low 7 bits of offset-129, with the next 4 bits holding bits 10-7.
+// A copy with a length code of 16 zero bits, then a 1, marks the end of the
+// data.
+
+const (
+	zx7MaxOffset    = 2176
+	zx7MaxLength    = 65536
+	zx7ShortOffset  = 128
+	zx7EndMarkerLen = 16
+)
+
+// zx7Block is the last block of the cheapest encoding found up to a position.
+type zx7Block struct {
+	valid  bool
+	cost   int // encoding size in bits
+	offset int // 0 for a literal
+	start  int // position of the first byte of the block
+}
+
+// ZX7Compress compresses the data using the ZX7 format.
+func ZX7Compress(data []uint8) ([]uint8, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("zx7: no data to compress")
+	}
+	n := len(data)
+
+	blocks := make([]zx7Block, n+1)
+	blocks[1] = zx7Block{valid: true, cost: 8}
+
+	update := func(pos int, block zx7Block) {
+		if !blocks[pos].valid || block.cost < blocks[pos].cost {
+			block.valid = true
+			blocks[pos] = block
+		}
+	}
+
+	matcher := newLZMatcher(data, zx7MaxOffset, zx7MaxLength)
+	for pos := 1; pos < n; pos++ {
+		prev := blocks[pos]
+		update(pos+1, zx7Block{cost: prev.cost + 9, start: pos})
+
+		shortest := 2
+		for _, match := range matcher.matches(pos) {
+			offsetSize := 8
+			if match.offset > zx7ShortOffset {
+				offsetSize += 4
+			}
+			for _, length := range lzLengths(shortest, match.length) {
+				cost := prev.cost + 1 + eliasGammaSize(length-1) + offsetSize
+				update(pos+length, zx7Block{cost: cost, offset: match.offset, start: pos})
+			}
+			shortest = match.length + 1
+		}
+	}
+
+	// collect the blocks of the cheapest encoding
+	var path []zx7Block
+	for pos := n; pos > 1; pos = blocks[pos].start {
+		path = append(path, blocks[pos])
+	}
+
+	w := &bitWriter{}
+	w.writeByte(data[0])
+	end := 1
+	for i := len(path) - 1; i >= 0; i-- {
+		block := path[i]
+		length := n - block.start
+		if i > 0 {
+			length = path[i-1].start - block.start
+		}
+		if block.offset == 0 {
+			w.writeBit(0)
+			w.writeByte(data[end])
+		} else {
+			w.writeBit(1)
+			zx7WriteEliasGamma(w, length-1)
+			offset := block.offset - 1
+			if offset < zx7ShortOffset {
+				w.writeByte(uint8(offset))
+			} else {
+				offset -= zx7ShortOffset
+				w.writeByte(uint8(offset&0x7F) | 0x80)
+				w.writeBits(offset>>7, 4)
+			}
+		}
+		end += length
+	}
+	w.writeBit(1)
+	w.writeBits(0, zx7EndMarkerLen)
+	w.writeBit(1)
+
+	return w.out, nil
+}
+
+// ZX7Decompress decompresses ZX7 data.
+func ZX7Decompress(data []uint8) ([]uint8, error) {
+	r := &bitReader{format: "zx7", data: data}
+
+	first, err := r.readByte()
+	if err != nil {
+		return nil, err
+	}
+	out := []uint8{first}
+
+	for {
+		bit, err := r.readBit()
+		if err != nil {
+			return nil, err
+		}
+		if bit == 0 {
+			b, err := r.readByte()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, b)
+			continue
+		}
+
+		// the length, where too many bits is the end marker
+		zeros := 0
+		for bit, err = r.readBit(); bit == 0 && err == nil; bit, err = r.readBit() {
+			zeros++
+		}
+		if err != nil {
+			return nil, err
+		}
+		if zeros >= zx7EndMarkerLen {
+			return out, nil
+		}
+		length := 1
+		for i := 0; i < zeros; i++ {
+			if bit, err = r.readBit(); err != nil {
+				return nil, err
+			}
+			length = length<<1 | bit
+		}
+
+		b, err := r.readByte()
+		if err != nil {
+			return nil, err
+		}
+		offset := int(b&0x7F) + 1
+		if b&0x80 != 0 {
+			high := 0
+			for i := 0; i < 4; i++ {
+				if bit, err = r.readBit(); err != nil {
+					return nil, err
+				}
+				high = high<<1 | bit
+			}
+			offset += (high + 1) << 7
+		}
+		if out, err = lzCopy("zx7", out, offset, length+1); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// writes the value using the Elias gamma code
+func zx7WriteEliasGamma(w *bitWriter, value int) {
+	size := (eliasGammaSize(value) + 1) / 2
+	w.writeBits(0, size-1)
+	w.writeBits(value, size)
+}
@@ -0,0 +1,70 @@
+package compress_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/mrcook/smstilemap/compress"
+)
+
+func TestZX7_RoundTrip(t *testing.T) {
+	inputs := map[string][]uint8{
+		"single byte": {0x2A},
+		"zeros":       make([]uint8, 5000),
+		"sequence":    sequence(256),
+		"repeated":    bytes.Repeat(sequence(200), 12),
+		"tiles":       randomTiles(200),
+	}
+
+	for name, data := range inputs {
+		t.Run(name, func(t *testing.T) {
+			packed, err := compress.ZX7Compress(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			unpacked, err := compress.ZX7Decompress(packed)
+			if err != nil {
+				t.Fatalf("unexpected error: %q", err)
+			}
+			if !bytes.Equal(unpacked, data) {
+				t.Errorf("expected decompressed data to match the original")
+			}
+		})
+	}
+}
+
+func TestZX7_Compress(t *testing.T) {
+	got, err := compress.ZX7Compress([]uint8{0x41, 0x41, 0x41, 0x41})
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	// literal, copy of 3 from offset 1, end marker
+	want := []uint8{0x41, 0b10101000, 0x00, 0x00, 0b00000100}
+	if !bytes.Equal(got, want) {
+		t.Errorf("unexpected compressed data, got % X", got)
+	}
+
+	t.Run("with a long offset", func(t *testing.T) {
+		data := append(sequence(200), sequence(200)...)
+		packed, _ := compress.ZX7Compress(data)
+		unpacked, err := compress.ZX7Decompress(packed)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if !bytes.Equal(unpacked, data) {
+			t.Errorf("expected decompressed data to match the original")
+		}
+	})
+}
+
+func TestZX7_Decompress(t *testing.T) {
+	t.Run("with an offset before the start of the data", func(t *testing.T) {
+		_, err := compress.ZX7Decompress([]uint8{0x41, 0b10101000, 0x05})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "zx7: invalid offset 6 at output position 1" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
@@ -0,0 +1,171 @@
+// Package csource writes the SMS data as C source and header files, for use
+// with devkitSMS and SDCC projects.
+//
+// Each data set is written as its own `const` array, in its own source file,
+// so that the arrays can be compiled into separate ROM banks (for example with
+// the SDCC `--constseg BANK2` option), and are named in the same way as the
+// assets2banks tool: `name_bin`, with a `name_bin_size` macro giving the size
+// in bytes. Tile and palette data use `unsigned char` arrays, and the tilemap
+// uses `unsigned int` (16-bit) arrays, which SDCC stores in little-endian
+// format, ready for loading with `SMS_loadTiles`, `SMS_loadTileMap`, and
+// `SMS_loadBGPalette`.
+package csource
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	bytesPerLine = 16
+	wordsPerLine = 8
+)
+
+// Define is a named constant written to the header file as a macro.
+type Define struct {
+	Name  string
+	Value int
+}
+
+// Declaration is an array declared in the header file.
+type Declaration struct {
+	Name string // array name, e.g. `level_tiles_bin`
+	Word bool   // an `unsigned int` array, otherwise `unsigned char`
+	Size int    // size in bytes
+}
+
+// Identifier returns a valid C identifier derived from the filename, without
+// its directory or extension, e.g. `/path/level-1.png` becomes `level_1`.
+func Identifier(filename string) string {
+	base := filepath.Base(filename)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+
+	var sb strings.Builder
+	for i, r := range strings.ToLower(base) {
+		switch {
+		case r >= 'a' && r <= 'z', r == '_':
+			sb.WriteRune(r)
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				sb.WriteRune('_') // identifiers can not start with a digit
+			}
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	if sb.Len() == 0 {
+		return "data"
+	}
+	return sb.String()
+}
+
+// Header writes the header file, with the constant macros, followed by the
+// extern declarations and size macros for each array. The guard is used for
+// the include guard macro.
+func Header(guard string, defines []Define, declarations []Declaration) *strings.Builder {
+	var sb strings.Builder
+	guard = strings.ToUpper(guard) + "_H"
+
+	sb.WriteString(fmt.Sprintf("#ifndef %s\n", guard))
+	sb.WriteString(fmt.Sprintf("#define %s\n", guard))
+	if len(defines) > 0 {
+		sb.WriteString("\n")
+	}
+	for _, d := range defines {
+		sb.WriteString(fmt.Sprintf("#define %s %d\n", d.Name, d.Value))
+	}
+	for _, d := range declarations {
+		sb.WriteString("\n")
+		sb.WriteString(fmt.Sprintf("#define %s_size %d\n", d.Name, d.Size))
+		if d.Word {
+			sb.WriteString(fmt.Sprintf("extern const unsigned int %s[%d];\n", d.Name, d.Size/2))
+		} else {
+			sb.WriteString(fmt.Sprintf("extern const unsigned char %s[%d];\n", d.Name, d.Size))
+		}
+	}
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("#endif // %s\n", guard))
+	return &sb
+}
+
+// Tiles writes the planar tile data, 32 bytes per tile.
+func Tiles(name string, data []uint8) *strings.Builder {
+	var sb strings.Builder
+
+	sb.WriteString("// Tile data (characters)\n")
+	sb.WriteString(fmt.Sprintf("// %d tiles of 32 bytes: 4 bit planes for each of the 8 rows of pixels.\n", len(data)/32))
+	sb.WriteString(Bytes(name, data).String())
+	return &sb
+}
+
+// Tilemap writes the tilemap entries, 32 words per row, up to the given number
+// of rows. Any remaining off-screen rows are not written.
+func Tilemap(name string, data []uint16, rows int) *strings.Builder {
+	var sb strings.Builder
+	if len(data) > rows*32 {
+		data = data[:rows*32]
+	}
+
+	sb.WriteString("// Tilemap data (the name table)\n")
+	sb.WriteString(fmt.Sprintf("// A matrix of %d rows and 32 columns of 16-bit values:\n", len(data)/32))
+	sb.WriteString("//   Bit  |15 14 13|    12    |    11     |      10       |        9        | 8 7 6 5 4 3 2 1 0\n")
+	sb.WriteString("//   Data | Unused | Priority | Palette # | Vertical flip | Horizontal flip |    Tile number\n")
+	sb.WriteString(Words(name, data).String())
+	return &sb
+}
+
+// Palette writes the 32 bytes of palette data: the background palette,
+// followed by the sprite palette.
+func Palette(name string, data [32]uint8) *strings.Builder {
+	var sb strings.Builder
+
+	sb.WriteString("// Palette data: the background palette, then the sprite palette.\n")
+	sb.WriteString("// Each colour byte is %00BBGGRR.\n")
+	sb.WriteString(Bytes(name, data[:]).String())
+	return &sb
+}
+
+// GGPalette writes the 64 bytes of Game Gear palette data: the background
+// palette, followed by the sprite palette.
+func GGPalette(name string, data [64]uint8) *strings.Builder {
+	var sb strings.Builder
+
+	sb.WriteString("// Palette data: the background palette, then the sprite palette.\n")
+	sb.WriteString("// Each colour is a little-endian word, %0000BBBBGGGGRRRR.\n")
+	sb.WriteString(Bytes(name, data[:]).String())
+	return &sb
+}
+
+// Bytes writes the data as a `const unsigned char` array.
+func Bytes(name string, data []uint8) *strings.Builder {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("const unsigned char %s[%d] = {\n", name, len(data)))
+	for i := 0; i < len(data); i += bytesPerLine {
+		var values []string
+		for _, b := range data[i:min(i+bytesPerLine, len(data))] {
+			values = append(values, fmt.Sprintf("0x%02X", b))
+		}
+		sb.WriteString(fmt.Sprintf("  %s,\n", strings.Join(values, ",")))
+	}
+	sb.WriteString("};\n")
+	return &sb
+}
+
+// Words writes the data as a `const unsigned int` array.
+func Words(name string, data []uint16) *strings.Builder {
+	var sb strings.Builder
+
+	sb.WriteString(fmt.Sprintf("const unsigned int %s[%d] = {\n", name, len(data)))
+	for i := 0; i < len(data); i += wordsPerLine {
+		var values []string
+		for _, w := range data[i:min(i+wordsPerLine, len(data))] {
+			values = append(values, fmt.Sprintf("0x%04X", w))
+		}
+		sb.WriteString(fmt.Sprintf("  %s,\n", strings.Join(values, ",")))
+	}
+	sb.WriteString("};\n")
+	return &sb
+}
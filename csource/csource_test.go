@@ -0,0 +1,84 @@
+package csource_test
+
+import (
+	"testing"
+
+	"github.com/mrcook/smstilemap/csource"
+)
+
+func TestCSource_Identifier(t *testing.T) {
+	table := map[string]string{
+		"/path/to/jetpac.png": "jetpac",
+		"Level-1 Map.png":     "level_1_map",
+		"1up.png":             "_1up",
+		".png":                "data",
+	}
+	for filename, want := range table {
+		if got := csource.Identifier(filename); got != want {
+			t.Errorf("expected identifier for '%s' to be '%s', got '%s'", filename, want, got)
+		}
+	}
+}
+
+func TestCSource_Header(t *testing.T) {
+	got := csource.Header("level", []csource.Define{
+		{Name: "level_tile_count", Value: 2},
+	}, []csource.Declaration{
+		{Name: "level_tiles_bin", Size: 64},
+		{Name: "level_tilemap_bin", Word: true, Size: 1536},
+	}).String()
+
+	want := `#ifndef LEVEL_H
+#define LEVEL_H
+
+#define level_tile_count 2
+
+#define level_tiles_bin_size 64
+extern const unsigned char level_tiles_bin[64];
+
+#define level_tilemap_bin_size 1536
+extern const unsigned int level_tilemap_bin[768];
+
+#endif // LEVEL_H
+`
+	if got != want {
+		t.Errorf("unexpected output, got:\n%s", got)
+	}
+}
+
+func TestCSource_Bytes(t *testing.T) {
+	data := make([]uint8, 18)
+	data[0] = 0xFF
+	data[17] = 0x3F
+
+	got := csource.Bytes("level_palette_bin", data).String()
+	want := `const unsigned char level_palette_bin[18] = {
+  0xFF,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,0x00,
+  0x00,0x3F,
+};
+`
+	if got != want {
+		t.Errorf("unexpected output, got:\n%s", got)
+	}
+}
+
+func TestCSource_Tilemap(t *testing.T) {
+	data := make([]uint16, 32*28)
+	data[0] = 0b0001000000000001
+	data[32*24] = 0x01FF // off-screen row, not written
+
+	got := csource.Tilemap("level_tilemap_bin", data, 24).String()
+	want := `// Tilemap data (the name table)
+// A matrix of 24 rows and 32 columns of 16-bit values:
+//   Bit  |15 14 13|    12    |    11     |      10       |        9        | 8 7 6 5 4 3 2 1 0
+//   Data | Unused | Priority | Palette # | Vertical flip | Horizontal flip |    Tile number
+const unsigned int level_tilemap_bin[768] = {
+  0x1001,0x0000,0x0000,0x0000,0x0000,0x0000,0x0000,0x0000,
+`
+	if len(got) < len(want) || got[:len(want)] != want {
+		t.Errorf("unexpected output, got:\n%s", got[:min(len(got), 500)])
+	}
+	if got[len(got)-3:] != "};\n" {
+		t.Errorf("expected array to be closed, got:\n%s", got[len(got)-60:])
+	}
+}
@@ -2,22 +2,19 @@ package gg
 
 // ColourDataForColour returns the colour data for the requested colour.
 func ColourDataForColour(c Colour) ColourData {
-	for _, colour := range AllColours {
-		if c.Equal(colour.Index) {
-			return colour
-		}
+	if int(c) < len(AllColours) {
+		return AllColours[c] // the colours are listed in index order
 	}
 	return AllColours[0] // defaults to black
 }
 
 // ColourDataForRGB gets one of the GG colours for the given RGB values.
 func ColourDataForRGB(r, g, b uint8) ColourData {
-	for _, colour := range AllColours {
-		if r == colour.R && g == colour.G && b == colour.B {
-			return colour
-		}
+	// each 4-bit channel value is a multiple of 17 in 8-bit RGB
+	if r%17 != 0 || g%17 != 0 || b%17 != 0 {
+		return AllColours[0] // defaults to black
 	}
-	return AllColours[0] // defaults to black
+	return AllColours[int(b/17)<<8|int(g/17)<<4|int(r/17)]
 }
 
 // ColourDataForNearestRGB using a nearest match, converts 8-bit RGB values to a GG colour.
@@ -0,0 +1,130 @@
+package gg
+
+import (
+	"encoding"
+	"fmt"
+)
+
+// Colour RAM stores two palettes of 16 colours each, the same as the Master
+// System, except that each entry is a 12-bit colour stored in two bytes,
+// giving 64 bytes of CRAM.
+//
+// The first sixteen colours are the background palette and the second sixteen
+// are the sprite palette, which can also be used by the background tiles.
+// Each colour is written to CRAM as a little-endian word:
+//
+//	Word:   15 14 13 12 | 11 10 9 8 | 7 6 5 4 | 3 2 1 0
+//	    :      Unused   |   Blue    |  Green  |   Red
+
+const (
+	paletteSize        = 32
+	PaletteColourCount = 16 // number of colours in each of the two palettes
+)
+
+var PaletteErr = fmt.Errorf("palette error")
+
+var (
+	_ encoding.BinaryMarshaler   = (*Palette)(nil)
+	_ encoding.BinaryUnmarshaler = (*Palette)(nil)
+)
+
+// PaletteId references one of the possible 32 palette colours.
+type PaletteId uint8
+
+// Palette defines two palettes, each with 16 colours.
+type Palette struct {
+	colours [paletteSize]entry
+}
+
+type entry struct {
+	colour  Colour
+	enabled bool // required as colours are initialised to black
+}
+
+// ColourAt returns the colour stored at the given index position.
+func (p *Palette) ColourAt(pos PaletteId) (Colour, error) {
+	if pos >= paletteSize {
+		return 0, fmt.Errorf("%w: index out of bounds, got %d, max value is %d", PaletteErr, pos, paletteSize-1)
+	}
+	if !p.colours[pos].enabled {
+		return 0, fmt.Errorf("%w: uninitialised colour for requested palette ID", PaletteErr)
+	}
+	return p.colours[pos].colour, nil
+}
+
+// SetColourAt sets the palette colour at the given index position.
+func (p *Palette) SetColourAt(pos PaletteId, colour Colour) error {
+	if pos >= paletteSize {
+		return fmt.Errorf("%w: index out of bounds, got %d, max value is %d", PaletteErr, pos, paletteSize-1)
+	}
+	p.colours[pos].colour = colour
+	p.colours[pos].enabled = true
+	return nil
+}
+
+// AddColour in the first available slot and return its index position.
+// When the palette already contains the colour its position is returned,
+// or an error is the palette is full.
+func (p *Palette) AddColour(colour Colour) (PaletteId, error) {
+	if pos, err := p.PaletteIdFor(colour); err == nil {
+		return pos, nil
+	}
+
+	for i := range p.colours {
+		if !p.colours[i].enabled {
+			p.colours[i].colour = colour
+			p.colours[i].enabled = true
+			return PaletteId(i), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w: can not add colour, palette full", PaletteErr)
+}
+
+// PaletteIdFor returns the position ID for a matching colour.
+// If the colour is not found, an error is returned.
+func (p *Palette) PaletteIdFor(colour Colour) (PaletteId, error) {
+	for i := range p.colours {
+		if p.colours[i].enabled && p.colours[i].colour.Equal(colour) {
+			return PaletteId(i), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: no ID found to requested colour", PaletteErr)
+}
+
+// Bytes returns the palettes as the 64 bytes of CRAM data, two bytes per
+// colour with the low byte first. Unset colours are returned as $0000 values.
+func (p *Palette) Bytes() (data [2 * paletteSize]uint8) {
+	for i, c := range p.colours {
+		data[i*2] = uint8(c.colour.GG())
+		data[i*2+1] = uint8(c.colour.GG() >> 8)
+	}
+	return
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning
+// the 64 bytes of palette data as stored in CRAM.
+func (p *Palette) MarshalBinary() ([]byte, error) {
+	data := p.Bytes()
+	return data[:], nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface,
+// decoding 64 bytes of CRAM data, or 32 bytes for the background palette only.
+// Any existing colours are replaced.
+func (p *Palette) UnmarshalBinary(data []byte) error {
+	if len(data) != 2*paletteSize && len(data) != 2*PaletteColourCount {
+		return fmt.Errorf("%w: invalid data size, expected %d or %d bytes, got %d", PaletteErr, 2*PaletteColourCount, 2*paletteSize, len(data))
+	}
+	for i := 0; i < len(data); i += 2 {
+		if data[i+1] > 0b00001111 {
+			return fmt.Errorf("%w: invalid GG colour $%02X%02X at index %d", PaletteErr, data[i+1], data[i], i/2)
+		}
+	}
+
+	*p = Palette{}
+	for i := 0; i < len(data); i += 2 {
+		p.colours[i/2] = entry{colour: Colour(uint16(data[i+1])<<8 | uint16(data[i])), enabled: true}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package gg_test
+
+import (
+	"testing"
+
+	"github.com/mrcook/smstilemap/gg"
+)
+
+func TestPalette_ColourAt(t *testing.T) {
+	pal := gg.Palette{}
+
+	t.Run("with valid position", func(t *testing.T) {
+		colour := gg.ColourDataForRGB(170, 187, 204).Index
+		_ = pal.SetColourAt(31, colour)
+
+		got, err := pal.ColourAt(31)
+		if err != nil {
+			t.Fatal("unexpected error")
+		}
+		if got != colour {
+			t.Errorf("expected correct colour to be returned, got '0b%012b', expected '0b%012b'", got, colour)
+		}
+	})
+
+	t.Run("when position is greater than palette size", func(t *testing.T) {
+		_, err := pal.ColourAt(32)
+		if err == nil {
+			t.Fatal("expected an error")
+		} else if err.Error() != "palette error: index out of bounds, got 32, max value is 31" {
+			t.Errorf("expected correct error message, got '%s", err.Error())
+		}
+	})
+
+	t.Run("when colour at position is not enabled", func(t *testing.T) {
+		_, err := pal.ColourAt(0)
+		if err == nil {
+			t.Fatal("expected an error")
+		} else if err.Error() != "palette error: uninitialised colour for requested palette ID" {
+			t.Errorf("expected correct error message, got '%s", err.Error())
+		}
+	})
+}
+
+func TestPalette_AddColour(t *testing.T) {
+	t.Run("add colour to first available slot", func(t *testing.T) {
+		pal := gg.Palette{}
+		_ = pal.SetColourAt(0, gg.Colour(0x0FFF))
+		_ = pal.SetColourAt(2, gg.Colour(0x0FFF))
+
+		pos, err := pal.AddColour(gg.Colour(0x0001))
+		if err != nil {
+			t.Fatalf("unexpected error, got '%s'", err)
+		}
+		if pos != 1 {
+			t.Errorf("expected colour to be added at second slot, got %d", pos)
+		}
+	})
+
+	t.Run("with an existing colour, return its position", func(t *testing.T) {
+		pal := gg.Palette{}
+		_ = pal.SetColourAt(2, gg.Colour(0x0ABC))
+
+		pos, err := pal.AddColour(gg.Colour(0x0ABC))
+		if err != nil {
+			t.Fatalf("unexpected error, got '%s'", err)
+		}
+		if pos != 2 {
+			t.Errorf("expected existing position, got %d", pos)
+		}
+	})
+
+	t.Run("when palette is full, return an error", func(t *testing.T) {
+		pal := gg.Palette{}
+		for i := gg.PaletteId(0); i < 32; i++ {
+			_ = pal.SetColourAt(i, gg.Colour(0x0FFF))
+		}
+
+		_, err := pal.AddColour(gg.Colour(0x0003))
+		if err == nil {
+			t.Fatalf("expected error")
+		} else if err.Error() != "palette error: can not add colour, palette full" {
+			t.Errorf("expect a valid error message, got '%s'", err)
+		}
+	})
+}
+
+func TestPalette_PaletteIdFor(t *testing.T) {
+	pal := gg.Palette{}
+	_ = pal.SetColourAt(2, gg.Colour(0x0F0F))
+
+	pos, err := pal.PaletteIdFor(gg.Colour(0x0F0F))
+	if err != nil {
+		t.Fatalf("unexpected error, got '%s'", err)
+	}
+	if pos != 2 {
+		t.Errorf("expected existing position, got %d", pos)
+	}
+
+	if _, err := pal.PaletteIdFor(gg.Colour(0x000F)); err == nil {
+		t.Error("expected an error for a missing colour")
+	}
+}
+
+func TestPalette_Bytes(t *testing.T) {
+	pal := gg.Palette{}
+	_ = pal.SetColourAt(0, gg.Colour(0x0ABC))
+	_ = pal.SetColourAt(31, gg.Colour(0x0F01))
+
+	data := pal.Bytes()
+	if data[0] != 0xBC || data[1] != 0x0A {
+		t.Errorf("expected the first colour as a little-endian word, got $%02X $%02X", data[0], data[1])
+	}
+	if data[62] != 0x01 || data[63] != 0x0F {
+		t.Errorf("expected the last colour as a little-endian word, got $%02X $%02X", data[62], data[63])
+	}
+	for i := 2; i < 62; i++ {
+		if data[i] != 0 {
+			t.Errorf("expected unset palette byte #%02d to be a zero value, got $%02X", i, data[i])
+		}
+	}
+}
+
+func TestPalette_UnmarshalBinary(t *testing.T) {
+	pal := gg.Palette{}
+	_ = pal.SetColourAt(0, gg.Colour(0x0ABC))
+	_ = pal.SetColourAt(31, gg.Colour(0x0FFF))
+
+	data, err := pal.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	decoded := gg.Palette{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if decoded.Bytes() != pal.Bytes() {
+		t.Errorf("expected decoded palette to match the original, got %v", decoded.Bytes())
+	}
+	if _, err := decoded.ColourAt(15); err != nil {
+		t.Errorf("expected all decoded colours to be set, got error: %q", err)
+	}
+
+	t.Run("with only the background palette", func(t *testing.T) {
+		decoded := gg.Palette{}
+		if err := decoded.UnmarshalBinary(data[:32]); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if _, err := decoded.ColourAt(16); err == nil {
+			t.Error("expected sprite palette colours to be unset")
+		}
+	})
+
+	t.Run("with invalid colour", func(t *testing.T) {
+		err := decoded.UnmarshalBinary(append([]byte{0xBC, 0x1A}, data[2:]...))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "palette error: invalid GG colour $1ABC at index 0" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+
+	t.Run("with invalid size", func(t *testing.T) {
+		if err := decoded.UnmarshalBinary(data[:16]); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
@@ -2,6 +2,7 @@ module github.com/mrcook/smstilemap
 
 go 1.22
 
-require github.com/disintegration/imaging v1.6.2
-
-require golang.org/x/image v0.15.0 // indirect
+require (
+	github.com/disintegration/imaging v1.6.2
+	golang.org/x/image v0.15.0
+)
@@ -0,0 +1,143 @@
+package match
+
+import (
+	"math"
+	"sync"
+
+	"github.com/mrcook/smstilemap/gg"
+	"github.com/mrcook/smstilemap/sms"
+)
+
+// CIEDE2000 picks the colour with the smallest CIEDE2000 colour difference,
+// measured in the CIELAB colour space, which is close to how different two
+// colours look. It is the slowest of the matchers.
+type CIEDE2000 struct{}
+
+func (CIEDE2000) SMS(r, g, b uint8) sms.Colour {
+	lab, table := LabFromRGB(r, g, b), smsLab()
+	return sms.AllColours[nearest(len(table), func(i int) float64 { return DeltaE2000(lab, table[i]) })].Index
+}
+
+func (CIEDE2000) GG(r, g, b uint8) gg.Colour {
+	lab, table := LabFromRGB(r, g, b), ggLab()
+	return gg.AllColours[nearest(len(table), func(i int) float64 { return DeltaE2000(lab, table[i]) })].Index
+}
+
+// the CIELAB values of the console colours, in the order of their colour tables
+var (
+	smsLab = sync.OnceValue(func() (table []Lab) {
+		for _, c := range sms.AllColours {
+			table = append(table, LabFromRGB(c.R, c.G, c.B))
+		}
+		return
+	})
+	ggLab = sync.OnceValue(func() (table []Lab) {
+		for _, c := range gg.AllColours {
+			table = append(table, LabFromRGB(c.R, c.G, c.B))
+		}
+		return
+	})
+)
+
+// Lab is a colour in the CIELAB colour space, with the D65 white point.
+type Lab struct {
+	L, A, B float64
+}
+
+// LabFromRGB converts an sRGB colour to CIELAB.
+func LabFromRGB(r, g, b uint8) Lab {
+	lr, lg, lb := linearRGB(r), linearRGB(g), linearRGB(b)
+
+	// to CIE XYZ, relative to the D65 reference white
+	x := (0.4124564*lr + 0.3575761*lg + 0.1804375*lb) / 0.95047
+	y := 0.2126729*lr + 0.7151522*lg + 0.0721750*lb
+	z := (0.0193339*lr + 0.1191920*lg + 0.9503041*lb) / 1.08883
+
+	fx, fy, fz := labF(x), labF(y), labF(z)
+	return Lab{L: 116*fy - 16, A: 500 * (fx - fy), B: 200 * (fy - fz)}
+}
+
+// returns the linear value of an sRGB channel, in the range 0..1
+func linearRGB(c uint8) float64 {
+	v := float64(c) / 255
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func labF(t float64) float64 {
+	const delta = 6.0 / 29
+	if t > delta*delta*delta {
+		return math.Cbrt(t)
+	}
+	return t/(3*delta*delta) + 4.0/29
+}
+
+// DeltaE2000 returns the CIEDE2000 colour difference between two CIELAB
+// colours, following "The CIEDE2000 Color-Difference Formula" by Sharma, Wu,
+// and Dalal, with the weighting factors kL, kC, and kH set to 1.
+func DeltaE2000(c1, c2 Lab) float64 {
+	const pow25to7 = 6103515625.0 // 25^7
+
+	meanC := (math.Hypot(c1.A, c1.B) + math.Hypot(c2.A, c2.B)) / 2
+	g := 0.5 * (1 - math.Sqrt(math.Pow(meanC, 7)/(math.Pow(meanC, 7)+pow25to7)))
+
+	a1, a2 := (1+g)*c1.A, (1+g)*c2.A
+	cp1, cp2 := math.Hypot(a1, c1.B), math.Hypot(a2, c2.B)
+	hp1, hp2 := hueAngle(c1.B, a1), hueAngle(c2.B, a2)
+
+	deltaL := c2.L - c1.L
+	deltaC := cp2 - cp1
+	deltaH := 0.0
+	if cp1*cp2 != 0 {
+		dh := hp2 - hp1
+		if dh > 180 {
+			dh -= 360
+		} else if dh < -180 {
+			dh += 360
+		}
+		deltaH = 2 * math.Sqrt(cp1*cp2) * math.Sin(radians(dh/2))
+	}
+
+	meanL := (c1.L + c2.L) / 2
+	meanCp := (cp1 + cp2) / 2
+	meanH := hp1 + hp2
+	if cp1*cp2 != 0 {
+		if math.Abs(hp1-hp2) <= 180 {
+			meanH /= 2
+		} else if meanH < 360 {
+			meanH = (meanH + 360) / 2
+		} else {
+			meanH = (meanH - 360) / 2
+		}
+	}
+
+	t := 1 - 0.17*math.Cos(radians(meanH-30)) + 0.24*math.Cos(radians(2*meanH)) +
+		0.32*math.Cos(radians(3*meanH+6)) - 0.20*math.Cos(radians(4*meanH-63))
+	deltaTheta := 30 * math.Exp(-math.Pow((meanH-275)/25, 2))
+	rc := 2 * math.Sqrt(math.Pow(meanCp, 7)/(math.Pow(meanCp, 7)+pow25to7))
+	sl := 1 + 0.015*math.Pow(meanL-50, 2)/math.Sqrt(20+math.Pow(meanL-50, 2))
+	sc := 1 + 0.045*meanCp
+	sh := 1 + 0.015*meanCp*t
+	rt := -math.Sin(radians(2*deltaTheta)) * rc
+
+	l, c, h := deltaL/sl, deltaC/sc, deltaH/sh
+	return math.Sqrt(l*l + c*c + h*h + rt*c*h)
+}
+
+// returns the hue angle in degrees, 0..360
+func hueAngle(b, a float64) float64 {
+	if a == 0 && b == 0 {
+		return 0
+	}
+	h := math.Atan2(b, a) * 180 / math.Pi
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+func radians(degrees float64) float64 {
+	return degrees * math.Pi / 180
+}
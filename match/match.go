@@ -0,0 +1,103 @@
+// Package match finds the console colour nearest to an RGB colour, for the
+// Master System and the Game Gear, using one of several strategies.
+package match
+
+import (
+	"math"
+
+	"github.com/mrcook/smstilemap/gg"
+	"github.com/mrcook/smstilemap/sms"
+)
+
+// Matcher returns the console colour nearest to an 8-bit RGB colour.
+type Matcher interface {
+	SMS(r, g, b uint8) sms.Colour
+	GG(r, g, b uint8) gg.Colour
+}
+
+var (
+	_ Matcher = Threshold{}
+	_ Matcher = Euclidean{}
+	_ Matcher = Weighted{}
+	_ Matcher = CIEDE2000{}
+)
+
+// Threshold quantises each RGB channel independently, using the fixed
+// thresholds of the sms and gg packages. It is fast, but a colour between two
+// console colours may be matched to the further one, as the SMS thresholds are
+// not the halfway points.
+type Threshold struct{}
+
+func (Threshold) SMS(r, g, b uint8) sms.Colour {
+	return sms.ColourDataForNearestRGB(r, g, b).Index
+}
+
+func (Threshold) GG(r, g, b uint8) gg.Colour {
+	return gg.ColourDataForNearestRGB(r, g, b).Index
+}
+
+// Euclidean picks the colour with the smallest straight line distance in the
+// RGB colour space.
+type Euclidean struct{}
+
+func (Euclidean) SMS(r, g, b uint8) sms.Colour {
+	return sms.AllColours[nearestSMS(r, g, b, euclidean)].Index
+}
+
+func (Euclidean) GG(r, g, b uint8) gg.Colour {
+	return gg.AllColours[nearestGG(r, g, b, euclidean)].Index
+}
+
+// Weighted picks the colour with the smallest RGB distance, weighting each
+// channel by how sensitive the eye is to it, with the red and blue weights
+// depending on the amount of red (the "redmean" approximation).
+type Weighted struct{}
+
+func (Weighted) SMS(r, g, b uint8) sms.Colour {
+	return sms.AllColours[nearestSMS(r, g, b, weighted)].Index
+}
+
+func (Weighted) GG(r, g, b uint8) gg.Colour {
+	return gg.AllColours[nearestGG(r, g, b, weighted)].Index
+}
+
+// a distance between two RGB colours, where only the order of the results
+// matters, so the square roots are not needed.
+type distanceFunc func(r1, g1, b1, r2, g2, b2 uint8) float64
+
+func euclidean(r1, g1, b1, r2, g2, b2 uint8) float64 {
+	dr, dg, db := float64(r1)-float64(r2), float64(g1)-float64(g2), float64(b1)-float64(b2)
+	return dr*dr + dg*dg + db*db
+}
+
+func weighted(r1, g1, b1, r2, g2, b2 uint8) float64 {
+	mean := (float64(r1) + float64(r2)) / 2
+	dr, dg, db := float64(r1)-float64(r2), float64(g1)-float64(g2), float64(b1)-float64(b2)
+	return (2+mean/256)*dr*dr + 4*dg*dg + (2+(255-mean)/256)*db*db
+}
+
+func nearestSMS(r, g, b uint8, distance distanceFunc) int {
+	return nearest(len(sms.AllColours), func(i int) float64 {
+		c := sms.AllColours[i]
+		return distance(r, g, b, c.R, c.G, c.B)
+	})
+}
+
+func nearestGG(r, g, b uint8, distance distanceFunc) int {
+	return nearest(len(gg.AllColours), func(i int) float64 {
+		c := gg.AllColours[i]
+		return distance(r, g, b, c.R, c.G, c.B)
+	})
+}
+
+// returns the index of the candidate with the smallest distance, the first
+// one when several are equally near.
+func nearest(count int, distance func(i int) float64) int {
+	best, bestDistance := 0, math.Inf(1)
+	for i := 0; i < count; i++ {
+		if d := distance(i); d < bestDistance {
+			best, bestDistance = i, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,104 @@
+package match_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/mrcook/smstilemap/gg"
+	"github.com/mrcook/smstilemap/match"
+	"github.com/mrcook/smstilemap/sms"
+)
+
+var matchers = map[string]match.Matcher{
+	"threshold": match.Threshold{},
+	"euclidean": match.Euclidean{},
+	"weighted":  match.Weighted{},
+	"ciede2000": match.CIEDE2000{},
+}
+
+func TestMatcher_ExactColours(t *testing.T) {
+	for name, m := range matchers {
+		for _, c := range sms.AllColours {
+			if got := m.SMS(c.R, c.G, c.B); got != c.Index {
+				t.Errorf("%s: expected SMS colour %s to match itself, got %s", name, c.HTML, got.HTML())
+			}
+		}
+		for _, c := range gg.AllColours[:256] {
+			if got := m.GG(c.R, c.G, c.B); got != c.Index {
+				t.Errorf("%s: expected GG colour %s to match itself, got %s", name, c.HTML, got.HTML())
+			}
+		}
+	}
+}
+
+func TestMatcher_NearestSMS(t *testing.T) {
+	table := []struct {
+		matcher string
+		r, g, b uint8
+		want    string
+	}{
+		{"threshold", 48, 0, 0, "#000000"},
+		{"euclidean", 48, 0, 0, "#550000"},
+		{"threshold", 208, 208, 208, "#FFFFFF"},
+		{"euclidean", 208, 208, 208, "#AAAAAA"},
+		{"weighted", 48, 0, 0, "#550000"},
+		{"ciede2000", 48, 0, 0, "#550000"},
+	}
+
+	for _, test := range table {
+		t.Run(fmt.Sprintf("%s (%d,%d,%d)", test.matcher, test.r, test.g, test.b), func(t *testing.T) {
+			if got := matchers[test.matcher].SMS(test.r, test.g, test.b); got.HTML() != test.want {
+				t.Errorf("expected %s, got %s", test.want, got.HTML())
+			}
+		})
+	}
+}
+
+func TestMatcher_NearestGG(t *testing.T) {
+	want := map[string]string{
+		"threshold": "#1111FF",
+		"euclidean": "#1111FF",
+		"weighted":  "#1111FF",
+		"ciede2000": "#0011FF", // the dark red is hard to see next to the bright blue
+	}
+	for name, m := range matchers {
+		if got := m.GG(0x12, 0x10, 0xFA); got.HTML() != want[name] {
+			t.Errorf("%s: expected %s, got %s", name, want[name], got.HTML())
+		}
+	}
+}
+
+func TestDeltaE2000(t *testing.T) {
+	// test data from "The CIEDE2000 Color-Difference Formula" by Sharma, Wu, and Dalal
+	table := []struct {
+		c1, c2 match.Lab
+		want   float64
+	}{
+		{match.Lab{L: 50, A: 2.6772, B: -79.7751}, match.Lab{L: 50, A: 0, B: -82.7485}, 2.0425},
+		{match.Lab{L: 50, A: 0, B: 0}, match.Lab{L: 50, A: -1, B: 2}, 2.3669},
+		{match.Lab{L: 50, A: 2.5, B: 0}, match.Lab{L: 73, A: 25, B: -18}, 27.1492},
+		{match.Lab{L: 60.2574, A: -34.0099, B: 36.2677}, match.Lab{L: 60.4626, A: -34.1751, B: 39.4387}, 1.2644},
+		{match.Lab{L: 2.0776, A: 0.0795, B: -1.1350}, match.Lab{L: 0.9033, A: -0.0636, B: -0.5514}, 0.9082},
+	}
+
+	for i, test := range table {
+		if got := match.DeltaE2000(test.c1, test.c2); math.Abs(got-test.want) > 0.0001 {
+			t.Errorf("pair %d: expected %.4f, got %.4f", i+1, test.want, got)
+		}
+		if got := match.DeltaE2000(test.c2, test.c1); math.Abs(got-test.want) > 0.0001 {
+			t.Errorf("pair %d reversed: expected %.4f, got %.4f", i+1, test.want, got)
+		}
+	}
+}
+
+func TestLabFromRGB(t *testing.T) {
+	white := match.LabFromRGB(255, 255, 255)
+	if math.Abs(white.L-100) > 0.01 || math.Abs(white.A) > 0.01 || math.Abs(white.B) > 0.01 {
+		t.Errorf("expected white to be L=100, a=0, b=0, got %+v", white)
+	}
+	red := match.LabFromRGB(255, 0, 0)
+	if math.Abs(red.L-53.24) > 0.01 || math.Abs(red.A-80.09) > 0.01 || math.Abs(red.B-67.20) > 0.01 {
+		t.Errorf("expected red to be L=53.24, a=80.09, b=67.20, got %+v", red)
+	}
+}
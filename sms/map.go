@@ -0,0 +1,80 @@
+package sms
+
+import "fmt"
+
+// Map is a tilemap for a level larger than a single screen, with a width and
+// height of any number of tiles. The name table can only hold a 32x28 tile
+// area, so a scroll engine streams rows and columns (strips) of the map into
+// the name table as the screen scrolls, with the name table wrapping around
+// at its edges.
+//
+// As the tiles must all be held in VRAM together, a map may still only use
+// a maximum of 448 unique tiles.
+type Map struct {
+	width, height int // in 8x8 tiles
+	table         []Word
+}
+
+// NewMap returns a new map of the given size, in tiles.
+func NewMap(width, height int) *Map {
+	if width < 0 {
+		width = 0
+	}
+	if height < 0 {
+		height = 0
+	}
+	return &Map{
+		width:  width,
+		height: height,
+		table:  make([]Word, width*height),
+	}
+}
+
+// Width returns the number of columns in the map.
+func (m *Map) Width() int {
+	return m.width
+}
+
+// Height returns the number of rows in the map.
+func (m *Map) Height() int {
+	return m.height
+}
+
+// Get returns the tile info from the requested location.
+func (m *Map) Get(row, col int) (*Word, error) {
+	if row < 0 || col < 0 || row >= m.height || col >= m.width {
+		return nil, fmt.Errorf("get map out of bounds indexing, max is (%d,%d), requested (%d,%d)", m.height-1, m.width-1, row, col)
+	}
+	return &m.table[row*m.width+col], nil
+}
+
+// Set adds the tile info at the requested location.
+func (m *Map) Set(row, col int, word Word) error {
+	if row < 0 || col < 0 || row >= m.height || col >= m.width {
+		return fmt.Errorf("set map out of bounds indexing, max is (%d,%d), requested (%d,%d)", m.height-1, m.width-1, row, col)
+	}
+	m.table[row*m.width+col] = word
+	return nil
+}
+
+// Words returns the map as a single slice of 16-bit values, one row after the
+// other. Each row is a strip for streaming into the name table when
+// scrolling vertically.
+func (m *Map) Words() (words []uint16) {
+	for _, word := range m.table {
+		words = append(words, word.ToUint())
+	}
+	return
+}
+
+// ColumnWords returns the map as a single slice of 16-bit values, one column
+// after the other. Each column is a strip for streaming into the name table
+// when scrolling horizontally.
+func (m *Map) ColumnWords() (words []uint16) {
+	for col := 0; col < m.width; col++ {
+		for row := 0; row < m.height; row++ {
+			words = append(words, m.table[row*m.width+col].ToUint())
+		}
+	}
+	return
+}
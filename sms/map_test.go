@@ -0,0 +1,89 @@
+package sms_test
+
+import (
+	"testing"
+
+	"github.com/mrcook/smstilemap/sms"
+)
+
+func TestMap_Size(t *testing.T) {
+	m := sms.NewMap(100, 30)
+	if m.Width() != 100 {
+		t.Errorf("expected map width to be 100, got %d", m.Width())
+	}
+	if m.Height() != 30 {
+		t.Errorf("expected map height to be 30, got %d", m.Height())
+	}
+}
+
+func TestMap_Get(t *testing.T) {
+	m := sms.NewMap(40, 2)
+	_ = m.Set(1, 39, sms.Word{TileNumber: 447})
+
+	t.Run("with valid data", func(t *testing.T) {
+		got, err := m.Get(1, 39)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if got.TileNumber != 447 {
+			t.Errorf("expected to get correct data, got tile id %d", got.TileNumber)
+		}
+	})
+
+	t.Run("with out of bounds indexing", func(t *testing.T) {
+		_, err := m.Get(2, 39)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "get map out of bounds indexing, max is (1,39), requested (2,39)" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
+
+func TestMap_Set(t *testing.T) {
+	m := sms.NewMap(40, 2)
+
+	t.Run("with out of bounds indexing", func(t *testing.T) {
+		err := m.Set(0, 40, sms.Word{})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "set map out of bounds indexing, max is (1,39), requested (0,40)" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
+
+func TestMap_Words(t *testing.T) {
+	m := sms.NewMap(3, 2)
+	_ = m.Set(0, 1, sms.Word{TileNumber: 1})
+	_ = m.Set(1, 0, sms.Word{TileNumber: 2})
+	_ = m.Set(1, 2, sms.Word{HorizontalFlip: true, TileNumber: 3})
+
+	t.Run("as rows", func(t *testing.T) {
+		want := []uint16{0, 1, 0, 2, 0, 0b0000001000000011}
+		got := m.Words()
+		if len(got) != len(want) {
+			t.Fatalf("expected %d words, got %d", len(want), len(got))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("expected word #%d to be %016b, got %016b", i, want[i], got[i])
+			}
+		}
+	})
+
+	t.Run("as columns", func(t *testing.T) {
+		want := []uint16{0, 2, 1, 0, 0, 0b0000001000000011}
+		got := m.ColumnWords()
+		if len(got) != len(want) {
+			t.Fatalf("expected %d words, got %d", len(want), len(got))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("expected word #%d to be %016b, got %016b", i, want[i], got[i])
+			}
+		}
+	})
+}
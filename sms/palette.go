@@ -1,6 +1,9 @@
 package sms
 
-import "fmt"
+import (
+	"encoding"
+	"fmt"
+)
 
 // Colour RAM stores two palettes of 16 colours each.
 // CRAM is accessible on the SMS using a base address of $C000.
@@ -22,10 +25,18 @@ import "fmt"
 // So, for example, if there was a little blue, no green and a lot of red, the
 // colour would be %00010011.
 
-const paletteSize = 32
+const (
+	paletteSize        = 32
+	PaletteColourCount = 16 // number of colours in each of the two palettes
+)
 
 var PaletteErr = fmt.Errorf("palette error")
 
+var (
+	_ encoding.BinaryMarshaler   = (*Palette)(nil)
+	_ encoding.BinaryUnmarshaler = (*Palette)(nil)
+)
+
 // PaletteId references one of the possible 32 palette colours.
 type PaletteId uint8
 
@@ -98,3 +109,26 @@ func (p *Palette) Bytes() (colours [32]uint8) {
 	}
 	return
 }
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning
+// the 32 bytes of palette data as stored in CRAM.
+func (p *Palette) MarshalBinary() ([]byte, error) {
+	data := p.Bytes()
+	return data[:], nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface,
+// decoding 32 bytes of CRAM data, or 16 bytes for the background palette only.
+// Any existing colours are replaced. As the VDP ignores bits 6 and 7 of each
+// CRAM byte, they are cleared rather than rejected.
+func (p *Palette) UnmarshalBinary(data []byte) error {
+	if len(data) != paletteSize && len(data) != PaletteColourCount {
+		return fmt.Errorf("%w: invalid data size, expected %d or %d bytes, got %d", PaletteErr, PaletteColourCount, paletteSize, len(data))
+	}
+
+	*p = Palette{}
+	for i, b := range data {
+		p.colours[i] = entry{colour: Colour(b & 0b00111111), enabled: true}
+	}
+	return nil
+}
@@ -10,7 +10,7 @@ func TestPalette_ColourAt(t *testing.T) {
 	pal := sms.Palette{}
 
 	t.Run("with valid position", func(t *testing.T) {
-		colour := sms.ColourDataForRGB(170, 170, 170)
+		colour := sms.ColourDataForRGB(170, 170, 170).Index
 		_ = pal.SetColourAt(31, colour)
 
 		want := colour.SMS()
@@ -45,7 +45,7 @@ func TestPalette_ColourAt(t *testing.T) {
 func TestPalette_SetColourAt(t *testing.T) {
 	t.Run("with valid position", func(t *testing.T) {
 		pal := sms.Palette{}
-		colour := sms.ColourDataForRGB(85, 85, 85)
+		colour := sms.ColourDataForRGB(85, 85, 85).Index
 		if err := pal.SetColourAt(5, colour); err != nil {
 			t.Fatalf("unexpected error, got '%s", err)
 		}
@@ -195,3 +195,44 @@ func TestPalette_Bytes(t *testing.T) {
 		}
 	})
 }
+
+func TestPalette_UnmarshalBinary(t *testing.T) {
+	pal := sms.Palette{}
+	_ = pal.SetColourAt(0, sms.Colour(0b00101010))
+	_ = pal.SetColourAt(31, sms.Colour(0b00111111))
+
+	data, err := pal.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	decoded := sms.Palette{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if decoded.Bytes() != pal.Bytes() {
+		t.Errorf("expected decoded palette to match the original, got %v", decoded.Bytes())
+	}
+	if _, err := decoded.ColourAt(15); err != nil {
+		t.Errorf("expected all decoded colours to be set, got error: %q", err)
+	}
+
+	t.Run("with only the background palette", func(t *testing.T) {
+		decoded := sms.Palette{}
+		if err := decoded.UnmarshalBinary(data[:16]); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if _, err := decoded.ColourAt(16); err == nil {
+			t.Error("expected sprite palette colours to be unset")
+		}
+	})
+
+	t.Run("with the unused colour bits set", func(t *testing.T) {
+		if err := decoded.UnmarshalBinary(append([]byte{0b11010101}, data[1:]...)); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if colour, _ := decoded.ColourAt(0); colour != sms.Colour(0b00010101) {
+			t.Errorf("expected bits 6 and 7 to be cleared, got %08b", colour)
+		}
+	})
+}
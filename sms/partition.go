@@ -0,0 +1,166 @@
+package sms
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Each entry in the tilemap selects one of the two 16 colour palettes, with
+// the tile pixels indexing colours 0-15 of that palette. This allows an image
+// to use up to 32 colours, as long as all the colours of each tile can be
+// found in just one of the two palettes.
+//
+// The partitioner places tiles using a greedy approach: tiles with the most
+// colours are placed first, each into the palette requiring the fewest new
+// colours to be added. When both palettes are equally suitable the background
+// palette is used.
+
+// PartitionError is returned when one or more tiles can not be assigned to
+// either of the palettes.
+type PartitionError struct {
+	Tiles []int // index positions of the tiles that could not be placed
+}
+
+func (e *PartitionError) Error() string {
+	return fmt.Sprintf("%s: %d tile(s) do not fit in either 16 colour palette", PaletteErr, len(e.Tiles))
+}
+
+// Unwrap returns the general palette error.
+func (e *PartitionError) Unwrap() error {
+	return PaletteErr
+}
+
+// Partition is the result of assigning tiles to the two palettes.
+// The colour type is generic so that other VDP colour types (e.g. Game Gear)
+// can be partitioned in the same way.
+type Partition[C comparable] struct {
+	Palettes [2][]C // colours of each palette, in palette index order
+	Selected []int  // the palette (0 or 1) selected for each tile
+}
+
+// PaletteIdFor returns the index position (0-15) of the colour within the
+// palette selected for the tile.
+func (p *Partition[C]) PaletteIdFor(tile int, colour C) (PaletteId, error) {
+	if tile < 0 || tile >= len(p.Selected) {
+		return 0, fmt.Errorf("%w: tile index out of bounds, got %d", PaletteErr, tile)
+	}
+	for i, c := range p.Palettes[p.Selected[tile]] {
+		if c == colour {
+			return PaletteId(i), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: colour not found in palette %d for tile %d", PaletteErr, p.Selected[tile], tile)
+}
+
+// PartitionOptions can be used to constrain the palette partitioning.
+type PartitionOptions[C comparable] struct {
+	// Seed colours are placed at the start of each palette, in the given order.
+	Seed [2][]C
+
+	// FirstColour lists tiles, by their index position, which require the
+	// given colour to be at index 0 of their palette, such as the background
+	// colour of tiles with the priority bit set.
+	FirstColour map[int]C
+
+	// Fixed uses the Seed colours as the complete palettes, keeping their
+	// index positions (including any duplicates), so no colours are added
+	// and tiles using other colours can not be placed.
+	Fixed bool
+}
+
+// PartitionColours assigns each tile, given as the list of colours it uses,
+// to one of the two palettes. Colours are added to the palettes in the order
+// they are first used by the placed tiles.
+//
+// When one or more tiles can not be placed, a *PartitionError is returned
+// along with the partial result.
+func PartitionColours[C comparable](tiles [][]C) (*Partition[C], error) {
+	return PartitionColoursWith(tiles, PartitionOptions[C]{})
+}
+
+// PartitionColoursWith assigns each tile to one of the two palettes, as with
+// PartitionColours, while applying the given constraints.
+func PartitionColoursWith[C comparable](tiles [][]C, opts PartitionOptions[C]) (*Partition[C], error) {
+	p := &Partition[C]{Selected: make([]int, len(tiles))}
+	for pal, seed := range opts.Seed {
+		if opts.Fixed {
+			p.Palettes[pal] = append(p.Palettes[pal], seed...)
+		} else {
+			p.Palettes[pal] = append(p.Palettes[pal], uniqueColours(seed)...)
+		}
+	}
+
+	unique := make([][]C, len(tiles))
+	order := make([]int, len(tiles))
+	for i, colours := range tiles {
+		unique[i] = uniqueColours(colours)
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return len(unique[order[a]]) > len(unique[order[b]])
+	})
+
+	var failed []int
+	for _, tile := range order {
+		selected := -1
+		var additions []C
+
+		first, hasFirst := opts.FirstColour[tile]
+
+		for pal := range p.Palettes {
+			if hasFirst && (len(p.Palettes[pal]) == 0 || p.Palettes[pal][0] != first) {
+				continue
+			}
+			missing := missingColours(p.Palettes[pal], unique[tile])
+			if len(p.Palettes[pal])+len(missing) > PaletteColourCount || (opts.Fixed && len(missing) > 0) {
+				continue
+			}
+			if selected < 0 || len(missing) < len(additions) {
+				selected = pal
+				additions = missing
+			}
+		}
+
+		if selected < 0 {
+			failed = append(failed, tile)
+			continue
+		}
+		p.Palettes[selected] = append(p.Palettes[selected], additions...)
+		p.Selected[tile] = selected
+	}
+
+	if len(failed) > 0 {
+		sort.Ints(failed)
+		return p, &PartitionError{Tiles: failed}
+	}
+	return p, nil
+}
+
+// returns the colours with any duplicates removed, keeping their order.
+func uniqueColours[C comparable](colours []C) (unique []C) {
+	seen := make(map[C]bool, len(colours))
+	for _, c := range colours {
+		if !seen[c] {
+			seen[c] = true
+			unique = append(unique, c)
+		}
+	}
+	return
+}
+
+// returns the colours not yet present in the palette.
+func missingColours[C comparable](palette, colours []C) (missing []C) {
+	for _, c := range colours {
+		found := false
+		for _, pc := range palette {
+			if pc == c {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, c)
+		}
+	}
+	return
+}
@@ -0,0 +1,184 @@
+package sms_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mrcook/smstilemap/sms"
+)
+
+func TestPartitionColours(t *testing.T) {
+	t.Run("tiles sharing colours use the background palette", func(t *testing.T) {
+		tiles := [][]sms.Colour{{1, 2, 3}, {3, 2}, {1, 1, 1}}
+
+		p, err := sms.PartitionColours(tiles)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		for i, pal := range p.Selected {
+			if pal != 0 {
+				t.Errorf("expected tile %d to use the background palette, got %d", i, pal)
+			}
+		}
+		if len(p.Palettes[0]) != 3 || len(p.Palettes[1]) != 0 {
+			t.Errorf("expected 3 background colours only, got %v", p.Palettes)
+		}
+	})
+
+	t.Run("up to 32 colours are split over both palettes", func(t *testing.T) {
+		var first, second []sms.Colour
+		for i := 0; i < 16; i++ {
+			first = append(first, sms.Colour(i))
+			second = append(second, sms.Colour(i+16))
+		}
+		tiles := [][]sms.Colour{first, second, {sms.Colour(20)}}
+
+		p, err := sms.PartitionColours(tiles)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if p.Selected[0] != 0 || p.Selected[1] != 1 {
+			t.Errorf("expected tiles to be split over both palettes, got %v", p.Selected)
+		}
+		if p.Selected[2] != 1 {
+			t.Errorf("expected the single colour tile to use the sprite palette, got %d", p.Selected[2])
+		}
+
+		pid, err := p.PaletteIdFor(2, sms.Colour(20))
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if pid != 4 {
+			t.Errorf("expected colour to be re-indexed within the palette, got %d", pid)
+		}
+	})
+
+	t.Run("when a tile does not fit in either palette", func(t *testing.T) {
+		var first, second, third []sms.Colour
+		for i := 0; i < 16; i++ {
+			first = append(first, sms.Colour(i))
+			second = append(second, sms.Colour(i+16))
+		}
+		third = []sms.Colour{0, 16}
+
+		_, err := sms.PartitionColours([][]sms.Colour{first, second, third})
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		var partitionErr *sms.PartitionError
+		if !errors.As(err, &partitionErr) {
+			t.Fatalf("expected a partition error, got %T", err)
+		}
+		if len(partitionErr.Tiles) != 1 || partitionErr.Tiles[0] != 2 {
+			t.Errorf("expected tile #2 to be reported, got %v", partitionErr.Tiles)
+		}
+		if !errors.Is(err, sms.PaletteErr) {
+			t.Error("expected error to be a palette error")
+		}
+	})
+}
+
+func TestPartition_PaletteIdFor(t *testing.T) {
+	p, _ := sms.PartitionColours([][]sms.Colour{{5, 6}})
+
+	t.Run("when colour is not in the palette", func(t *testing.T) {
+		_, err := p.PaletteIdFor(0, sms.Colour(7))
+		if err == nil {
+			t.Fatal("expected an error")
+		} else if err.Error() != "palette error: colour not found in palette 0 for tile 0" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+
+	t.Run("when tile index is out of bounds", func(t *testing.T) {
+		_, err := p.PaletteIdFor(1, sms.Colour(5))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestPartitionColoursWith(t *testing.T) {
+	t.Run("seed colours are placed first", func(t *testing.T) {
+		opts := sms.PartitionOptions[sms.Colour]{
+			Seed: [2][]sms.Colour{{9}, {10}},
+		}
+		p, err := sms.PartitionColoursWith([][]sms.Colour{{1, 2}}, opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if p.Palettes[0][0] != 9 || p.Palettes[1][0] != 10 {
+			t.Errorf("expected seed colours at index 0, got %v", p.Palettes)
+		}
+		pid, _ := p.PaletteIdFor(0, sms.Colour(2))
+		if pid != 2 {
+			t.Errorf("expected tile colours to follow the seed colours, got %d", pid)
+		}
+	})
+
+	t.Run("tiles requiring a first colour use the matching palette", func(t *testing.T) {
+		opts := sms.PartitionOptions[sms.Colour]{
+			Seed:        [2][]sms.Colour{{9}, {10}},
+			FirstColour: map[int]sms.Colour{1: 10},
+		}
+		p, err := sms.PartitionColoursWith([][]sms.Colour{{1, 10}, {1, 10}}, opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if p.Selected[1] != 1 {
+			t.Errorf("expected tile to use the palette starting with its first colour, got %d", p.Selected[1])
+		}
+		pid, _ := p.PaletteIdFor(1, sms.Colour(10))
+		if pid != 0 {
+			t.Errorf("expected the first colour to be at index 0, got %d", pid)
+		}
+	})
+
+	t.Run("when no palette starts with the first colour", func(t *testing.T) {
+		opts := sms.PartitionOptions[sms.Colour]{
+			FirstColour: map[int]sms.Colour{0: 3},
+		}
+		_, err := sms.PartitionColoursWith([][]sms.Colour{{3}}, opts)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+	t.Run("fixed palettes keep their colours and order", func(t *testing.T) {
+		opts := sms.PartitionOptions[sms.Colour]{
+			Seed:  [2][]sms.Colour{{0, 5, 0, 6}, {0, 7}},
+			Fixed: true,
+		}
+		p, err := sms.PartitionColoursWith([][]sms.Colour{{6, 0}, {7}}, opts)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if len(p.Palettes[0]) != 4 || len(p.Palettes[1]) != 2 {
+			t.Errorf("expected the fixed palettes to be unchanged, got %v", p.Palettes)
+		}
+		if p.Selected[0] != 0 || p.Selected[1] != 1 {
+			t.Errorf("expected the tiles to use palettes 0 and 1, got %v", p.Selected)
+		}
+		pid, _ := p.PaletteIdFor(0, sms.Colour(6))
+		if pid != 3 {
+			t.Errorf("expected the colour at its fixed index 3, got %d", pid)
+		}
+	})
+
+	t.Run("fixed palettes do not add missing colours", func(t *testing.T) {
+		opts := sms.PartitionOptions[sms.Colour]{
+			Seed:  [2][]sms.Colour{{1, 2}, {3}},
+			Fixed: true,
+		}
+		p, err := sms.PartitionColoursWith([][]sms.Colour{{1}, {1, 3}}, opts)
+		var partitionErr *sms.PartitionError
+		if !errors.As(err, &partitionErr) {
+			t.Fatalf("expected a partition error, got %v", err)
+		}
+		if len(partitionErr.Tiles) != 1 || partitionErr.Tiles[0] != 1 {
+			t.Errorf("expected tile 1 to fail, got %v", partitionErr.Tiles)
+		}
+		if len(p.Palettes[0]) != 2 || len(p.Palettes[1]) != 1 {
+			t.Errorf("expected no colours to be added, got %v", p.Palettes)
+		}
+	})
+}
@@ -22,6 +22,9 @@
 //   $2000 ---------------------------------------------------------------
 //         Sprite/tile patterns, 0..255
 //   $0000 ---------------------------------------------------------------
+//
+// Use a VRAMLayout to describe a different memory map, such as reserving a
+// block of tiles for a font.
 package sms
 
 import (
@@ -32,6 +35,7 @@ const (
 	ScreenWidth          = 256 // screen width in pixels
 	ScreenHeight         = 192 // screen height in pixels
 	ExtendedScreenHeight = 224 // extended 'mode 4' screen height in pixels on the SMS
+	LargeScreenHeight    = 240 // extended 'mode 4' 240-line screen height in pixels (PAL only)
 	MaxColourCount       = 64  // maximum colours the SMS supports
 	MaxTileCount         = 448 // maximum number of tiles the VDP can store
 )
@@ -39,7 +43,10 @@ const (
 type SMS struct {
 	// The Character generator (sprite/tile patterns) is 14 KB in size.
 	// Each tile occupies 32 bytes, allowing up to 448 unique tiles to be stored.
-	characters [MaxTileCount]*Tile
+	// When the name table and SAT are moved, sprite tiles may use up to 512.
+	characters [MaxTileNumber]*Tile
+	tileOffset int // first tile number used when adding tiles
+	tileLimit  int // tiles are added below this number, default: MaxTileCount
 
 	// The Screen Map can hold the positions of the 786 tiles (896 in the
 	// extended mode 4) and is 1792 bytes in size. Each entry is 2-bytes wide
@@ -52,8 +59,7 @@ type SMS struct {
 	// similar to the background layer, except each sprite contain two
 	// additional values representing the X/Y coordinates (x,y coords, tile ID).
 	// For the majority of cases the table is stored at VRAM address $3F00.
-	// NOTE: probably not needed in this library.
-	sat [256]uint8
+	sat [MaxSpriteCount]*Sprite
 
 	// Palette of 32 colours (2x16) used for the background and sprite palettes.
 	// Accessed using a base address of $C000.
@@ -70,9 +76,14 @@ func (s *SMS) WidthInTiles() int {
 	return s.nameTable.Width()
 }
 
-// HeightInPixels returns the maximum screen height in pixels.
+// SetScreenHeight sets the 'mode 4' screen height in pixels: 192, 224, or 240.
+func (s *SMS) SetScreenHeight(height int) error {
+	return s.nameTable.SetScreenHeight(height)
+}
+
+// HeightInPixels returns the screen height in pixels for the current screen mode.
 func (s *SMS) HeightInPixels() int {
-	return ScreenHeight
+	return s.nameTable.Height() * tileSize
 }
 
 // ExtendedHeightInPixels returns the extended mode 4 screen height in pixels.
@@ -80,7 +91,7 @@ func (s *SMS) ExtendedHeightInPixels() int {
 	return ExtendedScreenHeight
 }
 
-// HeightInTiles returns the maximum screen height calculated as 8x8 tiles.
+// HeightInTiles returns the screen height, for the current screen mode, calculated as 8x8 tiles.
 func (s *SMS) HeightInTiles() int {
 	return s.nameTable.Height()
 }
@@ -90,6 +101,14 @@ func (s *SMS) ExtendedHeightInTiles() int {
 	return s.nameTable.ExtendedHeight()
 }
 
+// VRAMLayout returns the typical VRAM layout for the current screen mode.
+func (s *SMS) VRAMLayout() VRAMLayout {
+	if s.nameTable.Extended() {
+		return ExtendedVRAMLayout()
+	}
+	return DefaultVRAMLayout()
+}
+
 // TileAt returns a reference to the character generator tile using the given ID.
 func (s *SMS) TileAt(tileId uint16) (*Tile, error) {
 	if int(tileId) >= len(s.characters) {
@@ -98,10 +117,11 @@ func (s *SMS) TileAt(tileId uint16) (*Tile, error) {
 	return s.characters[tileId], nil
 }
 
-// AddTile adds a tile at the next available slot, returning its index position.
+// AddTile adds a tile at the next available slot, starting from the tile
+// offset, returning its index position.
 func (s *SMS) AddTile(t *Tile) (uint16, error) {
-	for i, chr := range s.characters {
-		if chr == nil {
+	for i := s.tileOffset; i < s.TileLimit(); i++ {
+		if s.characters[i] == nil {
 			s.characters[i] = t
 			return uint16(i), nil
 		}
@@ -109,6 +129,49 @@ func (s *SMS) AddTile(t *Tile) (uint16, error) {
 	return 0, fmt.Errorf("tile memory full")
 }
 
+// SetTileAt sets the tile at the given tile number, replacing any existing tile.
+func (s *SMS) SetTileAt(tileId uint16, t *Tile) error {
+	if int(tileId) >= len(s.characters) {
+		return fmt.Errorf("invalid tile ID")
+	}
+	s.characters[tileId] = t
+	return nil
+}
+
+// TileOffset returns the first tile number used when adding tiles.
+func (s *SMS) TileOffset() int {
+	return s.tileOffset
+}
+
+// SetTileOffset sets the first tile number used when adding tiles, so that
+// the tiles can be loaded into VRAM after others, such as a font or HUD.
+func (s *SMS) SetTileOffset(offset int) error {
+	if offset < 0 || offset >= s.TileLimit() {
+		return fmt.Errorf("invalid tile offset %d, must be within 0..%d", offset, s.TileLimit()-1)
+	}
+	s.tileOffset = offset
+	return nil
+}
+
+// TileLimit returns the tile number that tiles are added below, which is
+// 448 unless changed for sprite tiles.
+func (s *SMS) TileLimit() int {
+	if s.tileLimit == 0 {
+		return MaxTileCount
+	}
+	return s.tileLimit
+}
+
+// SetTileLimit sets the tile number that tiles are added below: 448 for the
+// typical VRAM layout, or up to 512 when the tiles are for sprites.
+func (s *SMS) SetTileLimit(limit int) error {
+	if limit <= s.tileOffset || limit > MaxTileNumber {
+		return fmt.Errorf("invalid tile limit %d, must be within %d..%d", limit, s.tileOffset+1, MaxTileNumber)
+	}
+	s.tileLimit = limit
+	return nil
+}
+
 // TilemapEntryAt returns the tile info from the tilemap for the requested location.
 func (s *SMS) TilemapEntryAt(row, col int) (*Word, error) {
 	return s.nameTable.Get(row, col)
@@ -119,6 +182,12 @@ func (s *SMS) AddTilemapEntryAt(row, col int, word Word) error {
 	return s.nameTable.Set(row, col, word)
 }
 
+// FillTilemap sets every tilemap entry to the given tile info, such as to
+// point the entries an image does not cover at one of its tiles.
+func (s *SMS) FillTilemap(word Word) {
+	s.nameTable.Fill(word)
+}
+
 // PaletteColour returns the colour for the given palette ID.
 func (s *SMS) PaletteColour(id PaletteId) (Colour, error) {
 	return s.palette.ColourAt(id)
@@ -130,6 +199,11 @@ func (s *SMS) PaletteIdForColour(colour Colour) (PaletteId, error) {
 	return s.palette.PaletteIdFor(colour)
 }
 
+// SetPaletteColourAt sets the palette colour at the given palette ID.
+func (s *SMS) SetPaletteColourAt(id PaletteId, colour Colour) error {
+	return s.palette.SetColourAt(id, colour)
+}
+
 // AddPaletteColour in the first available palette slot and return its index position.
 // When the palette already contains the colour, its position is returned.
 // An error is returned when the palette is full.
@@ -137,6 +211,43 @@ func (s *SMS) AddPaletteColour(colour Colour) (PaletteId, error) {
 	return s.palette.AddColour(colour)
 }
 
+// SpriteAt returns a copy of the sprite in the SAT using the given ID, so the
+// SAT can only be changed through AddSprite, which checks the Y position.
+func (s *SMS) SpriteAt(spriteId int) (Sprite, error) {
+	if spriteId < 0 || spriteId >= len(s.sat) {
+		return Sprite{}, fmt.Errorf("invalid sprite ID")
+	}
+	if s.sat[spriteId] == nil {
+		return Sprite{}, fmt.Errorf("no sprite found for requested sprite ID")
+	}
+	return *s.sat[spriteId], nil
+}
+
+// AddSprite adds a sprite at the next available slot, returning its index position.
+// In the 192-line mode, a sprite with a Y position of $D0 is rejected as the
+// VDP would treat it as the end of the sprite list.
+func (s *SMS) AddSprite(sprite Sprite) (int, error) {
+	if sprite.Y == SpriteTerminator && !s.nameTable.Extended() {
+		return 0, fmt.Errorf("sprite Y position $%02X is reserved as the sprite list terminator", SpriteTerminator)
+	}
+	for i, spr := range s.sat {
+		if spr == nil {
+			s.sat[i] = &sprite
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("sprite attribute table full")
+}
+
+// RemoveSprite removes the sprite with the given ID from the SAT.
+func (s *SMS) RemoveSprite(spriteId int) error {
+	if _, err := s.SpriteAt(spriteId); err != nil {
+		return err
+	}
+	s.sat[spriteId] = nil
+	return nil
+}
+
 // TileData returns all tiles as a slice of bytes.
 func (s *SMS) TileData() (data []uint8) {
 	for _, tile := range s.characters {
@@ -155,6 +266,33 @@ func (s *SMS) TilemapData() (data []uint16) {
 	return s.nameTable.Words()
 }
 
+// SATData returns the Sprite Attribute Table as it is laid out in VRAM.
+// Sprites are written in ID order, skipping any unused slots, and when fewer
+// than 64 sprites are defined the list is terminated with a $D0 Y value.
+//
+// The 224 and 240-line modes have no sprite list terminator, so instead the
+// unused entries are placed below the display.
+func (s *SMS) SATData() (data [satSize]uint8) {
+	count := 0
+	for _, sprite := range s.sat {
+		if sprite == nil {
+			continue
+		}
+		data[count] = sprite.Y
+		data[satXOffset+count*2] = sprite.X
+		data[satXOffset+count*2+1] = sprite.TileNumber
+		count++
+	}
+	if s.nameTable.Extended() {
+		for i := count; i < MaxSpriteCount; i++ {
+			data[i] = uint8(s.HeightInPixels())
+		}
+	} else if count < MaxSpriteCount {
+		data[count] = SpriteTerminator
+	}
+	return
+}
+
 // PaletteData returns the palette data as a slice of bytes.
 func (s *SMS) PaletteData() (data [32]uint8) {
 	return s.palette.Bytes()
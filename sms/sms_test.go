@@ -29,6 +29,32 @@ func TestSMS_ScreenDimensions(t *testing.T) {
 	}
 }
 
+func TestSMS_SetScreenHeight(t *testing.T) {
+	sega := sms.SMS{}
+	if err := sega.SetScreenHeight(224); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if sega.HeightInPixels() != 224 {
+		t.Errorf("expected screen height to be 224px, got %dpx", sega.HeightInPixels())
+	}
+	if sega.HeightInTiles() != 28 {
+		t.Errorf("expected screen tile height to be 28, got %d", sega.HeightInTiles())
+	}
+
+	t.Run("sprites may use a $D0 Y position", func(t *testing.T) {
+		if _, err := sega.AddSprite(sms.Sprite{Y: 0xD0}); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		data := sega.SATData()
+		if data[0] != 0xD0 {
+			t.Errorf("expected sprite Y position to be set, got $%02X", data[0])
+		}
+		if data[1] != 224 {
+			t.Errorf("expected unused sprites to be placed below the display, got $%02X", data[1])
+		}
+	})
+}
+
 func TestSMS_TileAt(t *testing.T) {
 	sega := sms.SMS{}
 
@@ -50,7 +76,7 @@ func TestSMS_TileAt(t *testing.T) {
 	})
 
 	t.Run("when pid is greater than the length of the tile slice", func(t *testing.T) {
-		_, err := sega.TileAt(sms.MaxTileCount)
+		_, err := sega.TileAt(sms.MaxTileNumber)
 		if err == nil {
 			t.Fatal("expected an error")
 		} else if err.Error() != "invalid tile ID" {
@@ -80,6 +106,57 @@ func TestSMS_AddTile(t *testing.T) {
 	}
 }
 
+func TestSMS_AddTileWithOffset(t *testing.T) {
+	tile := sms.Tile{}
+	sega := sms.SMS{}
+	if err := sega.SetTileOffset(446); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	pos, err := sega.AddTile(&tile)
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if pos != 446 {
+		t.Errorf("expected tile to be placed at the offset, tile id was %d", pos)
+	}
+	_, _ = sega.AddTile(&tile)
+
+	_, err = sega.AddTile(&tile)
+	if err == nil {
+		t.Fatal("expected an error")
+	} else if err.Error() != "tile memory full" {
+		t.Errorf("expected error message, got '%s'", err)
+	}
+
+	t.Run("with a sprite tile limit", func(t *testing.T) {
+		if err := sega.SetTileLimit(sms.MaxTileNumber); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		pos, err := sega.AddTile(&tile)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if pos != 448 {
+			t.Errorf("expected tile to be placed above 447, tile id was %d", pos)
+		}
+	})
+}
+
+func TestSMS_SetTileOffset(t *testing.T) {
+	sega := sms.SMS{}
+
+	err := sega.SetTileOffset(448)
+	if err == nil {
+		t.Fatal("expected an error")
+	} else if err.Error() != "invalid tile offset 448, must be within 0..447" {
+		t.Errorf("expected error message, got '%s'", err)
+	}
+	if sega.TileOffset() != 0 {
+		t.Errorf("expected offset to be unchanged, got %d", sega.TileOffset())
+	}
+}
+
 func TestSMS_TilemapEntryAt(t *testing.T) {
 	vdp := sms.SMS{}
 	word := sms.Word{TileNumber: 56}
@@ -175,6 +252,25 @@ func TestSMS_PaletteIdForColour(t *testing.T) {
 	})
 }
 
+func TestSMS_SetPaletteColourAt(t *testing.T) {
+	sega := sms.SMS{}
+	colour := sms.Colour(0b00110011)
+
+	if err := sega.SetPaletteColourAt(17, colour); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	got, _ := sega.PaletteColour(17)
+	if got != colour {
+		t.Errorf("expected correct colour, got %08b", got)
+	}
+
+	t.Run("when palette ID is out of bounds", func(t *testing.T) {
+		if err := sega.SetPaletteColourAt(32, colour); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
 func TestSMS_AddPaletteColour(t *testing.T) {
 	t.Run("return position", func(t *testing.T) {
 		sega := sms.SMS{}
@@ -200,3 +296,147 @@ func TestSMS_AddPaletteColour(t *testing.T) {
 		}
 	})
 }
+
+func TestSMS_SpriteAt(t *testing.T) {
+	sega := sms.SMS{}
+	_, _ = sega.AddSprite(sms.Sprite{X: 10, Y: 20, TileNumber: 3})
+
+	t.Run("get the saved sprite correctly", func(t *testing.T) {
+		got, err := sega.SpriteAt(0)
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if got.X != 10 || got.Y != 20 || got.TileNumber != 3 {
+			t.Errorf("expected sprite to have been set correctly, got %+v", got)
+		}
+	})
+
+	t.Run("changing the returned sprite does not change the SAT", func(t *testing.T) {
+		got, _ := sega.SpriteAt(0)
+		got.Y = sms.SpriteTerminator
+
+		stored, _ := sega.SpriteAt(0)
+		if stored.Y != 20 {
+			t.Errorf("expected the stored sprite to be unchanged, got Y=%d", stored.Y)
+		}
+	})
+
+	t.Run("when sprite slot is empty", func(t *testing.T) {
+		_, err := sega.SpriteAt(1)
+		if err == nil {
+			t.Fatal("expected an error")
+		} else if err.Error() != "no sprite found for requested sprite ID" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+
+	t.Run("when given bad inputs", func(t *testing.T) {
+		_, err := sega.SpriteAt(64)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestSMS_AddSprite(t *testing.T) {
+	t.Run("adds sprites to the next available slot", func(t *testing.T) {
+		sega := sms.SMS{}
+		pos, err := sega.AddSprite(sms.Sprite{})
+		if err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if pos != 0 {
+			t.Errorf("expected sprite to be placed in first slot, sprite id was %d", pos)
+		}
+
+		pos, _ = sega.AddSprite(sms.Sprite{})
+		if pos != 1 {
+			t.Errorf("expected next sprite to be placed in second slot, sprite id was %d", pos)
+		}
+	})
+
+	t.Run("when the Y position is the sprite terminator", func(t *testing.T) {
+		sega := sms.SMS{}
+		_, err := sega.AddSprite(sms.Sprite{Y: 0xD0})
+		if err == nil {
+			t.Fatal("expected an error")
+		} else if err.Error() != "sprite Y position $D0 is reserved as the sprite list terminator" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+
+	t.Run("when the sprite table is full", func(t *testing.T) {
+		sega := sms.SMS{}
+		for i := 0; i < 64; i++ {
+			_, _ = sega.AddSprite(sms.Sprite{})
+		}
+		_, err := sega.AddSprite(sms.Sprite{})
+		if err == nil {
+			t.Fatal("expected an error")
+		} else if err.Error() != "sprite attribute table full" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
+
+func TestSMS_RemoveSprite(t *testing.T) {
+	sega := sms.SMS{}
+	_, _ = sega.AddSprite(sms.Sprite{X: 1})
+	_, _ = sega.AddSprite(sms.Sprite{X: 2})
+
+	if err := sega.RemoveSprite(0); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if _, err := sega.SpriteAt(0); err == nil {
+		t.Error("expected sprite to have been removed")
+	}
+
+	t.Run("freed slot is reused", func(t *testing.T) {
+		pos, _ := sega.AddSprite(sms.Sprite{X: 3})
+		if pos != 0 {
+			t.Errorf("expected sprite to be placed in the freed slot, sprite id was %d", pos)
+		}
+	})
+
+	t.Run("when sprite slot is empty", func(t *testing.T) {
+		if err := sega.RemoveSprite(5); err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
+
+func TestSMS_SATData(t *testing.T) {
+	sega := sms.SMS{}
+	_, _ = sega.AddSprite(sms.Sprite{X: 0x10, Y: 0x20, TileNumber: 0x01})
+	_, _ = sega.AddSprite(sms.Sprite{X: 0x30, Y: 0x40, TileNumber: 0x02})
+	_, _ = sega.AddSprite(sms.Sprite{X: 0x50, Y: 0x60, TileNumber: 0x03})
+	_ = sega.RemoveSprite(1)
+
+	data := sega.SATData()
+
+	if data[0] != 0x20 || data[1] != 0x60 {
+		t.Errorf("expected Y positions to be packed in sprite order, got $%02X, $%02X", data[0], data[1])
+	}
+	if data[2] != 0xD0 {
+		t.Errorf("expected the sprite list to be terminated, got $%02X", data[2])
+	}
+	if data[128] != 0x10 || data[129] != 0x01 || data[130] != 0x50 || data[131] != 0x03 {
+		t.Errorf("expected X/tile number pairs to be packed in sprite order, got % X", data[128:132])
+	}
+
+	t.Run("with a full sprite table there is no terminator", func(t *testing.T) {
+		sega := sms.SMS{}
+		for i := 0; i < 64; i++ {
+			_, _ = sega.AddSprite(sms.Sprite{Y: 1})
+		}
+		data := sega.SATData()
+		for i := 0; i < 64; i++ {
+			if data[i] != 1 {
+				t.Fatalf("expected sprite #%02d Y position to be set, got $%02X", i, data[i])
+			}
+		}
+		if data[64] != 0 {
+			t.Errorf("expected the unused SAT area to be zero, got $%02X", data[64])
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package sms
+
+// The SAT (Sprite Attribute Table) is a 256-byte area in VideoRam that holds
+// the position and tile number of the 64 hardware sprites. It is usually
+// located at VRAM address $3F00.
+//
+// Data format:
+//
+//   $00-$3F: Y positions for each of the 64 sprites
+//   $40-$7F: unused
+//   $80-$FF: X position and tile number pairs, one for each sprite
+//
+// Sprites are drawn at Y+1 on the screen, so a Y value of $FF will place the
+// sprite at the very top line of the display.
+//
+// In the 192-line display mode, a Y value of $D0 terminates the sprite list;
+// this sprite, and any after it, will not be displayed. The VDP draws the
+// sprites in table order, with earlier sprites having a higher priority, so
+// overlapping sprites later in the table are drawn behind them.
+//
+// The tile number is 8-bits wide and is added to the sprite pattern base
+// address, which is normally set to $2000 (tiles 256..447).
+// https://www.smspower.org/maxim/HowToProgram/Sprites
+
+const (
+	MaxSpriteCount   = 64   // maximum number of sprites the SAT can hold
+	SpriteTerminator = 0xD0 // Y value that terminates the sprite list
+	satSize          = 256
+	satXOffset       = 0x80 // start of the X position/tile number pairs
+)
+
+// Sprite is a single hardware sprite entry in the Sprite Attribute Table.
+type Sprite struct {
+	X          uint8 // horizontal screen position
+	Y          uint8 // vertical screen position; the sprite is drawn at Y+1
+	TileNumber uint8 // tile definition number, offset from the sprite pattern base
+}
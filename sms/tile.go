@@ -1,6 +1,9 @@
 package sms
 
-import "fmt"
+import (
+	"encoding"
+	"fmt"
+)
 
 // All graphics on the Master System are built up from 8×8 pixel tiles.
 // Each pixel is a palette index from 0 to 15, i.e. 4 bits.
@@ -21,6 +24,11 @@ const (
 	planarDataSize = 32
 )
 
+var (
+	_ encoding.BinaryMarshaler   = (*Tile)(nil)
+	_ encoding.BinaryUnmarshaler = (*Tile)(nil)
+)
+
 // Tile is a type holding the colour data for an 8x8 pixel tile
 type Tile struct {
 	pixels [tileSize][tileSize]PaletteId
@@ -108,3 +116,29 @@ func (t *Tile) Bytes() (data []uint8) {
 	}
 	return
 }
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning
+// the 32 bytes of planar tile data.
+func (t *Tile) MarshalBinary() ([]byte, error) {
+	return t.Bytes(), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface,
+// decoding 32 bytes of planar tile data.
+func (t *Tile) UnmarshalBinary(data []byte) error {
+	if len(data) != planarDataSize {
+		return fmt.Errorf("invalid tile data size, expected %d bytes, got %d", planarDataSize, len(data))
+	}
+	for row := 0; row < tileSize; row++ {
+		planes := data[row*4 : row*4+4]
+		for col := 0; col < tileSize; col++ {
+			var pid PaletteId
+			for plane := 0; plane < 4; plane++ {
+				bit := (planes[plane] >> (7 - col)) & 0b00000001 // the pixel bit on this plane
+				pid |= PaletteId(bit << plane)                   // is the nibble bit for this plane
+			}
+			t.pixels[row][col] = pid
+		}
+	}
+	return nil
+}
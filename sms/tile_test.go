@@ -170,3 +170,34 @@ func TestTile_Bytes(t *testing.T) {
 		}
 	})
 }
+
+func TestTile_UnmarshalBinary(t *testing.T) {
+	tile := sms.Tile{}
+	for row := 0; row < tile.Size(); row++ {
+		for col := 0; col < tile.Size(); col++ {
+			_ = tile.SetPaletteIdAt(row, col, sms.PaletteId((row*3+col)%16))
+		}
+	}
+	data, err := tile.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	decoded := sms.Tile{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if decoded != tile {
+		t.Errorf("expected decoded tile to match the original")
+	}
+
+	t.Run("with invalid data size", func(t *testing.T) {
+		err := decoded.UnmarshalBinary(data[:31])
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "invalid tile data size, expected 32 bytes, got 31" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
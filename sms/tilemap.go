@@ -1,6 +1,10 @@
 package sms
 
-import "fmt"
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+)
 
 // Tilemap represents the background graphics on the Master System screen,
 // which is 256x224 pixels (32x28 8x8 tiles). This "virtual screen" is slightly
@@ -24,6 +28,14 @@ import "fmt"
 // The data is stored in VideoRam (usually at location $3800), in little-endian
 // format, and takes up 1792 bytes (32x28x2 bytes).
 //
+// Extended screen modes:
+//
+// The SMS2 and Game Gear VDPs support a 224-line (28 row) display, and on PAL
+// consoles a 240-line (30 row) display. In these modes the name table changes
+// layout: it is 32x32 entries (2048 bytes) and is located at $x700 instead
+// of $x800 (usually at $3700). The rows beyond the display remain available
+// for vertical scrolling.
+//
 // Flags:
 //
 // Flipping:
@@ -38,15 +50,23 @@ import "fmt"
 // graphics seem more multi-layered.
 // https://www.smspower.org/maxim/HowToProgram/Tilemap
 
+var (
+	_ encoding.BinaryMarshaler   = (*Tilemap)(nil)
+	_ encoding.BinaryUnmarshaler = (*Tilemap)(nil)
+)
+
 const (
-	tilemapRows         = 24
-	tilemapExtendedRows = 28
-	tilemapCols         = 32
+	tilemapRows              = 24
+	tilemapExtendedRows      = 28
+	tilemapLargeRows         = 30
+	tilemapExtendedTableRows = 32 // name table rows in the 224 and 240-line modes
+	tilemapCols              = 32
 )
 
 // Tilemap represents the background graphics on the Master System screen,
 type Tilemap struct {
-	table [tilemapExtendedRows][tilemapCols]Word
+	table        [tilemapExtendedTableRows][tilemapCols]Word
+	screenHeight int // screen height in pixels, defaults to 192 when not set
 }
 
 // Width returns the number of columns in the tilemap.
@@ -54,9 +74,42 @@ func (t Tilemap) Width() int {
 	return tilemapCols
 }
 
-// Height returns the number of rows in the tilemap.
+// SetScreenHeight sets the screen mode using its height in pixels: 192, 224, or 240.
+func (t *Tilemap) SetScreenHeight(height int) error {
+	switch height {
+	case ScreenHeight, ExtendedScreenHeight, LargeScreenHeight:
+		t.screenHeight = height
+		return nil
+	default:
+		return fmt.Errorf("invalid screen height %d, must be one of %d, %d, or %d", height, ScreenHeight, ExtendedScreenHeight, LargeScreenHeight)
+	}
+}
+
+// Height returns the number of rows in the tilemap visible on screen.
 func (t Tilemap) Height() int {
-	return tilemapRows
+	switch t.screenHeight {
+	case ExtendedScreenHeight:
+		return tilemapExtendedRows
+	case LargeScreenHeight:
+		return tilemapLargeRows
+	default:
+		return tilemapRows
+	}
+}
+
+// TableHeight returns the number of rows in the name table for the current
+// screen mode: 28 rows for the 192-line mode, and 32 for the 224 and
+// 240-line modes.
+func (t Tilemap) TableHeight() int {
+	if t.Extended() {
+		return tilemapExtendedTableRows
+	}
+	return tilemapExtendedRows
+}
+
+// Extended returns true when using the 224 or 240-line screen modes.
+func (t Tilemap) Extended() bool {
+	return t.screenHeight == ExtendedScreenHeight || t.screenHeight == LargeScreenHeight
 }
 
 // ExtendedHeight returns the number of rows in the tilemap when using 'mode 4'.
@@ -66,8 +119,8 @@ func (t Tilemap) ExtendedHeight() int {
 
 // Get returns the tile info from the requested location.
 func (t *Tilemap) Get(row, col int) (*Word, error) {
-	if row >= tilemapRows || col >= tilemapCols {
-		return nil, fmt.Errorf("get tilemap out of bounds indexing, max is (%d,%d), requested (%d,%d)", tilemapRows-1, tilemapCols-1, row, col)
+	if row < 0 || col < 0 || row >= t.TableHeight() || col >= tilemapCols {
+		return nil, fmt.Errorf("get tilemap out of bounds indexing, max is (%d,%d), requested (%d,%d)", t.TableHeight()-1, tilemapCols-1, row, col)
 	}
 
 	return &t.table[row][col], nil
@@ -75,20 +128,64 @@ func (t *Tilemap) Get(row, col int) (*Word, error) {
 
 // Set adds the tile info at the requested location.
 func (t *Tilemap) Set(row, col int, word Word) error {
-	if row >= tilemapRows || col >= tilemapCols {
-		return fmt.Errorf("set tilemap out of bounds indexing, max is (%d,%d), requested (%d,%d)", tilemapRows-1, tilemapCols-1, row, col)
+	if row < 0 || col < 0 || row >= t.TableHeight() || col >= tilemapCols {
+		return fmt.Errorf("set tilemap out of bounds indexing, max is (%d,%d), requested (%d,%d)", t.TableHeight()-1, tilemapCols-1, row, col)
 	}
 
 	t.table[row][col] = word
 	return nil
 }
 
-// Words returns the tilemap as a single slice of 16-bit values.
+// Fill sets every entry of the name table, including the rows not used by
+// the current screen mode, to the given word.
+func (t *Tilemap) Fill(word Word) {
+	for row := range t.table {
+		for col := range t.table[row] {
+			t.table[row][col] = word
+		}
+	}
+}
+
+// Words returns the name table as a single slice of 16-bit values, with the
+// number of rows depending on the screen mode (see TableHeight).
 func (t *Tilemap) Words() (words []uint16) {
-	for _, cols := range t.table {
+	for _, cols := range t.table[:t.TableHeight()] {
 		for _, word := range cols {
 			words = append(words, word.ToUint())
 		}
 	}
 	return
 }
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning
+// the name table as little-endian words, as stored in VRAM.
+func (t *Tilemap) MarshalBinary() ([]byte, error) {
+	var data []byte
+	for _, word := range t.Words() {
+		data = binary.LittleEndian.AppendUint16(data, word)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface,
+// decoding little-endian words, as stored in VRAM. The data must contain whole
+// rows of 32 entries, up to the table height for the current screen mode, such
+// as only the 24 visible rows. Any remaining rows are cleared.
+func (t *Tilemap) UnmarshalBinary(data []byte) error {
+	rowSize := tilemapCols * wordSize
+	if len(data)%rowSize != 0 {
+		return fmt.Errorf("invalid tilemap data size, must be whole rows of %d bytes, got %d bytes", rowSize, len(data))
+	}
+	if rows := len(data) / rowSize; rows > t.TableHeight() {
+		return fmt.Errorf("invalid tilemap data size, got %d rows, max is %d", rows, t.TableHeight())
+	}
+
+	t.table = [tilemapExtendedTableRows][tilemapCols]Word{}
+	for i := 0; i < len(data); i += wordSize {
+		entry := i / wordSize
+		if err := t.table[entry/tilemapCols][entry%tilemapCols].UnmarshalBinary(data[i : i+wordSize]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
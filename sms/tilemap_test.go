@@ -27,6 +27,53 @@ func TestTilemap_ExtendedHeight(t *testing.T) {
 	}
 }
 
+func TestTilemap_SetScreenHeight(t *testing.T) {
+	table := []struct {
+		height, rows, tableRows int
+	}{
+		{192, 24, 28},
+		{224, 28, 32},
+		{240, 30, 32},
+	}
+	for _, data := range table {
+		tm := sms.Tilemap{}
+		if err := tm.SetScreenHeight(data.height); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		if tm.Height() != data.rows {
+			t.Errorf("expected %d line mode to have %d rows, got %d", data.height, data.rows, tm.Height())
+		}
+		if tm.TableHeight() != data.tableRows {
+			t.Errorf("expected %d line mode name table to have %d rows, got %d", data.height, data.tableRows, tm.TableHeight())
+		}
+	}
+
+	t.Run("with an invalid screen height", func(t *testing.T) {
+		tm := sms.Tilemap{}
+		err := tm.SetScreenHeight(200)
+		if err == nil {
+			t.Fatal("expected an error")
+		} else if err.Error() != "invalid screen height 200, must be one of 192, 224, or 240" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+
+	t.Run("all name table rows can be set in the extended modes", func(t *testing.T) {
+		tm := sms.Tilemap{}
+		_ = tm.SetScreenHeight(240)
+		if err := tm.Set(31, 31, sms.Word{TileNumber: 5}); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		words := tm.Words()
+		if len(words) != 1024 {
+			t.Fatalf("expected name table data to contain 1024 entries, got %d", len(words))
+		}
+		if words[1023] != 5 {
+			t.Errorf("expected last entry to be set, got %016b", words[1023])
+		}
+	})
+}
+
 func TestTilemap_Get(t *testing.T) {
 	tm := sms.Tilemap{}
 	word := sms.Word{TileNumber: 447}
@@ -43,11 +90,11 @@ func TestTilemap_Get(t *testing.T) {
 	})
 
 	t.Run("with out of bounds row indexing", func(t *testing.T) {
-		_, err := tm.Get(24, 31)
+		_, err := tm.Get(28, 31)
 		if err == nil {
 			t.Fatal("expected an error")
 		}
-		if err.Error() != "get tilemap out of bounds indexing, max is (23,31), requested (24,31)" {
+		if err.Error() != "get tilemap out of bounds indexing, max is (27,31), requested (28,31)" {
 			t.Errorf("unexpected error message, requested '%s'", err)
 		}
 	})
@@ -57,7 +104,7 @@ func TestTilemap_Get(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected an error")
 		}
-		if err.Error() != "get tilemap out of bounds indexing, max is (23,31), requested (23,32)" {
+		if err.Error() != "get tilemap out of bounds indexing, max is (27,31), requested (23,32)" {
 			t.Errorf("unexpected error message, requested '%s'", err)
 		}
 	})
@@ -79,11 +126,11 @@ func TestTilemap_Set(t *testing.T) {
 	})
 
 	t.Run("with out of bounds row indexing", func(t *testing.T) {
-		err := tm.Set(24, 31, word)
+		err := tm.Set(28, 31, word)
 		if err == nil {
 			t.Fatal("expected an error")
 		}
-		if err.Error() != "set tilemap out of bounds indexing, max is (23,31), requested (24,31)" {
+		if err.Error() != "set tilemap out of bounds indexing, max is (27,31), requested (28,31)" {
 			t.Errorf("unexpected error message, got '%s'", err)
 		}
 	})
@@ -93,7 +140,7 @@ func TestTilemap_Set(t *testing.T) {
 		if err == nil {
 			t.Fatal("expected an error")
 		}
-		if err.Error() != "set tilemap out of bounds indexing, max is (23,31), requested (23,32)" {
+		if err.Error() != "set tilemap out of bounds indexing, max is (27,31), requested (23,32)" {
 			t.Errorf("unexpected error message, got '%s'", err)
 		}
 	})
@@ -127,3 +174,81 @@ func TestTilemap_Words(t *testing.T) {
 		}
 	})
 }
+
+func TestTilemap_Fill(t *testing.T) {
+	tm := sms.Tilemap{}
+	tm.Fill(sms.Word{TileNumber: 5})
+	_ = tm.Set(0, 0, sms.Word{TileNumber: 6})
+
+	words := tm.Words()
+	if words[0] != 6 {
+		t.Errorf("expected the set entry to use tile #6, got %d", words[0])
+	}
+	if words[1] != 5 || words[len(words)-1] != 5 {
+		t.Errorf("expected the other entries to use tile #5, got %d and %d", words[1], words[len(words)-1])
+	}
+
+	t.Run("including the rows of the extended modes", func(t *testing.T) {
+		if err := tm.SetScreenHeight(224); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		words := tm.Words()
+		if words[len(words)-1] != 5 {
+			t.Errorf("expected the last entry to use tile #5, got %d", words[len(words)-1])
+		}
+	})
+}
+
+func TestTilemap_UnmarshalBinary(t *testing.T) {
+	tm := sms.Tilemap{}
+	_ = tm.Set(0, 0, sms.Word{Priority: true, TileNumber: 1})
+	_ = tm.Set(27, 31, sms.Word{PaletteSelect: true, TileNumber: 447})
+
+	data, err := tm.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if len(data) != 1792 {
+		t.Fatalf("expected 1792 bytes of tilemap data, got %d", len(data))
+	}
+
+	decoded := sms.Tilemap{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	word, _ := decoded.Get(27, 31)
+	if *word != (sms.Word{PaletteSelect: true, TileNumber: 447}) {
+		t.Errorf("expected decoded entry to match the original, got %+v", word)
+	}
+
+	t.Run("with only the visible rows", func(t *testing.T) {
+		decoded := sms.Tilemap{}
+		if err := decoded.UnmarshalBinary(data[:24*64]); err != nil {
+			t.Fatalf("unexpected error: %q", err)
+		}
+		word, _ := decoded.Get(0, 0)
+		if *word != (sms.Word{Priority: true, TileNumber: 1}) {
+			t.Errorf("expected decoded entry to match the original, got %+v", word)
+		}
+	})
+
+	t.Run("with too many rows", func(t *testing.T) {
+		err := decoded.UnmarshalBinary(make([]byte, 32*64))
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "invalid tilemap data size, got 32 rows, max is 28" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+
+	t.Run("with partial rows", func(t *testing.T) {
+		err := decoded.UnmarshalBinary(data[:100])
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "invalid tilemap data size, must be whole rows of 64 bytes, got 100 bytes" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
@@ -0,0 +1,167 @@
+package sms
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	VRAMSize          = 0x4000 // 16 KB of video RAM
+	TileByteSize      = 32     // each tile pattern occupies 32 bytes of VRAM
+	MaxTileNumber     = 512    // tilemap words can reference tiles 0..511
+	nameTableBytes    = 32 * tilemapExtendedRows * 2
+	extNameTableBytes = 32 * tilemapExtendedTableRows * 2
+)
+
+// VRAMRange is a named block of video RAM.
+type VRAMRange struct {
+	Name  string
+	Start int // VRAM address
+	Size  int // size in bytes
+}
+
+// End returns the address following the last byte of the range.
+func (r VRAMRange) End() int {
+	return r.Start + r.Size
+}
+
+// Overlaps reports whether the two ranges share any VRAM addresses.
+func (r VRAMRange) Overlaps(other VRAMRange) bool {
+	return r.Start < other.End() && other.Start < r.End()
+}
+
+func (r VRAMRange) String() string {
+	return fmt.Sprintf("%s ($%04X-$%04X)", r.Name, r.Start, r.End()-1)
+}
+
+// VRAMOverlap is a pair of VRAM ranges sharing the same addresses.
+type VRAMOverlap struct {
+	A, B VRAMRange
+}
+
+func (o VRAMOverlap) String() string {
+	return fmt.Sprintf("%s overlaps %s", o.A, o.B)
+}
+
+// VRAMLayout describes where the pattern (tile) data, name table, and SAT are
+// located in VRAM, along with any tile ranges reserved for other uses, such as
+// the sprite tiles, or a font loaded before the background tiles.
+type VRAMLayout struct {
+	Patterns  VRAMRange   // area the background tiles are taken from
+	NameTable VRAMRange   // tilemap
+	SAT       VRAMRange   // sprite attribute table
+	Reserved  []VRAMRange // tile ranges not available to the background
+}
+
+// DefaultVRAMLayout returns the typical 192-line mode layout, as described in
+// the package documentation, with tiles 0..447 available to the background.
+func DefaultVRAMLayout() VRAMLayout {
+	return VRAMLayout{
+		Patterns:  VRAMRange{Name: "Patterns", Start: 0x0000, Size: 0x3800},
+		NameTable: VRAMRange{Name: "NameTable", Start: 0x3800, Size: nameTableBytes},
+		SAT:       VRAMRange{Name: "SAT", Start: 0x3F00, Size: satSize},
+	}
+}
+
+// ExtendedVRAMLayout returns the typical layout for the 224 and 240-line
+// modes, where the larger 32x32 name table is located at $3700.
+func ExtendedVRAMLayout() VRAMLayout {
+	return VRAMLayout{
+		Patterns:  VRAMRange{Name: "Patterns", Start: 0x0000, Size: 0x3700},
+		NameTable: VRAMRange{Name: "NameTable", Start: 0x3700, Size: extNameTableBytes},
+		SAT:       VRAMRange{Name: "SAT", Start: 0x3F00, Size: satSize},
+	}
+}
+
+// ReserveTiles marks a block of tiles as unavailable to the background,
+// for example the sprite tiles 256 onwards, or a font block.
+func (l *VRAMLayout) ReserveTiles(name string, first, count int) error {
+	if first < 0 || count <= 0 || first+count > MaxTileNumber {
+		return fmt.Errorf("invalid reserved tile range %d+%d, must be within 0..%d", first, count, MaxTileNumber-1)
+	}
+	l.Reserved = append(l.Reserved, VRAMRange{Name: name, Start: first * TileByteSize, Size: count * TileByteSize})
+	return nil
+}
+
+// Overlaps returns all pairs of the name table, SAT, and reserved ranges that
+// share the same VRAM addresses.
+func (l VRAMLayout) Overlaps() []VRAMOverlap {
+	ranges := append([]VRAMRange{l.NameTable, l.SAT}, l.Reserved...)
+
+	var overlaps []VRAMOverlap
+	for i := 0; i < len(ranges); i++ {
+		for j := i + 1; j < len(ranges); j++ {
+			if ranges[i].Overlaps(ranges[j]) {
+				overlaps = append(overlaps, VRAMOverlap{A: ranges[i], B: ranges[j]})
+			}
+		}
+	}
+	return overlaps
+}
+
+// Validate checks the ranges fit within VRAM, the tables are correctly
+// aligned for the VDP registers, and that none of them overlap.
+func (l VRAMLayout) Validate() error {
+	ranges := append([]VRAMRange{l.Patterns, l.NameTable, l.SAT}, l.Reserved...)
+	for _, r := range ranges {
+		if r.Start < 0 || r.Size <= 0 || r.End() > VRAMSize {
+			return fmt.Errorf("VRAM layout error: %s is outside of VRAM ($0000-$%04X)", r, VRAMSize-1)
+		}
+	}
+
+	switch l.NameTable.Size {
+	case nameTableBytes:
+		if l.NameTable.Start%0x800 != 0 {
+			return fmt.Errorf("VRAM layout error: name table address $%04X must be a multiple of $0800", l.NameTable.Start)
+		}
+	case extNameTableBytes:
+		if l.NameTable.Start%0x1000 != 0x0700 {
+			return fmt.Errorf("VRAM layout error: extended name table address $%04X must be $0700, $1700, $2700, or $3700", l.NameTable.Start)
+		}
+	default:
+		return fmt.Errorf("VRAM layout error: invalid name table size %d bytes", l.NameTable.Size)
+	}
+	if l.SAT.Start%0x100 != 0 || l.SAT.Size != satSize {
+		return fmt.Errorf("VRAM layout error: SAT must be %d bytes at a multiple of $0100, got %s", satSize, l.SAT)
+	}
+
+	if overlaps := l.Overlaps(); len(overlaps) > 0 {
+		problems := make([]string, len(overlaps))
+		for i, o := range overlaps {
+			problems[i] = o.String()
+		}
+		return fmt.Errorf("VRAM layout error: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// BackgroundTiles returns the tile numbers available for background tiles:
+// those within the pattern area not used by the name table, SAT, or any
+// reserved range.
+func (l VRAMLayout) BackgroundTiles() []int {
+	used := append([]VRAMRange{l.NameTable, l.SAT}, l.Reserved...)
+
+	var tiles []int
+	for id := 0; id < MaxTileNumber; id++ {
+		tile := VRAMRange{Start: id * TileByteSize, Size: TileByteSize}
+		if tile.Start < l.Patterns.Start || tile.End() > l.Patterns.End() {
+			continue
+		}
+		free := true
+		for _, r := range used {
+			if tile.Overlaps(r) {
+				free = false
+				break
+			}
+		}
+		if free {
+			tiles = append(tiles, id)
+		}
+	}
+	return tiles
+}
+
+// BackgroundTileCount returns the number of tiles available for backgrounds.
+func (l VRAMLayout) BackgroundTileCount() int {
+	return len(l.BackgroundTiles())
+}
@@ -0,0 +1,107 @@
+package sms_test
+
+import (
+	"testing"
+
+	"github.com/mrcook/smstilemap/sms"
+)
+
+func TestVRAMLayout_Default(t *testing.T) {
+	layout := sms.DefaultVRAMLayout()
+
+	if err := layout.Validate(); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if layout.BackgroundTileCount() != 448 {
+		t.Errorf("expected 448 background tiles, got %d", layout.BackgroundTileCount())
+	}
+}
+
+func TestVRAMLayout_Extended(t *testing.T) {
+	layout := sms.ExtendedVRAMLayout()
+
+	if err := layout.Validate(); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if layout.NameTable.Start != 0x3700 {
+		t.Errorf("expected name table at $3700, got $%04X", layout.NameTable.Start)
+	}
+	if layout.BackgroundTileCount() != 440 {
+		t.Errorf("expected 440 background tiles, got %d", layout.BackgroundTileCount())
+	}
+}
+
+func TestVRAMLayout_ReserveTiles(t *testing.T) {
+	layout := sms.DefaultVRAMLayout()
+	if err := layout.ReserveTiles("Font", 0, 96); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if err := layout.ReserveTiles("SpriteTiles", 256, 192); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+
+	tiles := layout.BackgroundTiles()
+	if len(tiles) != 160 {
+		t.Fatalf("expected 160 background tiles, got %d", len(tiles))
+	}
+	if tiles[0] != 96 || tiles[len(tiles)-1] != 255 {
+		t.Errorf("expected background tiles 96..255, got %d..%d", tiles[0], tiles[len(tiles)-1])
+	}
+
+	t.Run("with invalid range", func(t *testing.T) {
+		err := layout.ReserveTiles("Bad", 500, 20)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "invalid reserved tile range 500+20, must be within 0..511" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
+
+func TestVRAMLayout_Overlaps(t *testing.T) {
+	layout := sms.DefaultVRAMLayout()
+	_ = layout.ReserveTiles("Font", 440, 16)
+
+	overlaps := layout.Overlaps()
+	if len(overlaps) != 1 {
+		t.Fatalf("expected 1 overlap, got %d", len(overlaps))
+	}
+	if overlaps[0].A.Name != "NameTable" || overlaps[0].B.Name != "Font" {
+		t.Errorf("unexpected overlap: %s", overlaps[0])
+	}
+
+	err := layout.Validate()
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if err.Error() != "VRAM layout error: NameTable ($3800-$3EFF) overlaps Font ($3700-$38FF)" {
+		t.Errorf("unexpected error message, got '%s'", err)
+	}
+}
+
+func TestVRAMLayout_Validate(t *testing.T) {
+	t.Run("with misaligned name table", func(t *testing.T) {
+		layout := sms.DefaultVRAMLayout()
+		layout.NameTable.Start = 0x3400
+		err := layout.Validate()
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "VRAM layout error: name table address $3400 must be a multiple of $0800" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+
+	t.Run("with SAT outside of VRAM", func(t *testing.T) {
+		layout := sms.DefaultVRAMLayout()
+		layout.SAT.Start = 0x4000
+		err := layout.Validate()
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "VRAM layout error: SAT ($4000-$40FF) is outside of VRAM ($0000-$3FFF)" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
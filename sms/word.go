@@ -1,5 +1,18 @@
 package sms
 
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+)
+
+const wordSize = 2 // tilemap entries are 2 bytes, stored little-endian
+
+var (
+	_ encoding.BinaryMarshaler   = Word{}
+	_ encoding.BinaryUnmarshaler = (*Word)(nil)
+)
+
 type Word struct {
 	Priority       bool   // bit 12: tile is displayed in front of sprites when set
 	PaletteSelect  bool   // bit 11: use tile palette or sprite palette (when set)
@@ -30,6 +43,33 @@ func (w Word) ToUint() uint16 {
 	return value
 }
 
+// WordFromUint returns the tilemap entry for the 16-bit value.
+func WordFromUint(value uint16) Word {
+	return Word{
+		Priority:       value&0b0001000000000000 != 0,
+		PaletteSelect:  value&0b0000100000000000 != 0,
+		VerticalFlip:   value&0b0000010000000000 != 0,
+		HorizontalFlip: value&0b0000001000000000 != 0,
+		TileNumber:     value & 0b0000000111111111,
+	}
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning
+// the entry as 2 bytes in little-endian format, as stored in VRAM.
+func (w Word) MarshalBinary() ([]byte, error) {
+	return binary.LittleEndian.AppendUint16(nil, w.ToUint()), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface,
+// decoding a 2 byte little-endian tilemap entry.
+func (w *Word) UnmarshalBinary(data []byte) error {
+	if len(data) != wordSize {
+		return fmt.Errorf("invalid tilemap entry size, expected %d bytes, got %d", wordSize, len(data))
+	}
+	*w = WordFromUint(binary.LittleEndian.Uint16(data))
+	return nil
+}
+
 func (w *Word) SetFlippedStateFromOrientation(or Orientation) {
 	switch or {
 	case OrientationFlippedVH:
@@ -89,3 +89,33 @@ func TestWord_SetFlippedStateFromOrientation(t *testing.T) {
 		}
 	})
 }
+
+func TestWord_UnmarshalBinary(t *testing.T) {
+	word := sms.Word{Priority: true, HorizontalFlip: true, TileNumber: 300}
+
+	data, err := word.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if len(data) != 2 || data[0] != 0b00101100 || data[1] != 0b00010011 {
+		t.Errorf("expected little-endian word data, got %08b", data)
+	}
+
+	decoded := sms.Word{}
+	if err := decoded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected error: %q", err)
+	}
+	if decoded != word {
+		t.Errorf("expected decoded word to match the original, got %+v", decoded)
+	}
+
+	t.Run("with invalid data size", func(t *testing.T) {
+		err := decoded.UnmarshalBinary(data[:1])
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+		if err.Error() != "invalid tilemap entry size, expected 2 bytes, got 1" {
+			t.Errorf("unexpected error message, got '%s'", err)
+		}
+	})
+}
@@ -0,0 +1,303 @@
+// Package tiled reads and writes maps and tilesets for the Tiled map editor,
+// using its TMX (map) and TSX (tileset) XML formats.
+//
+// Each map cell holds a global tile ID (GID): the tile number within its
+// tileset, plus the `firstgid` of the tileset, where 0 is an empty cell. The
+// top bits of a GID are the flip flags, matching the horizontal and vertical
+// flip bits of an SMS tilemap entry.
+package tiled
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The Tiled version the files are written for.
+const Version = "1.10"
+
+// GID flip flags.
+const (
+	FlippedHorizontally uint32 = 0x80000000
+	FlippedVertically   uint32 = 0x40000000
+	FlippedDiagonally   uint32 = 0x20000000 // not supported by the SMS
+
+	flipMask = FlippedHorizontally | FlippedVertically | FlippedDiagonally
+)
+
+// Tileset is a tileset using a single image, as stored in a TSX file.
+type Tileset struct {
+	XMLName    xml.Name `xml:"tileset"`
+	Version    string   `xml:"version,attr,omitempty"`
+	Name       string   `xml:"name,attr"`
+	TileWidth  int      `xml:"tilewidth,attr"`
+	TileHeight int      `xml:"tileheight,attr"`
+	Spacing    int      `xml:"spacing,attr,omitempty"` // pixels between the tiles
+	Margin     int      `xml:"margin,attr,omitempty"`  // pixels around the edge of the image
+	TileCount  int      `xml:"tilecount,attr"`
+	Columns    int      `xml:"columns,attr"`
+	Image      Image    `xml:"image"`
+}
+
+// Image is the tileset image, where the source is relative to the file
+// containing the tileset.
+type Image struct {
+	Source string `xml:"source,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+}
+
+// Map is an orthogonal map, as stored in a TMX file.
+type Map struct {
+	XMLName     xml.Name     `xml:"map"`
+	Version     string       `xml:"version,attr"`
+	Orientation string       `xml:"orientation,attr"`
+	RenderOrder string       `xml:"renderorder,attr"`
+	Width       int          `xml:"width,attr"` // in tiles
+	Height      int          `xml:"height,attr"`
+	TileWidth   int          `xml:"tilewidth,attr"`
+	TileHeight  int          `xml:"tileheight,attr"`
+	Infinite    int          `xml:"infinite,attr"`
+	NextLayerID int          `xml:"nextlayerid,attr"`
+	Tilesets    []MapTileset `xml:"tileset"`
+	Layers      []Layer      `xml:"layer"`
+}
+
+// MapTileset is a tileset used by a map: either a reference to an external
+// TSX tileset, or a tileset embedded in the map.
+type MapTileset struct {
+	FirstGID   int    `xml:"firstgid,attr"`
+	Source     string `xml:"source,attr,omitempty"`
+	Name       string `xml:"name,attr,omitempty"`
+	TileWidth  int    `xml:"tilewidth,attr,omitempty"`
+	TileHeight int    `xml:"tileheight,attr,omitempty"`
+	Spacing    int    `xml:"spacing,attr,omitempty"`
+	Margin     int    `xml:"margin,attr,omitempty"`
+	TileCount  int    `xml:"tilecount,attr,omitempty"`
+	Columns    int    `xml:"columns,attr,omitempty"`
+	Image      *Image `xml:"image,omitempty"`
+}
+
+// Tileset returns the embedded tileset.
+func (t MapTileset) Tileset() *Tileset {
+	ts := &Tileset{
+		Name:       t.Name,
+		TileWidth:  t.TileWidth,
+		TileHeight: t.TileHeight,
+		Spacing:    t.Spacing,
+		Margin:     t.Margin,
+		TileCount:  t.TileCount,
+		Columns:    t.Columns,
+	}
+	if t.Image != nil {
+		ts.Image = *t.Image
+	}
+	return ts
+}
+
+// Layer is a tile layer, with a GID for each cell of the map.
+type Layer struct {
+	ID     int    `xml:"id,attr"`
+	Name   string `xml:"name,attr"`
+	Width  int    `xml:"width,attr"`
+	Height int    `xml:"height,attr"`
+	Data   Data   `xml:"data"`
+}
+
+// Data is the encoded cells of a layer.
+type Data struct {
+	Encoding    string `xml:"encoding,attr,omitempty"`    // csv or base64
+	Compression string `xml:"compression,attr,omitempty"` // for base64: none, zlib, or gzip
+	Text        string `xml:",innerxml"`                  // digits and commas, or base64, so needs no escaping
+}
+
+// NewTileset returns a tileset for the image, which holds the tiles in rows
+// of the given number of columns.
+func NewTileset(name string, tileSize, tileCount, columns int, image Image) *Tileset {
+	return &Tileset{
+		Version:    Version,
+		Name:       name,
+		TileWidth:  tileSize,
+		TileHeight: tileSize,
+		TileCount:  tileCount,
+		Columns:    columns,
+		Image:      image,
+	}
+}
+
+// NewMap returns a map with a single tile layer, using the GIDs, row by row,
+// of the tiles in the TSX tileset file, which starts at GID 1.
+func NewMap(width, height, tileSize int, tilesetSource, layerName string, gids []uint32) (*Map, error) {
+	if len(gids) != width*height {
+		return nil, fmt.Errorf("expected %d tiles for a %dx%d map, got %d", width*height, width, height, len(gids))
+	}
+	return &Map{
+		Version:     Version,
+		Orientation: "orthogonal",
+		RenderOrder: "right-down",
+		Width:       width,
+		Height:      height,
+		TileWidth:   tileSize,
+		TileHeight:  tileSize,
+		NextLayerID: 2,
+		Tilesets:    []MapTileset{{FirstGID: 1, Source: tilesetSource}},
+		Layers: []Layer{{
+			ID:     1,
+			Name:   layerName,
+			Width:  width,
+			Height: height,
+			Data:   Data{Encoding: "csv", Text: CSV(gids, width)},
+		}},
+	}, nil
+}
+
+// GID returns the global tile ID for the tile number of a tileset, with the
+// flip flags set.
+func GID(firstGID, tile int, horizontal, vertical bool) uint32 {
+	gid := uint32(firstGID + tile)
+	if horizontal {
+		gid |= FlippedHorizontally
+	}
+	if vertical {
+		gid |= FlippedVertically
+	}
+	return gid
+}
+
+// SplitGID returns the global tile ID without its flip flags, along with the
+// horizontal and vertical flip flags.
+func SplitGID(gid uint32) (id uint32, horizontal, vertical bool) {
+	return gid &^ flipMask, gid&FlippedHorizontally != 0, gid&FlippedVertically != 0
+}
+
+// CSV returns the GIDs in the CSV layer encoding, with a line for each row
+// of the layer.
+func CSV(gids []uint32, width int) string {
+	var sb strings.Builder
+
+	sb.WriteString("\n")
+	for i := 0; i < len(gids); i += width {
+		values := make([]string, 0, width)
+		for _, gid := range gids[i:min(i+width, len(gids))] {
+			values = append(values, fmt.Sprintf("%d", gid))
+		}
+		sb.WriteString(strings.Join(values, ","))
+		if i+width < len(gids) {
+			sb.WriteString(",")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// GIDs returns the decoded GIDs of the layer, row by row.
+func (l *Layer) GIDs() ([]uint32, error) {
+	var gids []uint32
+
+	switch l.Data.Encoding {
+	case "csv":
+		for _, value := range strings.Split(strings.TrimSpace(l.Data.Text), ",") {
+			gid, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("layer '%s': invalid CSV tile: %w", l.Name, err)
+			}
+			gids = append(gids, uint32(gid))
+		}
+	case "base64":
+		data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(l.Data.Text))
+		if err != nil {
+			return nil, fmt.Errorf("layer '%s': invalid base64 data: %w", l.Name, err)
+		}
+		if data, err = decompress(l.Data.Compression, data); err != nil {
+			return nil, fmt.Errorf("layer '%s': %w", l.Name, err)
+		}
+		if len(data)%4 != 0 {
+			return nil, fmt.Errorf("layer '%s': base64 data is not a multiple of 4 bytes", l.Name)
+		}
+		for i := 0; i < len(data); i += 4 {
+			gids = append(gids, binary.LittleEndian.Uint32(data[i:]))
+		}
+	default:
+		return nil, fmt.Errorf("layer '%s': unsupported layer encoding '%s', must be csv or base64", l.Name, l.Data.Encoding)
+	}
+
+	if len(gids) != l.Width*l.Height {
+		return nil, fmt.Errorf("layer '%s': expected %d tiles for a %dx%d layer, got %d", l.Name, l.Width*l.Height, l.Width, l.Height, len(gids))
+	}
+	return gids, nil
+}
+
+// decompresses the base64 decoded layer data
+func decompress(compression string, data []uint8) ([]uint8, error) {
+	var r io.Reader
+	var err error
+
+	switch compression {
+	case "":
+		return data, nil
+	case "zlib":
+		r, err = zlib.NewReader(bytes.NewReader(data))
+	case "gzip":
+		r, err = gzip.NewReader(bytes.NewReader(data))
+	default:
+		return nil, fmt.Errorf("unsupported layer compression '%s', must be zlib or gzip", compression)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s data: %w", compression, err)
+	}
+	return io.ReadAll(r)
+}
+
+// DecodeMap reads a map from a TMX file.
+func DecodeMap(r io.Reader) (*Map, error) {
+	m := &Map{}
+	if err := xml.NewDecoder(r).Decode(m); err != nil {
+		return nil, fmt.Errorf("invalid TMX map: %w", err)
+	}
+	if m.Orientation != "orthogonal" {
+		return nil, fmt.Errorf("unsupported map orientation '%s', must be orthogonal", m.Orientation)
+	}
+	if m.Infinite != 0 {
+		return nil, fmt.Errorf("infinite maps are not supported")
+	}
+	return m, nil
+}
+
+// DecodeTileset reads a tileset from a TSX file.
+func DecodeTileset(r io.Reader) (*Tileset, error) {
+	t := &Tileset{}
+	if err := xml.NewDecoder(r).Decode(t); err != nil {
+		return nil, fmt.Errorf("invalid TSX tileset: %w", err)
+	}
+	return t, nil
+}
+
+// Encode writes the tileset as a TSX file.
+func (t *Tileset) Encode(w io.Writer) error {
+	return encode(w, t)
+}
+
+// Encode writes the map as a TMX file.
+func (m *Map) Encode(w io.Writer) error {
+	return encode(w, m)
+}
+
+func encode(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", " ")
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,185 @@
+package tiled_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/mrcook/smstilemap/tiled"
+)
+
+func TestTiled_GID(t *testing.T) {
+	gid := tiled.GID(1, 4, true, false)
+	if gid != 0x80000005 {
+		t.Errorf("expected GID to be $80000005, got $%08X", gid)
+	}
+	gid = tiled.GID(1, 0, true, true)
+	if gid != 0xC0000001 {
+		t.Errorf("expected GID to be $C0000001, got $%08X", gid)
+	}
+
+	id, h, v := tiled.SplitGID(0x40000003)
+	if id != 3 || h || !v {
+		t.Errorf("expected a vertically flipped GID 3, got %d (h: %t, v: %t)", id, h, v)
+	}
+}
+
+func TestTiled_CSV(t *testing.T) {
+	got := tiled.CSV([]uint32{1, 2, 3, 4, 2147483649, 0}, 3)
+	want := "\n1,2,3,\n4,2147483649,0\n"
+	if got != want {
+		t.Errorf("unexpected CSV data:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestTiled_MapEncode(t *testing.T) {
+	m, err := tiled.NewMap(2, 1, 8, "level.tsx", "Tilemap", []uint32{1, tiled.GID(1, 0, false, true)})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var sb strings.Builder
+	if err := m.Encode(&sb); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" renderorder="right-down" width="2" height="1" tilewidth="8" tileheight="8" infinite="0" nextlayerid="2">
+ <tileset firstgid="1" source="level.tsx"></tileset>
+ <layer id="1" name="Tilemap" width="2" height="1">
+  <data encoding="csv">
+1,1073741825
+</data>
+ </layer>
+</map>
+`
+	if got := sb.String(); got != want {
+		t.Errorf("unexpected TMX output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTiled_NewMapSize(t *testing.T) {
+	if _, err := tiled.NewMap(2, 2, 8, "level.tsx", "Tilemap", []uint32{1, 2, 3}); err == nil {
+		t.Error("expected an error for the missing map cells")
+	}
+}
+
+func TestTiled_TilesetEncode(t *testing.T) {
+	ts := tiled.NewTileset("level", 8, 40, 32, tiled.Image{Source: "level-tileset.png", Width: 256, Height: 16})
+
+	var sb strings.Builder
+	if err := ts.Encode(&sb); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<tileset version="1.10" name="level" tilewidth="8" tileheight="8" tilecount="40" columns="32">
+ <image source="level-tileset.png" width="256" height="16"></image>
+</tileset>
+`
+	if got := sb.String(); got != want {
+		t.Errorf("unexpected TSX output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTiled_LayerGIDs(t *testing.T) {
+	want := []uint32{1, 2, 0x80000003, 4}
+	raw := []uint8{1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0x80, 4, 0, 0, 0}
+
+	var zlibData bytes.Buffer
+	zw := zlib.NewWriter(&zlibData)
+	zw.Write(raw)
+	zw.Close()
+
+	var gzipData bytes.Buffer
+	gw := gzip.NewWriter(&gzipData)
+	gw.Write(raw)
+	gw.Close()
+
+	table := map[string]tiled.Data{
+		"csv":    {Encoding: "csv", Text: "\n1,2,\n2147483651,4\n"},
+		"base64": {Encoding: "base64", Text: "\n   " + base64.StdEncoding.EncodeToString(raw) + "\n  "},
+		"zlib":   {Encoding: "base64", Compression: "zlib", Text: base64.StdEncoding.EncodeToString(zlibData.Bytes())},
+		"gzip":   {Encoding: "base64", Compression: "gzip", Text: base64.StdEncoding.EncodeToString(gzipData.Bytes())},
+	}
+	for name, data := range table {
+		layer := tiled.Layer{Name: name, Width: 2, Height: 2, Data: data}
+		gids, err := layer.GIDs()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+			continue
+		}
+		if len(gids) != len(want) {
+			t.Fatalf("%s: expected %d GIDs, got %d", name, len(want), len(gids))
+		}
+		for i := range want {
+			if gids[i] != want[i] {
+				t.Errorf("%s: expected GID %d to be %d, got %d", name, i, want[i], gids[i])
+			}
+		}
+	}
+}
+
+func TestTiled_LayerGIDsErrors(t *testing.T) {
+	table := map[string]tiled.Data{
+		"xml encoding":  {Text: "<tile gid=\"1\"/>"},
+		"missing cells": {Encoding: "csv", Text: "1,2,3"},
+		"zstd":          {Encoding: "base64", Compression: "zstd", Text: "AQAAAA=="},
+	}
+	for name, data := range table {
+		layer := tiled.Layer{Name: name, Width: 2, Height: 2, Data: data}
+		if _, err := layer.GIDs(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestTiled_DecodeMap(t *testing.T) {
+	tmx := `<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" tiledversion="1.10.2" orientation="orthogonal" renderorder="right-down" width="2" height="1" tilewidth="8" tileheight="8" infinite="0" nextlayerid="2" nextobjectid="1">
+ <tileset firstgid="1" name="level" tilewidth="8" tileheight="8" spacing="1" margin="2" tilecount="4" columns="2">
+  <image source="level.png" width="21" height="21"/>
+ </tileset>
+ <layer id="1" name="Background" width="2" height="1">
+  <data encoding="csv">
+1,2147483650
+</data>
+ </layer>
+</map>`
+
+	m, err := tiled.DecodeMap(strings.NewReader(tmx))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if m.Width != 2 || m.Height != 1 || len(m.Tilesets) != 1 || len(m.Layers) != 1 {
+		t.Fatalf("unexpected map: %+v", m)
+	}
+	ts := m.Tilesets[0].Tileset()
+	if ts.TileCount != 4 || ts.Columns != 2 || ts.Spacing != 1 || ts.Margin != 2 || ts.Image.Source != "level.png" {
+		t.Errorf("unexpected embedded tileset: %+v", ts)
+	}
+	gids, err := m.Layers[0].GIDs()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if gids[0] != 1 || gids[1] != 0x80000002 {
+		t.Errorf("unexpected GIDs: %v", gids)
+	}
+}
+
+func TestTiled_DecodeTileset(t *testing.T) {
+	ts := tiled.NewTileset("level", 8, 40, 32, tiled.Image{Source: "level-tileset.png", Width: 256, Height: 16})
+
+	var sb strings.Builder
+	if err := ts.Encode(&sb); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	got, err := tiled.DecodeTileset(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.Name != "level" || got.TileCount != 40 || got.Columns != 32 || got.Image != ts.Image {
+		t.Errorf("unexpected tileset: %+v", got)
+	}
+}